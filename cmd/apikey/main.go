@@ -0,0 +1,80 @@
+// apikey 管理 API key 文件：
+//
+//	go run ./cmd/apikey create <name>
+//	go run ./cmd/apikey list
+//	go run ./cmd/apikey enable <id>
+//	go run ./cmd/apikey disable <id>
+//
+// key 文件路径通过 -file 或 API_KEYS_FILE 指定，默认为 api_keys.json。
+// 明文 key 只在创建时输出一次，文件中只保存哈希。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/web3-smart-wallet/src/auth"
+)
+
+func main() {
+	defaultPath := os.Getenv("API_KEYS_FILE")
+	if defaultPath == "" {
+		defaultPath = "api_keys.json"
+	}
+	path := flag.String("file", defaultPath, "API key file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: apikey [-file path] create <name> | list | enable <id> | disable <id>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	store, err := auth.NewFileKeyStore(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		plain, key, err := store.Create(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("id:   %s\nname: %s\nkey:  %s\n", key.ID, key.Name, plain)
+	case "list":
+		keys, err := store.List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, key := range keys {
+			status := "enabled"
+			if !key.Enabled {
+				status = "disabled"
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\n", key.ID, status, key.CreatedAt.Format("2006-01-02T15:04:05Z"), key.Name)
+		}
+	case "enable", "disable":
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := store.SetEnabled(args[1], args[0] == "enable"); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s %sd\n", args[1], args[0])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -1,57 +1,371 @@
 package main
 
 import (
-	"fmt"
+	"cmp"
+	"context"
+	"flag"
 	"log"
+	"log/slog"
+	"net/http/httptest"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+	"github.com/web3-smart-wallet/src/admin"
 	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/apierror"
+	"github.com/web3-smart-wallet/src/auth"
+	"github.com/web3-smart-wallet/src/breaker"
+	"github.com/web3-smart-wallet/src/cache"
+	"github.com/web3-smart-wallet/src/config"
+	"github.com/web3-smart-wallet/src/did"
+	"github.com/web3-smart-wallet/src/logging"
+	"github.com/web3-smart-wallet/src/metrics"
+	"github.com/web3-smart-wallet/src/ratelimit"
 	"github.com/web3-smart-wallet/src/server"
 	"github.com/web3-smart-wallet/src/services"
+	"github.com/web3-smart-wallet/src/services/fakes"
+	"github.com/web3-smart-wallet/src/timeout"
+	"github.com/web3-smart-wallet/src/tracing"
 )
 
-func init() {
-	// 加载 .env 文件
-	if err := godotenv.Load(); err != nil {
-		log.Printf("未找到 .env 文件")
+func main() {
+	// 配置来自环境变量、.env 和可选的 YAML 文件（-config 或 CONFIG_FILE），环境变量优先，见 config.example.yaml
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "path to YAML config file")
+	// -fake-upstream 回放 Ankr 响应、原生币余额使用假 RPC 节点，不访问外部网络；-fixtures 指定录制目录，默认使用由假服务生成的内置数据
+	// -record 把真实 Ankr Advanced API 的请求和响应录制到指定目录
+	fakeUpstream := flag.Bool("fake-upstream", false, "serve Ankr responses from fixtures instead of calling upstream APIs")
+	fixturesDir := flag.String("fixtures", "", "directory of fixtures for -fake-upstream (default: built-in fixtures generated from the fake Ankr server)")
+	recordDir := flag.String("record", "", "record Ankr Advanced API exchanges into this directory")
+	flag.Parse()
+	if *fakeUpstream && *recordDir != "" {
+		log.Fatal("-fake-upstream and -record cannot be used together")
 	}
-}
 
-func main() {
-	app := fiber.New(fiber.Config{
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			message := "Internal Server Error"
+	// 假上游在加载配置之前启动，替换 Ankr 地址后无需 ANKR_API_KEY，其他提供方不启用
+	var overrides []func(*config.Config)
+	var fakeRPC *httptest.Server
+	if *fakeUpstream {
+		fixtures := fakes.AnkrFixtures()
+		if *fixturesDir != "" {
+			fixtures = os.DirFS(*fixturesDir)
+		}
+		replay, err := fakes.NewReplayServer(fixtures)
+		if err != nil {
+			log.Fatalf("failed to load fixtures: %v", err)
+		}
+		defer replay.Close()
+		fakeRPC = fakes.NewRPCServer()
+		defer fakeRPC.Close()
+
+		overrides = append(overrides, func(c *config.Config) {
+			c.Ankr = config.AnkrConfig{APIURL: replay.URL}
+			c.Providers.Default = config.ProviderList{"ankr"}
+			c.Providers.Chains = nil
+			c.Providers.Alchemy.APIKey = ""
+			c.Providers.Moralis.APIKey = ""
+			c.Providers.Covalent.APIKey = ""
+			c.Balance.RPCURLs = nil
+		})
+	}
+
+	cfg, err := config.Load(*configFile, overrides...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// JSON 日志，LOG_LEVEL 设置初始级别，运行时可通过 PUT /debug/log-level 调整；LOG_FORMAT=text 输出文本格式
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.Log.Level)
+	logger := logging.New(os.Stdout, cfg.Log.Format, logLevel)
+	slog.SetDefault(logger)
+	// 上游 key 在日志和 trace 中出现的任何位置都脱敏，不依赖 URL 格式
+	logging.AddSecrets(cfg.Ankr.APIKey, cfg.Providers.Alchemy.APIKey, cfg.Providers.Moralis.APIKey, cfg.Providers.Covalent.APIKey)
 
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-				message = e.Message
+	// 录制时请求经本地代理转发给真实的 Ankr，录制文件中不包含上游地址和 API key
+	if *recordDir != "" {
+		recorder, err := fakes.NewRecordingServer(cfg.AnkrURL(), *recordDir)
+		if err != nil {
+			log.Fatalf("failed to start recorder: %v", err)
+		}
+		defer recorder.Close()
+		cfg.Ankr.APIURL = recorder.URL
+		slog.Info("recording Ankr exchanges", "dir", *recordDir)
+	}
+	if *fakeUpstream {
+		slog.Warn("serving upstream responses from fixtures", "flag", "fake-upstream", "fixtures", cmp.Or(*fixturesDir, "built-in"))
+	}
+
+	// 缓存和限流共用一个 Redis 连接池，只在用到时创建，退出时关闭
+	var sharedRedis *redis.Client
+	redisClient := func() *redis.Client {
+		if sharedRedis == nil {
+			redisOptions, err := redis.ParseURL(cfg.Redis.URL)
+			if err != nil {
+				log.Fatalf("invalid REDIS_URL: %v", err)
 			}
+			sharedRedis = redis.NewClient(redisOptions)
+		}
+		return sharedRedis
+	}
 
-			return c.Status(code).JSON(api.Error{
-				Code:    "error",
-				Message: message,
-			})
-		},
+	app := fiber.New(fiber.Config{
+		// SERVER_TIMEOUTS 同时作为读写超时，MAX_CONNECTIONS 限制并发连接数
+		ReadTimeout:  cfg.Server.Timeouts,
+		WriteTimeout: cfg.Server.Timeouts,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		Concurrency:  cfg.Server.MaxConnections,
+		// 部署在反向代理之后时设置 PROXY_HEADER（如 X-Real-IP），c.IP() 才能取到客户端 IP
+		ProxyHeader:        cfg.Server.ProxyHeader,
+		EnableIPValidation: true,
+		// handler 返回的错误按 apierror 分类写出，details 中带上请求 ID
+		ErrorHandler: apierror.Handler,
+	})
+
+	// 链路追踪，OTEL_TRACES_EXPORTER 可选 otlp、stdout 或 none（默认），OTLP 地址等通过 OTEL_EXPORTER_OTLP_* 配置
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
 	})
+	if err != nil {
+		log.Fatalf("failed to set up tracing: %v", err)
+	}
+	app.Use(tracing.Middleware())
+
+	// 请求日志，后续中间件和服务通过 UserContext 取得带 request_id 的 logger
+	app.Use(logging.Middleware(logger))
+
+	// 请求数、延迟和并发请求数指标
+	app.Use(metrics.Middleware())
+
+	// handler 中的 panic 返回 500，不会导致进程退出
+	app.Use(apierror.Recover())
 
 	// 添加 CORS 中间件
 	app.Use(cors.New())
 
-	// Register health check routes
-	api.RegisterHealthRoutes(app)
+	// 健康检查：/health 为存活探针，/ready 为就绪探针，关闭时先让 /ready 失败
+	ready := new(atomic.Bool)
+	admin.RegisterHealthRoutes(app, ready)
 
 	// Register documentation routes
 	api.RegisterDocsRoutes(app)
 
-	ankrURL := fmt.Sprintf("https://rpc.ankr.com/multichain/%s", os.Getenv("ANKR_API_KEY"))
+	// 按 IP 和 API key 限流，RATE_LIMIT_BACKEND 可选 memory（默认）、redis（多副本共享）或 none
+	// RATE_LIMIT_KEY / RATE_LIMIT_IP 格式为 "600/1m,/api/user/*/portfolio=60/1m"
+	var rateLimitStore ratelimit.Store
+	switch cfg.RateLimit.Backend {
+	case "memory":
+		rateLimitStore = ratelimit.NewMemoryStore()
+	case "redis":
+		rateLimitStore = ratelimit.NewRedisStore(redisClient(), "web3-smartwatch:ratelimit:")
+	}
+	rateLimitConfig := ratelimit.Config{
+		Store:       rateLimitStore,
+		KeyLimits:   cfg.RateLimit.Key,
+		IPLimits:    cfg.RateLimit.IP,
+		ExemptPaths: auth.DefaultExemptPaths,
+	}
+	// IP 限流在认证之前，无效 key 的请求同样计入 IP 限额
+	if rateLimitStore != nil {
+		app.Use(ratelimit.IPMiddleware(rateLimitConfig))
+	}
+
+	// API key 认证，/health、/ready 和 /docs 无需认证；key 通过 go run ./cmd/apikey 管理
+	if cfg.Auth.Disabled {
+		slog.Warn("API key authentication is disabled", "env", "AUTH_DISABLED")
+	} else {
+		keyStore, err := auth.NewFileKeyStore(cfg.Auth.KeysFile)
+		if err != nil {
+			log.Fatalf("failed to load API keys: %v", err)
+		}
+		app.Use(auth.Middleware(keyStore, auth.DefaultExemptPaths...))
+	}
+
+	if rateLimitStore != nil {
+		app.Use(ratelimit.KeyMiddleware(rateLimitConfig))
+	}
+
+	// 每个请求的超时预算（REQUEST_TIMEOUT），服务层的上游调用继承 UserContext 的 deadline，超时返回 504
+	app.Use(timeout.Middleware(cfg.Server.RequestTimeout, func(path string) bool {
+		return auth.IsExempt(path, auth.DefaultExemptPaths)
+	}))
+
+	// 所有上游调用共用一个连接池，失败时按指数退避重试；每个上游方法失败率过高时熔断，直接返回 503
+	breakers := breaker.NewSet(breaker.Config(cfg.Breaker))
+	services.ConfigureUpstreamClient(services.ClientOptions(cfg.Upstream), breakers)
+
+	// 余额、NFT、价格、交易和转账共用同一个 Ankr Advanced API 地址
+	ankrURL := cfg.AnkrURL()
 	ankrService := services.NewAnkrService(ankrURL)
-	nftService := services.NewNFTService()
-	server := server.NewServer(ankrService, nftService)
+	nftService := services.NewNFTService(ankrURL)
+
+	// 数据提供方：Ankr 始终可用，其他提供方在配置了 API key 时启用
+	providers := map[string]services.Provider{
+		"ankr": services.NewAnkrProvider(ankrService, nftService),
+	}
+	if alchemy := cfg.Providers.Alchemy; alchemy.APIKey != "" {
+		providers["alchemy"] = services.NewAlchemyProvider(alchemy.BaseURL, alchemy.APIKey)
+	}
+	if moralis := cfg.Providers.Moralis; moralis.APIKey != "" {
+		providers["moralis"] = services.NewMoralisProvider(moralis.BaseURL, moralis.APIKey)
+	}
+	if covalent := cfg.Providers.Covalent; covalent.APIKey != "" {
+		providers["covalent"] = services.NewCovalentProvider(covalent.BaseURL, covalent.APIKey)
+	}
+
+	// PROVIDER_DEFAULT 和 PROVIDER_CHAINS 中可以用 "|" 指定备用提供方，例如 "eth=alchemy|ankr,polygon=moralis"
+	failoverOptions := services.FailoverOptions{
+		AttemptTimeout: cfg.Providers.Timeout,
+		HedgeDelay:     cfg.Providers.HedgeDelay,
+	}
+	providerRouter, err := services.NewProviderRouter(providers, cfg.Providers.Default, cfg.Providers.Chains, failoverOptions)
+	if err != nil {
+		log.Fatalf("invalid provider configuration: %v", err)
+	}
+	ankrService = providerRouter
+	nftService = providerRouter
+
+	// 原生币余额默认通过 Ankr 的各链 RPC 节点读取
+	rpcEndpoints := make(map[string]string)
+	for _, chain := range services.SupportedChains() {
+		rpcEndpoints[chain.ID] = cfg.AnkrRPCURL(chain.ID)
+		if fakeRPC != nil {
+			rpcEndpoints[chain.ID] = fakeRPC.URL
+		}
+	}
+
+	// 配置了 BALANCE_RPC_URLS 时，代币余额直接通过 JSON-RPC 节点读取（例如本地开发链），
+	// BALANCE_RPC_TOKENS 列出需要查询的 ERC20 合约
+	if len(cfg.Balance.RPCURLs) > 0 {
+		rpcEndpoints = cfg.Balance.RPCURLs
+		ankrService = services.NewRPCBalanceService(rpcEndpoints, cfg.Balance.RPCTokens, cfg.Balance.MulticallAddress)
+	}
+
+	// 余额结果的第一页始终以各链原生币开头，NATIVE_ALWAYS_INCLUDE=true 时余额为 0 也返回
+	priceService := services.NewAnkrPriceService(ankrURL)
+	ankrService = services.NewNativeTokenService(
+		ankrService,
+		services.NewRPCBalanceService(rpcEndpoints, nil, ""),
+		priceService,
+		cfg.Balance.NativeAlwaysInclude,
+	)
+
+	// 缓存 Ankr 查询结果，CACHE_BACKEND 可选 memory（默认）、redis 或 none
+	cacheStats := cache.NewStats()
+	cacheTTLs := services.CacheTTLs{
+		Tokens:  cfg.Cache.TTLTokens,
+		Balance: cfg.Cache.TTLBalance,
+		NFTs:    cfg.Cache.TTLNFTs,
+		Stale:   cfg.Cache.TTLStale,
+	}
+	var cacheStore cache.Store
+	switch cfg.Cache.Backend {
+	case "memory":
+		cacheStore = cache.NewMemoryStore(cfg.Cache.MaxEntries)
+	case "redis":
+		cacheStore = cache.NewRedisStore(redisClient(), "web3-smartwatch:cache:")
+	}
+	if cacheStore != nil {
+		ankrService = services.NewCachedAnkrService(ankrService, cacheStore, cacheTTLs, cacheStats)
+		nftService = services.NewCachedNFTService(nftService, cacheStore, cacheTTLs, cacheStats)
+	}
+	metrics.RegisterCacheStats(cacheStats)
+	metrics.RegisterBreakers(breakers)
+
+	// 资产汇总基于（已缓存的）余额分页，24 小时变化使用的历史价格按小时缓存
+	portfolioPrices := priceService
+	if cacheStore != nil {
+		portfolioPrices = services.NewCachedPriceService(priceService, cacheStore, cacheStats)
+	}
+	portfolioService := services.NewPortfolioService(ankrService, portfolioPrices)
+
+	// 交易记录
+	transactionService := services.NewTransactionService(ankrURL)
+
+	// 代币转账记录，ERC20 元数据与余额接口共用
+	transferService := services.NewTransferService(ankrURL, ankrService)
+
+	// DID 解析器：did:ethr 在配置了 Ankr key 时通过链上注册表查询 owner
+	ethrRPCURLs := map[string]string{}
+	if cfg.Ankr.APIKey != "" {
+		ethrRPCURLs["mainnet"] = cfg.AnkrRPCURL("eth")
+	}
+	didResolver := did.NewMethodResolver()
+	didResolver.Register("pkh", did.NewPKHResolver())
+	didResolver.Register("ethr", did.NewEthrResolver(ethrRPCURLs))
+	didResolver.Register("web", did.NewWebResolver("https"))
+
+	// 地址与 DID 的关联表
+	didRegistry, err := did.NewFileRegistry(cfg.DID.RegistryFile)
+	if err != nil {
+		log.Fatalf("failed to load DID registry: %v", err)
+	}
+
+	// 签名挑战有效期 10 分钟，最多同时保存 10000 个未使用的挑战
+	didLinker := did.NewLinker(didRegistry, didResolver, did.NewChallengeStore(10*time.Minute, 10000))
+
+	server := server.NewServer(ankrService, nftService, portfolioService, transactionService, transferService, didResolver, didRegistry, didLinker, cfg.Server.DefaultPageSize)
 
 	api.RegisterHandlers(app, server)
-	log.Fatal(app.Listen(":8080"))
+
+	// Prometheus 指标和运维接口使用单独的端口（METRICS_ADDR，默认 :9090），不经过 ingress 对外暴露，也不需要 API key
+	metricsApp := fiber.New(fiber.Config{DisableStartupMessage: true, ErrorHandler: apierror.Handler})
+	metricsApp.Get("/metrics", metrics.Handler())
+	admin.RegisterLogRoutes(metricsApp, logLevel)
+	admin.RegisterCacheRoutes(metricsApp, cacheStats)
+	admin.RegisterBreakerRoutes(metricsApp, breakers)
+	go func() {
+		if err := metricsApp.Listen(cfg.Metrics.Addr); err != nil {
+			log.Fatalf("metrics server failed: %v", err)
+		}
+	}()
+
+	// 关闭时在响应中带上 Connection: close，让 keep-alive 客户端改用其他实例
+	app.Server().CloseOnShutdown = true
+	go func() {
+		if err := app.Listen(cfg.Server.Addr); err != nil {
+			log.Fatalf("server failed: %v", err)
+		}
+	}()
+	ready.Store(true)
+
+	// 收到 SIGTERM（k8s 滚动更新）或 SIGINT 后优雅关闭
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	<-signalCtx.Done()
+	stop()
+
+	// 1. /ready 开始失败，等待负载均衡摘除本实例，期间仍正常处理请求
+	slog.Info("shutting down", "delay", cfg.Server.ShutdownDelay.String(), "timeout", cfg.Server.ShutdownTimeout.String())
+	ready.Store(false)
+	time.Sleep(cfg.Server.ShutdownDelay)
+
+	// 2. 停止接受新连接，等待处理中的请求完成，超时后强制关闭
+	if err := app.ShutdownWithTimeout(cfg.Server.ShutdownTimeout); err != nil {
+		slog.Warn("in-flight requests did not finish before shutdown timeout", "error", err)
+	}
+
+	// 3. 刷新未导出的 span，关闭 Redis 连接池和指标端口（请求处理完后指标不再变化）
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("failed to flush traces", "error", err)
+	}
+	if sharedRedis != nil {
+		if err := sharedRedis.Close(); err != nil {
+			slog.Warn("failed to close redis client", "error", err)
+		}
+	}
+	if err := metricsApp.ShutdownWithContext(flushCtx); err != nil {
+		slog.Warn("failed to stop metrics server", "error", err)
+	}
+
+	slog.Info("shutdown complete")
+	_ = os.Stdout.Sync()
 }
@@ -0,0 +1,19 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/breaker"
+)
+
+// RegisterBreakerRoutes registers the upstream circuit breaker diagnostics endpoint
+func RegisterBreakerRoutes(app *fiber.App, breakers *breaker.Set) {
+	app.Get("/debug/circuit-breakers", func(c *fiber.Ctx) error {
+		statuses := breakers.Snapshot()
+		if statuses == nil {
+			statuses = []breaker.Status{}
+		}
+		return c.JSON(fiber.Map{
+			"breakers": statuses,
+		})
+	})
+}
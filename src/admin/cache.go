@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/cache"
+)
+
+// RegisterCacheRoutes registers the cache statistics endpoint
+func RegisterCacheRoutes(app *fiber.App, stats *cache.Stats) {
+	app.Get("/debug/cache", func(c *fiber.Ctx) error {
+		routes := fiber.Map{}
+		for name, counts := range stats.Snapshot() {
+			hitRatio := 0.0
+			if total := counts.Hits + counts.Misses; total > 0 {
+				hitRatio = float64(counts.Hits) / float64(total)
+			}
+			routes[name] = fiber.Map{
+				"hits":     counts.Hits,
+				"misses":   counts.Misses,
+				"hitRatio": hitRatio,
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"routes": routes,
+		})
+	})
+}
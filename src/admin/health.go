@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/apierror"
+)
+
+// RegisterHealthRoutes registers health check endpoints
+// /health is the liveness probe and always succeeds while the process is serving;
+// /ready is the readiness probe and fails with 503 once ready is false, e.g. during shutdown
+func RegisterHealthRoutes(app *fiber.App, ready *atomic.Bool) {
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{
+			"status": "ok",
+		})
+	})
+
+	app.Get("/ready", func(c *fiber.Ctx) error {
+		if !ready.Load() {
+			return apierror.Unavailable("not_ready", "Server is shutting down")
+		}
+		return c.Status(200).JSON(fiber.Map{
+			"status": "ready",
+		})
+	})
+}
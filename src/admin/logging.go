@@ -0,0 +1,38 @@
+// Package admin 运维接口：健康检查注册在业务端口供探针使用，日志级别、缓存和熔断器状态只注册在不对外暴露的管理端口（METRICS_ADDR）上
+package admin
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/apierror"
+)
+
+// RegisterLogRoutes registers the runtime log level endpoints
+func RegisterLogRoutes(app *fiber.App, level *slog.LevelVar) {
+	app.Get("/debug/log-level", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"level": strings.ToLower(level.Level().String()),
+		})
+	})
+
+	app.Put("/debug/log-level", func(c *fiber.Ctx) error {
+		var body struct {
+			Level string `json:"level"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apierror.Validation("", "invalid_request", "Invalid request body")
+		}
+
+		var newLevel slog.Level
+		if err := newLevel.UnmarshalText([]byte(body.Level)); err != nil {
+			return apierror.Validation("level", "invalid_level", "level must be one of debug, info, warn, error")
+		}
+		level.Set(newLevel)
+
+		return c.JSON(fiber.Map{
+			"level": strings.ToLower(newLevel.String()),
+		})
+	})
+}
@@ -0,0 +1,269 @@
+// Package apierror API 错误分类：每类错误对应固定的 HTTP 状态码和响应中的 code，
+// 具体原因（如 invalid_address）和请求 ID 放在响应的 details 中
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/breaker"
+	"github.com/web3-smart-wallet/src/logging"
+	"github.com/web3-smart-wallet/src/services"
+)
+
+// 响应中的错误码
+const (
+	// CodeValidation 请求参数或请求体不合法，400
+	CodeValidation = "validation_error"
+	// CodeUnauthorized API key 或签名等身份校验失败，401
+	CodeUnauthorized = "unauthorized"
+	// CodeForbidden 凭据有效但没有访问权限（如 API key 已停用），403
+	CodeForbidden = "forbidden"
+	// CodeNotFound 请求的资源不存在，404
+	CodeNotFound = "not_found"
+	// CodeConflict 与已有数据冲突，409
+	CodeConflict = "conflict"
+	// CodeRateLimited 客户端（API key 或 IP）超出本服务的限流额度，429
+	CodeRateLimited = "rate_limited"
+	// CodeUpstreamRateLimited 上游数据提供方限流，429
+	CodeUpstreamRateLimited = "upstream_rate_limited"
+	// CodeInternal 服务内部错误（包括 panic），500
+	CodeInternal = "internal_server_error"
+	// CodeUpstreamError 上游数据提供方返回错误或拒绝了我们的凭据，502
+	CodeUpstreamError = "upstream_error"
+	// CodeUnavailable 服务本身暂不可用（如正在关闭），503
+	CodeUnavailable = "service_unavailable"
+	// CodeUpstreamUnavailable 上游数据提供方不可用（如熔断），503
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	// CodeTimeout 请求超出超时预算，504
+	CodeTimeout = "upstream_timeout"
+)
+
+// Error 可以直接从 handler 返回的 API 错误，由 Handler 写出响应
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details map[string]interface{}
+	// RetryAfter 大于 0 时写入 Retry-After 响应头
+	RetryAfter time.Duration
+	// Err 原始错误，只写入日志，不返回给客户端
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Code, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// With 在 details 中添加一项
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// Response 返回 api.Error 格式的响应体
+func (e *Error) Response() api.Error {
+	response := api.Error{
+		Code:    e.Code,
+		Message: e.Message,
+	}
+	if len(e.Details) > 0 {
+		details := e.Details
+		response.Details = &details
+	}
+	return response
+}
+
+// Validation 参数不合法，field 为参数名（请求体整体不合法时为空），reason 为具体原因，如 invalid_address
+func Validation(field string, reason string, message string) *Error {
+	e := newError(fiber.StatusBadRequest, CodeValidation, message).With("reason", reason)
+	if field != "" {
+		e.With("field", field)
+	}
+	return e
+}
+
+// Unauthorized 身份校验失败，reason 为具体原因，如 invalid_signature
+func Unauthorized(reason string, message string) *Error {
+	return newError(fiber.StatusUnauthorized, CodeUnauthorized, message).With("reason", reason)
+}
+
+// Forbidden 没有访问权限，reason 为具体原因，如 api_key_disabled
+func Forbidden(reason string, message string) *Error {
+	return newError(fiber.StatusForbidden, CodeForbidden, message).With("reason", reason)
+}
+
+// NotFound 资源不存在，reason 为具体原因，如 did_not_found
+func NotFound(reason string, message string) *Error {
+	return newError(fiber.StatusNotFound, CodeNotFound, message).With("reason", reason)
+}
+
+// Conflict 与已有数据冲突，reason 为具体原因，如 did_conflict
+func Conflict(reason string, message string) *Error {
+	return newError(fiber.StatusConflict, CodeConflict, message).With("reason", reason)
+}
+
+// RateLimited 客户端超出限流额度，retryAfter 为下一个令牌可用的时间
+func RateLimited(retryAfter time.Duration) *Error {
+	e := newError(fiber.StatusTooManyRequests, CodeRateLimited, "Too many requests")
+	e.RetryAfter = retryAfter
+	return e
+}
+
+// UpstreamRateLimited 上游限流，retryAfter 为建议的重试等待时间，未知时为 0
+func UpstreamRateLimited(retryAfter time.Duration) *Error {
+	e := newError(fiber.StatusTooManyRequests, CodeUpstreamRateLimited, "Upstream data provider is rate limiting requests, please retry later")
+	e.RetryAfter = retryAfter
+	return e
+}
+
+// UpstreamFailed 上游返回错误，reason 为具体原因，如 upstream_auth_failed
+func UpstreamFailed(reason string, message string) *Error {
+	return newError(fiber.StatusBadGateway, CodeUpstreamError, message).With("reason", reason)
+}
+
+// Unavailable 服务本身暂不可用，reason 为具体原因，如 not_ready
+func Unavailable(reason string, message string) *Error {
+	return newError(fiber.StatusServiceUnavailable, CodeUnavailable, message).With("reason", reason)
+}
+
+// UpstreamUnavailable 上游不可用，retryAfter 为建议的重试等待时间，未知时为 0
+func UpstreamUnavailable(retryAfter time.Duration) *Error {
+	e := newError(fiber.StatusServiceUnavailable, CodeUpstreamUnavailable, "Upstream data provider is temporarily unavailable, please retry later")
+	e.RetryAfter = retryAfter
+	return e
+}
+
+// Timeout 请求超出超时预算，budget 未知时为 0
+func Timeout(budget time.Duration) *Error {
+	if budget <= 0 {
+		return newError(fiber.StatusGatewayTimeout, CodeTimeout, "The request did not complete in time")
+	}
+	return newError(fiber.StatusGatewayTimeout, CodeTimeout, "The request did not complete within "+budget.String()).
+		With("timeout", budget.String())
+}
+
+// Internal 内部错误，err 只写入日志，响应中不包含错误内容
+func Internal(err error) *Error {
+	e := newError(fiber.StatusInternalServerError, CodeInternal, "Internal server error")
+	e.Err = err
+	return e
+}
+
+// From 将任意错误归类：*Error 原样返回，无法解析的分页游标返回 Validation，熔断返回 UpstreamUnavailable，*services.UpstreamError 按类型归类，超时返回 Timeout，
+// fiber.Error（如路由不存在、参数格式错误）按状态码归类，其余为 Internal
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	// 分页游标由客户端传入，即使在故障转移中被包装也属于请求错误，不能归为上游故障
+	if errors.Is(err, services.ErrInvalidPageToken) {
+		e := Validation("pageToken", "invalid_page_token", "Invalid page token")
+		e.Err = err
+		return e
+	}
+
+	var open *breaker.OpenError
+	if errors.As(err, &open) {
+		e := UpstreamUnavailable(open.RetryAfter)
+		e.Err = err
+		return e
+	}
+
+	var upstream *services.UpstreamError
+	if errors.As(err, &upstream) {
+		e := fromUpstream(upstream)
+		e.Err = err
+		return e
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		e := Timeout(0)
+		e.Err = err
+		return e
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		switch fiberErr.Code {
+		case fiber.StatusBadRequest:
+			return Validation("", "invalid_request", fiberErr.Message)
+		case fiber.StatusNotFound:
+			return NotFound("route_not_found", fiberErr.Message)
+		}
+		if fiberErr.Code < fiber.StatusInternalServerError {
+			return newError(fiberErr.Code, strings.ReplaceAll(strings.ToLower(utils.StatusMessage(fiberErr.Code)), " ", "_"), fiberErr.Message)
+		}
+	}
+	return Internal(err)
+}
+
+// Handler 作为 fiber 的 ErrorHandler，把错误归类后写出 api.Error 响应，details 中带上请求 ID；
+// 5xx 的原始错误写入日志
+func Handler(c *fiber.Ctx, err error) error {
+	e := From(err)
+	if e.Status >= fiber.StatusInternalServerError && e.Err != nil {
+		logging.FromContext(c.UserContext()).Error("request failed", "code", e.Code, "error", e.Err)
+	}
+
+	response := e.Response()
+	if requestID := c.GetRespHeader(logging.RequestIDHeader); requestID != "" {
+		details := make(map[string]interface{}, len(e.Details)+1)
+		for key, value := range e.Details {
+			details[key] = value
+		}
+		details["requestId"] = requestID
+		response.Details = &details
+	}
+	if e.RetryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+	}
+	return c.Status(e.Status).JSON(response)
+}
+
+// 辅助函数：上游错误的响应，使用固定的 message，上游返回的原始信息（可能包含内部细节）只写入日志
+func fromUpstream(upstream *services.UpstreamError) *Error {
+	switch upstream.Kind {
+	case services.UpstreamInvalidParams:
+		return Validation("", "upstream_invalid_params", "The upstream data provider rejected the request parameters")
+	case services.UpstreamRateLimited:
+		return UpstreamRateLimited(upstream.RetryAfter)
+	case services.UpstreamAuthFailed:
+		return UpstreamFailed("upstream_auth_failed", "The upstream data provider rejected this service's credentials")
+	case services.UpstreamUnsupportedChain:
+		// 请求的链本身不被支持，属于请求错误，不是上游故障
+		return Validation("chain", "unsupported_chain", "The upstream data provider does not support the requested chain")
+	case services.UpstreamTimeout:
+		e := Timeout(0).With("reason", "upstream_timeout")
+		e.Message = "The upstream data provider did not respond in time"
+		return e
+	}
+	return UpstreamFailed("upstream_error", "The upstream data provider returned an error")
+}
+
+func newError(status int, code string, message string) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
@@ -0,0 +1,148 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/breaker"
+	"github.com/web3-smart-wallet/src/services"
+)
+
+func TestFrom(t *testing.T) {
+	upstream := func(kind services.UpstreamErrorKind) error {
+		return fmt.Errorf("alchemy api error: %w", &services.UpstreamError{Kind: kind, Method: "ankr_getAccountBalance", RetryAfter: 3 * time.Second, Err: errors.New("rpc error -32000: node ankr-eu-1 key=secret")})
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantReason string
+		wantField  string
+		wantRetry  time.Duration
+		// wantCause 原始错误保留在 Err 中，只写入日志
+		wantCause bool
+	}{
+		{name: "api error", err: NotFound("did_not_found", "DID not found"), wantStatus: 404, wantCode: CodeNotFound, wantReason: "did_not_found"},
+		{name: "wrapped api error", err: fmt.Errorf("handler: %w", Conflict("did_conflict", "conflict")), wantStatus: 409, wantCode: CodeConflict, wantReason: "did_conflict"},
+		{name: "invalid page token", err: fmt.Errorf("all providers failed: %w", services.ErrInvalidPageToken), wantStatus: 400, wantCode: CodeValidation, wantReason: "invalid_page_token", wantField: "pageToken", wantCause: true},
+		{name: "breaker open", err: fmt.Errorf("ankr: %w", &breaker.OpenError{Name: "ankr", RetryAfter: 5 * time.Second}), wantStatus: 503, wantCode: CodeUpstreamUnavailable, wantRetry: 5 * time.Second, wantCause: true},
+		{name: "upstream invalid params", err: upstream(services.UpstreamInvalidParams), wantStatus: 400, wantCode: CodeValidation, wantReason: "upstream_invalid_params", wantCause: true},
+		{name: "upstream rate limited", err: upstream(services.UpstreamRateLimited), wantStatus: 429, wantCode: CodeUpstreamRateLimited, wantRetry: 3 * time.Second, wantCause: true},
+		{name: "upstream auth failed", err: upstream(services.UpstreamAuthFailed), wantStatus: 502, wantCode: CodeUpstreamError, wantReason: "upstream_auth_failed", wantCause: true},
+		{name: "unsupported chain", err: upstream(services.UpstreamUnsupportedChain), wantStatus: 400, wantCode: CodeValidation, wantReason: "unsupported_chain", wantField: "chain", wantCause: true},
+		{name: "upstream timeout", err: upstream(services.UpstreamTimeout), wantStatus: 504, wantCode: CodeTimeout, wantReason: "upstream_timeout", wantCause: true},
+		{name: "upstream failed", err: upstream(services.UpstreamFailed), wantStatus: 502, wantCode: CodeUpstreamError, wantReason: "upstream_error", wantCause: true},
+		{name: "deadline exceeded", err: fmt.Errorf("request: %w", context.DeadlineExceeded), wantStatus: 504, wantCode: CodeTimeout, wantCause: true},
+		{name: "fiber bad request", err: fiber.NewError(fiber.StatusBadRequest, "bad body"), wantStatus: 400, wantCode: CodeValidation, wantReason: "invalid_request"},
+		{name: "fiber not found", err: fiber.ErrNotFound, wantStatus: 404, wantCode: CodeNotFound, wantReason: "route_not_found"},
+		{name: "fiber method not allowed", err: fiber.ErrMethodNotAllowed, wantStatus: 405, wantCode: "method_not_allowed"},
+		{name: "fiber 5xx", err: fiber.ErrBadGateway, wantStatus: 500, wantCode: CodeInternal, wantCause: true},
+		{name: "other error", err: errors.New("open /etc/keys.json: permission denied"), wantStatus: 500, wantCode: CodeInternal, wantCause: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := From(tt.err)
+			if e.Status != tt.wantStatus || e.Code != tt.wantCode {
+				t.Fatalf("From = %d %s, want %d %s", e.Status, e.Code, tt.wantStatus, tt.wantCode)
+			}
+			if reason, _ := e.Details["reason"].(string); reason != tt.wantReason {
+				t.Fatalf("reason = %q, want %q", reason, tt.wantReason)
+			}
+			if field, _ := e.Details["field"].(string); field != tt.wantField {
+				t.Fatalf("field = %q, want %q", field, tt.wantField)
+			}
+			if e.RetryAfter != tt.wantRetry {
+				t.Fatalf("RetryAfter = %s, want %s", e.RetryAfter, tt.wantRetry)
+			}
+			if tt.wantCause && !errors.Is(e.Err, tt.err) {
+				t.Fatalf("Err = %v, want the original error", e.Err)
+			}
+		})
+	}
+}
+
+// 响应只包含固定的 message，上游的原始信息不会返回给客户端
+func TestHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Set("X-Request-ID", "req-1")
+		return c.Next()
+	})
+	app.Get("/upstream", func(c *fiber.Ctx) error {
+		return &services.UpstreamError{Kind: services.UpstreamRateLimited, RetryAfter: 1500 * time.Millisecond, Err: errors.New("key=secret")}
+	})
+	app.Get("/error", func(c *fiber.Ctx) error {
+		return errors.New("database password=hunter2")
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantCode   string
+		wantRetry  string
+	}{
+		{path: "/upstream", wantStatus: 429, wantCode: CodeUpstreamRateLimited, wantRetry: "2"},
+		{path: "/error", wantStatus: 500, wantCode: CodeInternal},
+		{path: "/missing", wantStatus: 404, wantCode: CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+
+			var response struct {
+				Code    string                 `json:"code"`
+				Message string                 `json:"message"`
+				Details map[string]interface{} `json:"details"`
+			}
+			if err := json.Unmarshal(body, &response); err != nil {
+				t.Fatalf("invalid body %s: %v", body, err)
+			}
+			if resp.StatusCode != tt.wantStatus || response.Code != tt.wantCode {
+				t.Fatalf("response = %d %s, want %d %s", resp.StatusCode, response.Code, tt.wantStatus, tt.wantCode)
+			}
+			if response.Details["requestId"] != "req-1" {
+				t.Fatalf("details.requestId = %v, want req-1", response.Details["requestId"])
+			}
+			if got := resp.Header.Get("Retry-After"); got != tt.wantRetry {
+				t.Fatalf("Retry-After = %q, want %q", got, tt.wantRetry)
+			}
+			for _, secret := range []string{"secret", "hunter2"} {
+				if strings.Contains(string(body), secret) {
+					t.Fatalf("response leaks %q: %s", secret, body)
+				}
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: Handler})
+	app.Use(Recover())
+	app.Get("/", func(c *fiber.Ctx) error {
+		panic("boom")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", resp.StatusCode)
+	}
+}
@@ -0,0 +1,23 @@
+package apierror
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/logging"
+)
+
+// Recover 捕获后续 handler 中的 panic，记录堆栈后返回 500；
+// 放在日志和指标中间件之后，panic 的请求也会记录访问日志和指标
+func Recover() fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.FromContext(c.UserContext()).Error("panic recovered", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+				err = Internal(nil)
+			}
+		}()
+		return c.Next()
+	}
+}
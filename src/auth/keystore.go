@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound API key 不存在
+var ErrKeyNotFound = errors.New("api key not found")
+
+// 生成的 API key 前缀，便于在日志和配置中识别
+const keyPrefix = "wsk_"
+
+// Key 是一条 API key 记录，只保存 key 的 SHA-256 哈希
+type Key struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// KeyStore 保存 API key
+type KeyStore interface {
+	// Lookup 按明文 key 查找记录；不存在时返回 ErrKeyNotFound
+	Lookup(key string) (*Key, error)
+	// Create 创建新的 key，返回明文 key（只在创建时可见）和记录
+	Create(name string) (string, *Key, error)
+	// SetEnabled 启用或停用 key
+	SetEnabled(id string, enabled bool) error
+	// List 返回所有记录
+	List() ([]Key, error)
+}
+
+// 检查 key 文件是否被修改的最小间隔
+const reloadInterval = 2 * time.Second
+
+// FileKeyStore 基于 JSON 文件持久化的 key 存储，path 为空时只保存在内存中
+// 文件被其他进程（如 cmd/apikey）修改后会自动重新加载，停用 key 无需重启服务
+type FileKeyStore struct {
+	mu        sync.RWMutex
+	path      string
+	keys      []Key
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+type keyStoreFile struct {
+	Keys []Key `json:"keys"`
+}
+
+// NewFileKeyStore 创建 key 存储，并从 path 加载已有记录（文件不存在时视为空）
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	s := &FileKeyStore{
+		path: path,
+		keys: make([]Key, 0),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileKeyStore) Lookup(key string) (*Key, error) {
+	hash := HashKey(key)
+	s.reloadIfChanged()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			found := k
+			return &found, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (s *FileKeyStore) Create(name string) (string, *Key, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return "", nil, err
+	}
+	plain := keyPrefix + secret
+
+	key := Key{
+		ID:        id,
+		Name:      name,
+		Hash:      HashKey(plain),
+		Enabled:   true,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := append(append(make([]Key, 0, len(s.keys)+1), s.keys...), key)
+	if err := s.replace(keys); err != nil {
+		return "", nil, err
+	}
+	return plain, &key, nil
+}
+
+func (s *FileKeyStore) SetEnabled(id string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, k := range s.keys {
+		if k.ID == id {
+			keys := append(make([]Key, 0, len(s.keys)), s.keys...)
+			keys[i].Enabled = enabled
+			return s.replace(keys)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+}
+
+func (s *FileKeyStore) List() ([]Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append(make([]Key, 0, len(s.keys)), s.keys...), nil
+}
+
+// 文件修改时间变化时重新加载，加载失败时保留原有记录
+// 每次 Lookup 都会调用，未到检查间隔时只持有读锁；stat 在锁外执行，只有文件确实变化时才持有写锁
+func (s *FileKeyStore) reloadIfChanged() {
+	if s.path == "" {
+		return
+	}
+
+	s.mu.RLock()
+	due := time.Since(s.checkedAt) >= reloadInterval
+	s.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	info, err := os.Stat(s.path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 其他请求可能已经完成了这次检查
+	if time.Since(s.checkedAt) < reloadInterval {
+		return
+	}
+	s.checkedAt = time.Now()
+	if err != nil || info.ModTime().Equal(s.modTime) {
+		return
+	}
+	if err := s.load(); err != nil {
+		slog.Warn("failed to reload api key store", "error", err)
+	}
+}
+
+// 从文件加载记录（文件不存在时视为空），调用方需持有写锁或处于初始化阶段
+func (s *FileKeyStore) load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read api key store: %v", err)
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read api key store: %v", err)
+	}
+
+	var file keyStoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to decode api key store: %v", err)
+	}
+	s.keys = append(make([]Key, 0, len(file.Keys)), file.Keys...)
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// HashKey 返回 key 的 SHA-256 十六进制哈希
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// 先持久化新的记录，成功后再替换内存中的记录，调用方需持有写锁
+func (s *FileKeyStore) replace(keys []Key) error {
+	if err := s.save(keys); err != nil {
+		return err
+	}
+	s.keys = keys
+	return nil
+}
+
+// 将记录写入临时文件后原子替换，文件只对所有者可读写
+func (s *FileKeyStore) save(keys []Key) error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(keyStoreFile{Keys: keys}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode api key store: %v", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create api key store directory: %v", err)
+		}
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write api key store: %v", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to write api key store: %v", err)
+	}
+
+	return nil
+}
+
+// 辅助函数：生成 n 字节的随机十六进制字符串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "api_keys.json")
+	store, err := NewFileKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, key, err := store.Create("dashboard")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(plain, keyPrefix) || key.Hash != HashKey(plain) || !key.Enabled {
+		t.Fatalf("unexpected key %q: %+v", plain, key)
+	}
+
+	// 文件中只保存哈希，且只对所有者可读写
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), plain) {
+		t.Fatal("key store file contains the plain key")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("key store file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	tests := []struct {
+		name        string
+		key         string
+		wantErr     error
+		wantEnabled bool
+	}{
+		{name: "known key", key: plain, wantEnabled: true},
+		{name: "unknown key", key: keyPrefix + "unknown", wantErr: ErrKeyNotFound},
+		{name: "hash is not a key", key: key.Hash, wantErr: ErrKeyNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := store.Lookup(tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lookup error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (found.ID != key.ID || found.Enabled != tt.wantEnabled) {
+				t.Fatalf("Lookup = %+v", found)
+			}
+		})
+	}
+
+	if err := store.SetEnabled(key.ID, false); err != nil {
+		t.Fatalf("SetEnabled: %v", err)
+	}
+	if err := store.SetEnabled("missing", false); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("SetEnabled unknown id error = %v, want ErrKeyNotFound", err)
+	}
+
+	// 重新加载后状态保持
+	reloaded, err := NewFileKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := reloaded.Lookup(plain)
+	if err != nil || found.Enabled {
+		t.Fatalf("Lookup after reload = %+v, %v; want disabled key", found, err)
+	}
+}
+
+// 其他进程（如 cmd/apikey）修改文件后，检查间隔过后自动重新加载
+func TestFileKeyStoreReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	server, err := NewFileKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cli, _ := NewFileKeyStore(path)
+	plain, key, err := cli.Create("mobile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 确保修改时间变化，部分文件系统的时间精度较低
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+
+	// 刚检查过时不重新加载
+	server.mu.Lock()
+	server.checkedAt = time.Now()
+	server.mu.Unlock()
+	if _, err := server.Lookup(plain); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Lookup within the reload interval error = %v, want ErrKeyNotFound", err)
+	}
+
+	// 跳过检查间隔
+	server.mu.Lock()
+	server.checkedAt = time.Time{}
+	server.mu.Unlock()
+	found, err := server.Lookup(plain)
+	if err != nil || found.ID != key.ID {
+		t.Fatalf("Lookup after reload = %+v, %v", found, err)
+	}
+
+	// 文件损坏时保留已加载的记录
+	os.WriteFile(path, []byte("{not json"), 0o600)
+	later := future.Add(time.Minute)
+	os.Chtimes(path, later, later)
+	server.mu.Lock()
+	server.checkedAt = time.Time{}
+	server.mu.Unlock()
+	if _, err := server.Lookup(plain); err != nil {
+		t.Fatalf("Lookup after corrupt file = %v, want the previous keys", err)
+	}
+}
+
+func TestNewFileKeyStore(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.json")
+	os.WriteFile(corrupt, []byte("[]"), 0o600)
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "memory only", path: ""},
+		{name: "missing file", path: filepath.Join(dir, "missing.json")},
+		{name: "invalid file", path: corrupt, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewFileKeyStore(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err == nil {
+				if keys, _ := store.List(); len(keys) != 0 {
+					t.Fatalf("List = %v, want empty", keys)
+				}
+			}
+		})
+	}
+}
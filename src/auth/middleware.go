@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/apierror"
+	"github.com/web3-smart-wallet/src/logging"
+)
+
+// HeaderName 是 apispec.yaml 中 ApiKeyAuth 声明的请求头
+const HeaderName = "X-API-Key"
+
+// localsKey 通过认证的 key 记录在 fiber.Ctx.Locals 中的键
+const localsKey = "apiKey"
+
+// DefaultExemptPaths 无需认证的路径前缀
+var DefaultExemptPaths = []string{"/health", "/ready", "/docs"}
+
+// Middleware 校验 X-API-Key 请求头
+// 缺少或未知的 key 返回 401，已停用的 key 返回 403；exemptPaths 中的路径（及其子路径）不校验
+func Middleware(store KeyStore, exemptPaths ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if IsExempt(c.Path(), exemptPaths) {
+			return c.Next()
+		}
+
+		plain := c.Get(HeaderName)
+		if plain == "" {
+			return apierror.Unauthorized("missing_api_key", "X-API-Key header is required")
+		}
+
+		key, err := store.Lookup(plain)
+		if err != nil {
+			if errors.Is(err, ErrKeyNotFound) {
+				return apierror.Unauthorized("invalid_api_key", "Invalid API key")
+			}
+			// key 存储的错误可能包含文件路径等内部信息，只写入日志
+			return apierror.Internal(err)
+		}
+		if !key.Enabled {
+			return apierror.Forbidden("api_key_disabled", "API key is disabled")
+		}
+
+		c.Locals(localsKey, key)
+		c.SetUserContext(logging.WithLogger(c.UserContext(), logging.FromContext(c.UserContext()).With("api_key_id", key.ID)))
+		return c.Next()
+	}
+}
+
+// FromContext 返回当前请求通过认证的 key，未认证（如豁免路径）时返回 nil
+func FromContext(c *fiber.Ctx) *Key {
+	key, _ := c.Locals(localsKey).(*Key)
+	return key
+}
+
+// IsExempt 判断路径是否在豁免列表中，"/docs" 匹配 "/docs" 和 "/docs/..."
+func IsExempt(path string, exemptPaths []string) bool {
+	for _, exempt := range exemptPaths {
+		if path == exempt || strings.HasPrefix(path, strings.TrimRight(exempt, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/apierror"
+)
+
+// 查找总是失败的 key 存储
+type failingStore struct {
+	KeyStore
+}
+
+func (failingStore) Lookup(string) (*Key, error) {
+	return nil, errors.New("open /etc/web3-smartwatch/api-keys/api_keys.json: permission denied")
+}
+
+func TestMiddleware(t *testing.T) {
+	store, _ := NewFileKeyStore("")
+	enabled, _, _ := store.Create("enabled")
+	disabled, disabledKey, _ := store.Create("disabled")
+	store.SetEnabled(disabledKey.ID, false)
+
+	newApp := func(store KeyStore) *fiber.App {
+		app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+		app.Use(Middleware(store, DefaultExemptPaths...))
+		app.Get("/*", func(c *fiber.Ctx) error {
+			key := FromContext(c)
+			if key == nil {
+				return c.SendString("anonymous")
+			}
+			return c.SendString(key.Name)
+		})
+		return app
+	}
+
+	tests := []struct {
+		name       string
+		store      KeyStore
+		path       string
+		key        string
+		wantStatus int
+		wantReason string
+		wantBody   string
+	}{
+		{name: "valid key", store: store, path: "/api/user/0x1", key: enabled, wantStatus: 200, wantBody: "enabled"},
+		{name: "missing key", store: store, path: "/api/user/0x1", wantStatus: 401, wantReason: "missing_api_key"},
+		{name: "unknown key", store: store, path: "/api/user/0x1", key: "wsk_unknown", wantStatus: 401, wantReason: "invalid_api_key"},
+		{name: "disabled key", store: store, path: "/api/user/0x1", key: disabled, wantStatus: 403, wantReason: "api_key_disabled"},
+		{name: "store failure", store: failingStore{}, path: "/api/user/0x1", key: enabled, wantStatus: 500},
+		{name: "exempt health", store: store, path: "/health", wantStatus: 200, wantBody: "anonymous"},
+		{name: "exempt docs subpath", store: store, path: "/docs/index.html", wantStatus: 200, wantBody: "anonymous"},
+		{name: "prefix is not a subpath", store: store, path: "/healthz", wantStatus: 401, wantReason: "missing_api_key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(HeaderName, tt.key)
+			}
+			resp, err := newApp(tt.store).Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			if tt.wantBody != "" && string(body) != tt.wantBody {
+				t.Fatalf("body = %q, want %q", body, tt.wantBody)
+			}
+			if tt.wantStatus >= 400 {
+				var response struct {
+					Details map[string]interface{} `json:"details"`
+				}
+				json.Unmarshal(body, &response)
+				if reason, _ := response.Details["reason"].(string); reason != tt.wantReason {
+					t.Fatalf("reason = %q, want %q", reason, tt.wantReason)
+				}
+				// 存储错误的内容（如文件路径）只写入日志
+				if strings.Contains(string(body), "/etc/") {
+					t.Fatalf("response leaks the store error: %s", body)
+				}
+			}
+		})
+	}
+}
+
+func TestIsExempt(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/health", want: true},
+		{path: "/ready", want: true},
+		{path: "/docs", want: true},
+		{path: "/docs/swagger.json", want: true},
+		{path: "/healthz", want: false},
+		{path: "/api/health", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsExempt(tt.path, DefaultExemptPaths); got != tt.want {
+			t.Errorf("IsExempt(%s) = %t, want %t", tt.path, got, tt.want)
+		}
+	}
+}
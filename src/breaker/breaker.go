@@ -0,0 +1,269 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State 熔断器状态
+type State int
+
+const (
+	// Closed 正常放行请求并统计失败率
+	Closed State = iota
+	// HalfOpen 打开一段时间后放行少量探测请求，全部成功则关闭，任一失败则重新打开
+	HalfOpen
+	// Open 直接拒绝请求
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half_open"
+	case Open:
+		return "open"
+	}
+	return "unknown"
+}
+
+// MarshalText 在 JSON 中输出状态名称
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ErrOpen 熔断器打开时拒绝请求返回的错误，可以用 errors.Is 判断
+var ErrOpen = errors.New("circuit breaker is open")
+
+// OpenError 熔断器拒绝请求的详细信息
+type OpenError struct {
+	Name string
+	// RetryAfter 距离进入半开状态的时间
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit breaker %s is open", e.Name)
+}
+
+func (e *OpenError) Is(target error) bool {
+	return target == ErrOpen
+}
+
+// Config 熔断器配置
+type Config struct {
+	// ErrorRate 统计窗口内失败比例达到该值时打开，0 到 1
+	ErrorRate float64
+	// MinRequests 统计窗口内请求数达到该值才计算失败比例，避免少量请求误判
+	MinRequests int
+	// Window 失败率的统计窗口
+	Window time.Duration
+	// OpenDuration 打开后经过该时间进入半开状态
+	OpenDuration time.Duration
+	// HalfOpenRequests 半开状态下放行的探测请求数
+	HalfOpenRequests int
+}
+
+// Enabled 是否启用熔断
+func (c Config) Enabled() bool {
+	return c.ErrorRate > 0 && c.Window > 0 && c.OpenDuration > 0
+}
+
+// Breaker 单个上游的熔断器
+type Breaker struct {
+	name   string
+	config Config
+
+	mu          sync.Mutex
+	state       State
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+	rejected    uint64
+	// generation 每次状态变化时加一，放行请求时记录，用于忽略之前状态下放行的请求的结果
+	generation uint64
+}
+
+// Status 熔断器当前状态的快照
+type Status struct {
+	Name     string `json:"name"`
+	State    State  `json:"state"`
+	Requests int    `json:"requests"`
+	Failures int    `json:"failures"`
+	// Rejected 累计拒绝的请求数
+	Rejected uint64 `json:"rejected"`
+	// OpenedAt 最近一次打开的时间，从未打开时为空
+	OpenedAt *time.Time `json:"openedAt,omitempty"`
+}
+
+// Allow 判断是否放行请求；放行时返回的 done 必须在请求结束后调用，参数为请求是否失败
+func (b *Breaker) Allow() (func(failed bool), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if b.state == Open {
+		if wait := b.config.OpenDuration - now.Sub(b.openedAt); wait > 0 {
+			b.rejected++
+			return nil, &OpenError{Name: b.name, RetryAfter: wait}
+		}
+		b.setState(HalfOpen)
+		b.probes, b.successes = 0, 0
+	}
+
+	if b.state == HalfOpen {
+		if b.probes >= max(b.config.HalfOpenRequests, 1) {
+			b.rejected++
+			return nil, &OpenError{Name: b.name, RetryAfter: time.Second}
+		}
+		b.probes++
+		generation := b.generation
+		return func(failed bool) { b.doneHalfOpen(generation, failed) }, nil
+	}
+
+	if now.Sub(b.windowStart) >= b.config.Window {
+		b.windowStart = now
+		b.requests, b.failures = 0, 0
+	}
+	generation := b.generation
+	return func(failed bool) { b.doneClosed(generation, failed) }, nil
+}
+
+// 关闭状态下的请求结束，失败率超过阈值时打开
+func (b *Breaker) doneClosed(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 请求期间状态可能已经被其他请求改变（即使又回到了关闭状态，也属于新的统计周期）
+	if b.generation != generation {
+		return
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests >= b.config.MinRequests && float64(b.failures)/float64(b.requests) >= b.config.ErrorRate {
+		b.open()
+	}
+}
+
+// 半开状态下的探测请求结束，任一失败重新打开，全部成功则关闭
+func (b *Breaker) doneHalfOpen(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.generation != generation {
+		return
+	}
+	if failed {
+		b.open()
+		return
+	}
+	b.successes++
+	if b.successes >= max(b.config.HalfOpenRequests, 1) {
+		b.setState(Closed)
+		b.windowStart = time.Now()
+		b.requests, b.failures = 0, 0
+	}
+}
+
+func (b *Breaker) open() {
+	b.setState(Open)
+	b.openedAt = time.Now()
+}
+
+func (b *Breaker) setState(state State) {
+	b.state = state
+	b.generation++
+}
+
+// Status 返回当前状态
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{
+		Name:     b.name,
+		State:    b.state,
+		Requests: b.requests,
+		Failures: b.failures,
+		Rejected: b.rejected,
+	}
+	// 打开后尚未有请求触发状态检查时，按时间判断是否已可以半开
+	if b.state == Open && time.Since(b.openedAt) >= b.config.OpenDuration {
+		status.State = HalfOpen
+	}
+	if !b.openedAt.IsZero() {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// Set 按名称管理一组使用相同配置的熔断器，首次使用时创建
+type Set struct {
+	config Config
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewSet 创建熔断器集合，config 未启用时 Get 返回 nil
+func NewSet(config Config) *Set {
+	return &Set{
+		config:   config,
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+// Get 返回名称对应的熔断器，未启用熔断时返回 nil
+func (s *Set) Get(name string) *Breaker {
+	if s == nil || !s.config.Enabled() {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.breakers[name]
+	if !ok {
+		b = &Breaker{
+			name:        name,
+			config:      s.config,
+			windowStart: time.Now(),
+		}
+		s.breakers[name] = b
+	}
+	return b
+}
+
+// Snapshot 返回所有熔断器的状态，按名称排序
+func (s *Set) Snapshot() []Status {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	breakers := make([]*Breaker, 0, len(s.breakers))
+	for _, b := range s.breakers {
+		breakers = append(breakers, b)
+	}
+	s.mu.Unlock()
+
+	statuses := make([]Status, len(breakers))
+	for i, b := range breakers {
+		statuses[i] = b.Status()
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
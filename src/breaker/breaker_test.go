@@ -0,0 +1,199 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	ErrorRate:        0.5,
+	MinRequests:      4,
+	Window:           time.Minute,
+	OpenDuration:     time.Minute,
+	HalfOpenRequests: 2,
+}
+
+// 辅助函数：放行并立即结束一个请求
+func call(t *testing.T, b *Breaker, failed bool) {
+	t.Helper()
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	done(failed)
+}
+
+// 辅助函数：跳过打开时长，下一次 Allow 进入半开状态
+func expireOpen(b *Breaker) {
+	b.mu.Lock()
+	b.openedAt = time.Now().Add(-b.config.OpenDuration)
+	b.mu.Unlock()
+}
+
+func TestBreakerOpensAtErrorRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		results   []bool // 依次结束的请求是否失败
+		wantState State
+	}{
+		{name: "below min requests", results: []bool{true, true, true}, wantState: Closed},
+		{name: "below error rate", results: []bool{false, false, false, true, false}, wantState: Closed},
+		{name: "at error rate", results: []bool{false, true, false, true}, wantState: Open},
+		{name: "all failed", results: []bool{true, true, true, true}, wantState: Open},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewSet(testConfig).Get("ankr")
+			for _, failed := range tt.results {
+				call(t, b, failed)
+			}
+			if got := b.Status().State; got != tt.wantState {
+				t.Fatalf("state = %s, want %s", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestBreakerWindowReset(t *testing.T) {
+	config := testConfig
+	config.Window = 20 * time.Millisecond
+	b := NewSet(config).Get("ankr")
+
+	for range 3 {
+		call(t, b, true)
+	}
+	// 窗口结束后重新计数，之前的失败不再计入
+	time.Sleep(30 * time.Millisecond)
+	call(t, b, true)
+	if status := b.Status(); status.State != Closed || status.Requests != 1 {
+		t.Fatalf("status = %+v, want closed with 1 request", status)
+	}
+}
+
+func TestBreakerRejectsWhenOpen(t *testing.T) {
+	b := NewSet(testConfig).Get("ankr")
+	for range 4 {
+		call(t, b, true)
+	}
+
+	done, err := b.Allow()
+	if done != nil || !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow = %v, want ErrOpen", err)
+	}
+	var open *OpenError
+	if !errors.As(err, &open) || open.Name != "ankr" {
+		t.Fatalf("error = %#v, want *OpenError for ankr", err)
+	}
+	if open.RetryAfter <= 0 || open.RetryAfter > testConfig.OpenDuration {
+		t.Fatalf("RetryAfter = %s, want (0, %s]", open.RetryAfter, testConfig.OpenDuration)
+	}
+	if status := b.Status(); status.Rejected != 1 || status.OpenedAt == nil {
+		t.Fatalf("status = %+v, want 1 rejected and OpenedAt set", status)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name      string
+		probes    []bool // 探测请求是否失败
+		wantState State
+	}{
+		{name: "all probes succeed", probes: []bool{false, false}, wantState: Closed},
+		{name: "a probe fails", probes: []bool{false, true}, wantState: Open},
+		{name: "first probe fails", probes: []bool{true, false}, wantState: Open},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewSet(testConfig).Get("ankr")
+			for range 4 {
+				call(t, b, true)
+			}
+			expireOpen(b)
+			if got := b.Status().State; got != HalfOpen {
+				t.Fatalf("state after OpenDuration = %s, want half_open", got)
+			}
+
+			// 半开状态只放行 HalfOpenRequests 个探测请求
+			dones := make([]func(bool), 0, len(tt.probes))
+			for range testConfig.HalfOpenRequests {
+				done, err := b.Allow()
+				if err != nil {
+					t.Fatalf("probe rejected: %v", err)
+				}
+				dones = append(dones, done)
+			}
+			if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+				t.Fatalf("extra probe error = %v, want ErrOpen", err)
+			}
+
+			for i, failed := range tt.probes {
+				dones[i](failed)
+			}
+			if got := b.Status().State; got != tt.wantState {
+				t.Fatalf("state = %s, want %s", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoresStaleDone(t *testing.T) {
+	t.Run("closed request finishing after the breaker reopened and closed", func(t *testing.T) {
+		b := NewSet(testConfig).Get("ankr")
+		stale, _ := b.Allow()
+		for range 4 {
+			call(t, b, true)
+		}
+		expireOpen(b)
+		call(t, b, false)
+		call(t, b, false)
+		if got := b.Status().State; got != Closed {
+			t.Fatalf("state = %s, want closed", got)
+		}
+
+		stale(true)
+		if status := b.Status(); status.Requests != 0 || status.Failures != 0 {
+			t.Fatalf("status = %+v, stale result was counted", status)
+		}
+	})
+
+	t.Run("probe from an earlier half-open period", func(t *testing.T) {
+		b := NewSet(testConfig).Get("ankr")
+		for range 4 {
+			call(t, b, true)
+		}
+		expireOpen(b)
+		stale, _ := b.Allow()
+		call(t, b, true) // 另一个探测失败，重新打开
+		expireOpen(b)
+		call(t, b, false)
+
+		// 上一轮的探测成功不能算作本轮的探测
+		stale(false)
+		if got := b.Status().State; got != HalfOpen {
+			t.Fatalf("state = %s, want half_open", got)
+		}
+	})
+}
+
+func TestSet(t *testing.T) {
+	if b := NewSet(Config{}).Get("ankr"); b != nil {
+		t.Fatal("disabled config should return a nil breaker")
+	}
+	var nilSet *Set
+	if nilSet.Get("ankr") != nil || nilSet.Snapshot() != nil {
+		t.Fatal("nil set should be disabled")
+	}
+
+	set := NewSet(testConfig)
+	if set.Get("moralis") != set.Get("moralis") {
+		t.Fatal("Get should return the same breaker for a name")
+	}
+	set.Get("ankr")
+	snapshot := set.Snapshot()
+	if len(snapshot) != 2 || snapshot[0].Name != "ankr" || snapshot[1].Name != "moralis" {
+		t.Fatalf("Snapshot = %+v, want ankr and moralis sorted", snapshot)
+	}
+}
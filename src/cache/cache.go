@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store 是缓存后端的抽象，可以是进程内内存，也可以是 Redis 等共享存储
+// ctx 为请求的 context，请求超时或客户端断开时不再等待共享存储
+type Store interface {
+	// Get 返回缓存的值，未命中或已过期时第二个返回值为 false
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Counts 单个路由的命中统计
+type Counts struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// Stats 按名称（通常是路由）统计缓存命中与未命中次数
+type Stats struct {
+	mu       sync.RWMutex
+	counters map[string]*counter
+}
+
+type counter struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		counters: make(map[string]*counter),
+	}
+}
+
+func (s *Stats) Hit(name string) {
+	s.get(name).hits.Add(1)
+}
+
+func (s *Stats) Miss(name string) {
+	s.get(name).misses.Add(1)
+}
+
+// Snapshot 返回当前所有计数的副本
+func (s *Stats) Snapshot() map[string]Counts {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := make(map[string]Counts, len(s.counters))
+	for name, c := range s.counters {
+		snapshot[name] = Counts{
+			Hits:   c.hits.Load(),
+			Misses: c.misses.Load(),
+		}
+	}
+	return snapshot
+}
+
+func (s *Stats) get(name string) *counter {
+	s.mu.RLock()
+	c, ok := s.counters[name]
+	s.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok = s.counters[name]; !ok {
+		c = &counter{}
+		s.counters[name] = c
+	}
+	return c
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		wait   time.Duration
+		wantOK bool
+	}{
+		{name: "fresh entry", ttl: time.Minute, wantOK: true},
+		{name: "expired entry", ttl: 10 * time.Millisecond, wait: 20 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(0)
+			if err := store.Set(ctx, "key", []byte("value"), tt.ttl); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(tt.wait)
+
+			value, ok, err := store.Get(ctx, "key")
+			if err != nil || ok != tt.wantOK {
+				t.Fatalf("Get = %q, %t, %v; want ok %t", value, ok, err, tt.wantOK)
+			}
+			if ok && string(value) != "value" {
+				t.Fatalf("value = %q", value)
+			}
+		})
+	}
+
+	t.Run("missing key", func(t *testing.T) {
+		if _, ok, err := NewMemoryStore(0).Get(ctx, "missing"); ok || err != nil {
+			t.Fatalf("Get = %t, %v; want miss", ok, err)
+		}
+	})
+}
+
+func TestMemoryStoreMaxEntries(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(3)
+
+	// 过期条目优先清理，未过期的条目不受影响
+	store.Set(ctx, "expired", []byte("x"), time.Nanosecond)
+	store.Set(ctx, "a", []byte("a"), time.Minute)
+	store.Set(ctx, "b", []byte("b"), time.Minute)
+	time.Sleep(time.Millisecond)
+	store.Set(ctx, "c", []byte("c"), time.Minute)
+	for _, key := range []string{"a", "b", "c"} {
+		if _, ok, _ := store.Get(ctx, key); !ok {
+			t.Fatalf("%s evicted while an expired entry was available", key)
+		}
+	}
+
+	// 没有过期条目时淘汰一个，条目数不超过上限
+	for i := range 10 {
+		store.Set(ctx, fmt.Sprint("extra", i), []byte("x"), time.Minute)
+	}
+	if len(store.entries) > 3 {
+		t.Fatalf("%d entries, limit is 3", len(store.entries))
+	}
+	if _, ok, _ := store.Get(ctx, "extra9"); !ok {
+		t.Fatal("latest entry was evicted")
+	}
+}
+
+func TestStats(t *testing.T) {
+	stats := NewStats()
+	stats.Hit("balance")
+	stats.Hit("balance")
+	stats.Miss("balance")
+	stats.Miss("nfts")
+
+	want := map[string]Counts{
+		"balance": {Hits: 2, Misses: 1},
+		"nfts":    {Misses: 1},
+	}
+	got := stats.Snapshot()
+	if len(got) != len(want) {
+		t.Fatalf("Snapshot = %v, want %v", got, want)
+	}
+	for name, counts := range want {
+		if got[name] != counts {
+			t.Fatalf("%s = %+v, want %+v", name, got[name], counts)
+		}
+	}
+}
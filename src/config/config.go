@@ -0,0 +1,413 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/web3-smart-wallet/src/ratelimit"
+	"github.com/web3-smart-wallet/src/services"
+	"github.com/web3-smart-wallet/src/timeout"
+	"gopkg.in/yaml.v3"
+)
+
+// Config 服务的全部配置
+// 加载顺序：默认值 → YAML 文件 → .env 文件 → 环境变量，后者覆盖前者；字段的 env 标签为对应的环境变量名
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Ankr      AnkrConfig      `yaml:"ankr"`
+	Providers ProvidersConfig `yaml:"providers"`
+	Upstream  UpstreamConfig  `yaml:"upstream"`
+	Breaker   BreakerConfig   `yaml:"circuitBreaker"`
+	Balance   BalanceConfig   `yaml:"balance"`
+	Cache     CacheConfig     `yaml:"cache"`
+	Redis     RedisConfig     `yaml:"redis"`
+	Auth      AuthConfig      `yaml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+	Log       LogConfig       `yaml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	DID       DIDConfig       `yaml:"did"`
+}
+
+type ServerConfig struct {
+	// Addr 监听地址
+	Addr string `yaml:"addr" env:"SERVER_ADDR"`
+	// Timeouts 读写超时
+	Timeouts time.Duration `yaml:"timeouts" env:"SERVER_TIMEOUTS"`
+	// IdleTimeout keep-alive 连接的空闲超时
+	IdleTimeout time.Duration `yaml:"idleTimeout" env:"SERVER_IDLE_TIMEOUT"`
+	// RequestTimeout 每个请求（含所有上游调用）的超时预算，可按路由覆盖，例如 "15s,/api/user/*/portfolio=30s"
+	RequestTimeout timeout.Budgets `yaml:"requestTimeout" env:"REQUEST_TIMEOUT"`
+	// MaxConnections 最大并发连接数
+	MaxConnections int `yaml:"maxConnections" env:"MAX_CONNECTIONS"`
+	// ProxyHeader 部署在可信反向代理之后时读取客户端 IP 的请求头，如 X-Real-IP；为空时使用 TCP 对端地址
+	ProxyHeader string `yaml:"proxyHeader" env:"PROXY_HEADER"`
+	// DefaultPageSize 请求未指定 pageSize 时的默认值
+	DefaultPageSize int `yaml:"defaultPageSize" env:"DEFAULT_PAGE_SIZE"`
+	// ShutdownDelay 收到 SIGTERM 后 /ready 开始失败，等待该时间让负载均衡摘除实例，再停止接受新连接
+	ShutdownDelay time.Duration `yaml:"shutdownDelay" env:"SERVER_SHUTDOWN_DELAY"`
+	// ShutdownTimeout 等待处理中的请求完成的最长时间，超时后强制关闭连接
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+type AnkrConfig struct {
+	APIKey string `yaml:"apiKey" env:"ANKR_API_KEY"`
+	// APIURL Advanced API（multichain）地址，未设置时由 APIKey 生成
+	APIURL string `yaml:"apiURL" env:"ANKR_API_URL"`
+}
+
+type ProvidersConfig struct {
+	// Default 默认的提供方，可以用 "|" 指定备用提供方，例如 "alchemy|ankr"
+	Default ProviderList `yaml:"default" env:"PROVIDER_DEFAULT"`
+	// Chains 按链指定提供方，例如 "eth=alchemy|ankr,polygon=moralis"
+	Chains ProviderChains `yaml:"chains" env:"PROVIDER_CHAINS"`
+	// Timeout 单个提供方的请求超时
+	Timeout time.Duration `yaml:"timeout" env:"PROVIDER_TIMEOUT"`
+	// HedgeDelay 超过该时间未返回时并发请求下一个提供方，0 表示不启用
+	HedgeDelay time.Duration `yaml:"hedgeDelay" env:"PROVIDER_HEDGE_DELAY"`
+
+	Alchemy  ProviderConfig `yaml:"alchemy" env:"ALCHEMY"`
+	Moralis  ProviderConfig `yaml:"moralis" env:"MORALIS"`
+	Covalent ProviderConfig `yaml:"covalent" env:"COVALENT"`
+}
+
+// ProviderConfig 第三方提供方的 key 和地址，环境变量为 <前缀>_API_KEY 和 <前缀>_BASE_URL
+type ProviderConfig struct {
+	APIKey  string `yaml:"apiKey" env:"API_KEY"`
+	BaseURL string `yaml:"baseURL" env:"BASE_URL"`
+}
+
+// UpstreamConfig 所有上游调用共用的 HTTP 客户端，字段与 services.ClientOptions 一一对应
+type UpstreamConfig struct {
+	// Timeout 单次请求的超时，不包括重试
+	Timeout time.Duration `yaml:"timeout" env:"UPSTREAM_TIMEOUT"`
+	// MaxRetries 网络错误、429、5xx 和临时性 JSON-RPC 错误的最大重试次数
+	MaxRetries int `yaml:"maxRetries" env:"UPSTREAM_MAX_RETRIES"`
+	// BackoffBase 第一次重试前的等待时间，之后每次翻倍
+	BackoffBase time.Duration `yaml:"backoffBase" env:"UPSTREAM_BACKOFF_BASE"`
+	// BackoffMax 单次等待的上限，上游 Retry-After 超过该值时不再重试
+	BackoffMax time.Duration `yaml:"backoffMax" env:"UPSTREAM_BACKOFF_MAX"`
+	// MaxIdleConnsPerHost 每个上游主机保持的空闲连接数
+	MaxIdleConnsPerHost int `yaml:"maxIdleConnsPerHost" env:"UPSTREAM_MAX_IDLE_CONNS_PER_HOST"`
+}
+
+// BreakerConfig 每个上游方法的熔断器，字段与 breaker.Config 一一对应
+type BreakerConfig struct {
+	// ErrorRate 统计窗口内失败比例达到该值时熔断，0 表示不启用
+	ErrorRate float64 `yaml:"errorRate" env:"CIRCUIT_BREAKER_ERROR_RATE"`
+	// MinRequests 统计窗口内请求数达到该值才计算失败比例
+	MinRequests int `yaml:"minRequests" env:"CIRCUIT_BREAKER_MIN_REQUESTS"`
+	// Window 失败率的统计窗口
+	Window time.Duration `yaml:"window" env:"CIRCUIT_BREAKER_WINDOW"`
+	// OpenDuration 熔断后经过该时间放行探测请求
+	OpenDuration time.Duration `yaml:"openDuration" env:"CIRCUIT_BREAKER_OPEN_DURATION"`
+	// HalfOpenRequests 探测请求数，全部成功后恢复
+	HalfOpenRequests int `yaml:"halfOpenRequests" env:"CIRCUIT_BREAKER_HALF_OPEN_REQUESTS"`
+}
+
+type BalanceConfig struct {
+	// RPCURLs 设置后代币余额直接通过各链 JSON-RPC 节点读取，例如 "eth=http://localhost:8545"
+	RPCURLs RPCEndpoints `yaml:"rpcURLs" env:"BALANCE_RPC_URLS"`
+	// RPCTokens 通过 JSON-RPC 查询的 ERC20 合约，例如 "eth=0xA|0xB"
+	RPCTokens RPCTokens `yaml:"rpcTokens" env:"BALANCE_RPC_TOKENS"`
+	// MulticallAddress Multicall3 合约地址，默认使用官方地址
+	MulticallAddress string `yaml:"multicallAddress" env:"MULTICALL_ADDRESS"`
+	// NativeAlwaysInclude 原生币余额为 0 时也返回
+	NativeAlwaysInclude bool `yaml:"nativeAlwaysInclude" env:"NATIVE_ALWAYS_INCLUDE"`
+}
+
+type CacheConfig struct {
+	// Backend 可选 memory、redis 或 none
+	Backend    string        `yaml:"backend" env:"CACHE_BACKEND"`
+	MaxEntries int           `yaml:"maxEntries" env:"CACHE_MAX_ENTRIES"`
+	TTLTokens  time.Duration `yaml:"ttlTokens" env:"CACHE_TTL_TOKENS"`
+	TTLBalance time.Duration `yaml:"ttlBalance" env:"CACHE_TTL_BALANCE"`
+	TTLNFTs    time.Duration `yaml:"ttlNFTs" env:"CACHE_TTL_NFTS"`
+	// TTLStale 过期结果的额外保留时间，上游熔断时用于降级返回，0 表示不保留
+	TTLStale time.Duration `yaml:"ttlStale" env:"CACHE_TTL_STALE"`
+}
+
+type RedisConfig struct {
+	URL string `yaml:"url" env:"REDIS_URL"`
+}
+
+type AuthConfig struct {
+	Disabled bool   `yaml:"disabled" env:"AUTH_DISABLED"`
+	KeysFile string `yaml:"keysFile" env:"API_KEYS_FILE"`
+}
+
+type RateLimitConfig struct {
+	// Backend 可选 memory、redis 或 none
+	Backend string           `yaml:"backend" env:"RATE_LIMIT_BACKEND"`
+	Key     ratelimit.Limits `yaml:"key" env:"RATE_LIMIT_KEY"`
+	IP      ratelimit.Limits `yaml:"ip" env:"RATE_LIMIT_IP"`
+}
+
+type LogConfig struct {
+	Level slog.Level `yaml:"level" env:"LOG_LEVEL"`
+	// Format 可选 json 或 text
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+type TracingConfig struct {
+	// Exporter 可选 otlp、stdout 或 none
+	Exporter    string  `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	ServiceName string  `yaml:"serviceName" env:"OTEL_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sampleRatio" env:"OTEL_TRACES_SAMPLE_RATIO"`
+}
+
+type MetricsConfig struct {
+	Addr string `yaml:"addr" env:"METRICS_ADDR"`
+}
+
+type DIDConfig struct {
+	// RegistryFile 地址与 DID 关联表的持久化文件，需位于重启后保留的卷上
+	RegistryFile string `yaml:"registryFile" env:"DID_REGISTRY_FILE"`
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:        ":8080",
+			Timeouts:    30 * time.Second,
+			IdleTimeout: 2 * time.Minute,
+			RequestTimeout: timeout.Budgets{
+				Default: 15 * time.Second,
+				Routes:  []timeout.RouteBudget{{Pattern: "/api/user/*/portfolio", Timeout: 30 * time.Second}},
+			},
+			MaxConnections:  256 * 1024,
+			DefaultPageSize: 10,
+			ShutdownDelay:   5 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Providers: ProvidersConfig{
+			Default: ProviderList{"ankr"},
+			Timeout: 10 * time.Second,
+		},
+		Upstream: UpstreamConfig(services.DefaultClientOptions()),
+		Breaker: BreakerConfig{
+			ErrorRate:        0.5,
+			MinRequests:      20,
+			Window:           30 * time.Second,
+			OpenDuration:     15 * time.Second,
+			HalfOpenRequests: 3,
+		},
+		Cache: CacheConfig{
+			Backend:    "memory",
+			MaxEntries: 10000,
+			TTLTokens:  5 * time.Minute,
+			TTLBalance: 30 * time.Second,
+			TTLNFTs:    2 * time.Minute,
+			TTLStale:   time.Hour,
+		},
+		Auth: AuthConfig{
+			KeysFile: "api_keys.json",
+		},
+		DID: DIDConfig{
+			RegistryFile: "did_registry.json",
+		},
+		RateLimit: RateLimitConfig{
+			Backend: "memory",
+			Key:     ratelimit.Limits{Default: ratelimit.Limit{Requests: 600, Period: time.Minute}},
+			IP:      ratelimit.Limits{Default: ratelimit.Limit{Requests: 300, Period: time.Minute}},
+		},
+		Log: LogConfig{
+			Level:  slog.LevelInfo,
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "web3-smartwatch-server",
+			SampleRatio: 1,
+		},
+		Metrics: MetricsConfig{
+			Addr: ":9090",
+		},
+	}
+}
+
+// Load 加载并校验配置，path 为 YAML 配置文件路径，为空时不读取；overrides 在环境变量之后、校验之前执行（例如命令行参数）
+func Load(path string, overrides ...func(*Config)) (*Config, error) {
+	config := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %v", err)
+		}
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+		}
+	}
+
+	// .env 不覆盖已经存在的环境变量
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load .env: %v", err)
+	}
+	if errs := applyEnv(config, ""); len(errs) > 0 {
+		return nil, invalid(errs)
+	}
+	for _, override := range overrides {
+		override(config)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate 校验配置，返回所有错误
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "SERVER_ADDR must not be empty")
+	check(c.Server.Timeouts >= 0, "SERVER_TIMEOUTS must not be negative")
+	check(c.Server.IdleTimeout >= 0, "SERVER_IDLE_TIMEOUT must not be negative")
+	check(c.Server.RequestTimeout.Default >= 0, "REQUEST_TIMEOUT must not be negative")
+	check(c.Server.ShutdownDelay >= 0, "SERVER_SHUTDOWN_DELAY must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "SERVER_SHUTDOWN_TIMEOUT must be positive")
+	check(c.Server.MaxConnections > 0, "MAX_CONNECTIONS must be positive, got %d", c.Server.MaxConnections)
+	check(c.Server.DefaultPageSize > 0 && c.Server.DefaultPageSize <= 100, "DEFAULT_PAGE_SIZE must be between 1 and 100, got %d", c.Server.DefaultPageSize)
+
+	check(c.Ankr.APIKey != "" || c.Ankr.APIURL != "", "ANKR_API_KEY or ANKR_API_URL is required")
+
+	check(len(c.Providers.Default) > 0, "PROVIDER_DEFAULT must not be empty")
+	check(c.Providers.Timeout >= 0, "PROVIDER_TIMEOUT must not be negative")
+	check(c.Providers.HedgeDelay >= 0, "PROVIDER_HEDGE_DELAY must not be negative")
+	known := map[string]bool{
+		"ankr":     true,
+		"alchemy":  c.Providers.Alchemy.APIKey != "",
+		"moralis":  c.Providers.Moralis.APIKey != "",
+		"covalent": c.Providers.Covalent.APIKey != "",
+	}
+	for _, name := range c.Providers.Default {
+		check(known[name], "PROVIDER_DEFAULT: provider %q is unknown or has no API key", name)
+	}
+	for chain, names := range c.Providers.Chains {
+		for _, name := range names {
+			check(known[name], "PROVIDER_CHAINS: provider %q for chain %s is unknown or has no API key", name, chain)
+		}
+	}
+
+	check(c.Upstream.Timeout >= 0, "UPSTREAM_TIMEOUT must not be negative")
+	check(c.Upstream.MaxRetries >= 0, "UPSTREAM_MAX_RETRIES must not be negative")
+	check(c.Upstream.BackoffBase >= 0 && c.Upstream.BackoffMax >= c.Upstream.BackoffBase, "UPSTREAM_BACKOFF_MAX must not be less than UPSTREAM_BACKOFF_BASE")
+	check(c.Upstream.MaxIdleConnsPerHost > 0, "UPSTREAM_MAX_IDLE_CONNS_PER_HOST must be positive")
+
+	check(c.Breaker.ErrorRate >= 0 && c.Breaker.ErrorRate <= 1, "CIRCUIT_BREAKER_ERROR_RATE must be between 0 and 1, got %v", c.Breaker.ErrorRate)
+	if c.Breaker.ErrorRate > 0 {
+		check(c.Breaker.MinRequests > 0, "CIRCUIT_BREAKER_MIN_REQUESTS must be positive")
+		check(c.Breaker.Window > 0, "CIRCUIT_BREAKER_WINDOW must be positive")
+		check(c.Breaker.OpenDuration > 0, "CIRCUIT_BREAKER_OPEN_DURATION must be positive")
+		check(c.Breaker.HalfOpenRequests > 0, "CIRCUIT_BREAKER_HALF_OPEN_REQUESTS must be positive")
+	}
+
+	check(oneOf(c.Cache.Backend, "memory", "redis", "none"), "CACHE_BACKEND must be memory, redis or none, got %q", c.Cache.Backend)
+	check(c.Cache.MaxEntries >= 0, "CACHE_MAX_ENTRIES must not be negative")
+	check(c.Cache.TTLTokens >= 0 && c.Cache.TTLBalance >= 0 && c.Cache.TTLNFTs >= 0 && c.Cache.TTLStale >= 0, "CACHE_TTL_* must not be negative")
+	check(oneOf(c.RateLimit.Backend, "memory", "redis", "none"), "RATE_LIMIT_BACKEND must be memory, redis or none, got %q", c.RateLimit.Backend)
+	if c.Cache.Backend == "redis" || c.RateLimit.Backend == "redis" {
+		check(c.Redis.URL != "", "REDIS_URL is required when CACHE_BACKEND or RATE_LIMIT_BACKEND is redis")
+	}
+
+	check(c.Auth.Disabled || c.Auth.KeysFile != "", "API_KEYS_FILE must not be empty unless AUTH_DISABLED=true")
+	// 为空时关联只保存在内存中，重启后全部丢失
+	check(c.DID.RegistryFile != "", "DID_REGISTRY_FILE must not be empty")
+
+	check(oneOf(c.Log.Format, "json", "text"), "LOG_FORMAT must be json or text, got %q", c.Log.Format)
+	check(oneOf(c.Tracing.Exporter, "otlp", "stdout", "none"), "OTEL_TRACES_EXPORTER must be otlp, stdout or none, got %q", c.Tracing.Exporter)
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "OTEL_TRACES_SAMPLE_RATIO must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	check(c.Metrics.Addr != "", "METRICS_ADDR must not be empty")
+
+	if len(errs) > 0 {
+		return invalid(errs)
+	}
+	return nil
+}
+
+// AnkrURL 返回 Ankr Advanced API 地址
+func (c *Config) AnkrURL() string {
+	if c.Ankr.APIURL != "" {
+		return c.Ankr.APIURL
+	}
+	return fmt.Sprintf("https://rpc.ankr.com/multichain/%s", c.Ankr.APIKey)
+}
+
+// AnkrRPCURL 返回指定链的 Ankr JSON-RPC 节点地址
+func (c *Config) AnkrRPCURL(chain string) string {
+	return fmt.Sprintf("https://rpc.ankr.com/%s/%s", chain, c.Ankr.APIKey)
+}
+
+// ProviderList 提供方列表，环境变量格式为 "alchemy|ankr"
+type ProviderList []string
+
+func (l *ProviderList) UnmarshalText(text []byte) error {
+	*l = services.ParseProviderList(string(text))
+	return nil
+}
+
+// ProviderChains 按链指定的提供方，环境变量格式为 "eth=alchemy|ankr,polygon=moralis"
+type ProviderChains map[string][]string
+
+func (p *ProviderChains) UnmarshalText(text []byte) error {
+	chains, err := services.ParseProviderChains(string(text))
+	if err != nil {
+		return err
+	}
+	*p = chains
+	return nil
+}
+
+// RPCEndpoints 各链 JSON-RPC 节点地址，环境变量格式为 "eth=https://...,polygon=https://..."
+type RPCEndpoints map[string]string
+
+func (e *RPCEndpoints) UnmarshalText(text []byte) error {
+	endpoints, err := services.ParseRPCEndpoints(string(text))
+	if err != nil {
+		return err
+	}
+	*e = endpoints
+	return nil
+}
+
+// RPCTokens 各链通过 JSON-RPC 查询的 ERC20 合约，环境变量格式为 "eth=0xA|0xB"
+type RPCTokens map[string][]string
+
+func (t *RPCTokens) UnmarshalText(text []byte) error {
+	tokens, err := services.ParseRPCTokens(string(text))
+	if err != nil {
+		return err
+	}
+	*t = tokens
+	return nil
+}
+
+// 辅助函数：value 是否为 options 之一
+func oneOf(value string, options ...string) bool {
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
+
+// 辅助函数：合并多个配置错误，每个错误占一行
+func invalid(errs []error) error {
+	message := "invalid configuration:"
+	for _, err := range errs {
+		message += "\n  - " + err.Error()
+	}
+	return errors.New(message)
+}
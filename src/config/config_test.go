@@ -0,0 +1,252 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试中读取或设置的环境变量，每个测试开始时清空，结束后恢复
+var testEnv = []string{"ANKR_API_KEY", "ANKR_API_URL", "SERVER_ADDR", "SERVER_TIMEOUTS", "MAX_CONNECTIONS", "LOG_FORMAT", "PROVIDER_DEFAULT", "ALCHEMY_API_KEY"}
+
+// 在临时目录中写入 config.yaml 和 .env（内容为空时不写入）并切换到该目录，返回 YAML 路径
+func setup(t *testing.T, yamlContent string, dotenv string) string {
+	t.Helper()
+	for _, name := range testEnv {
+		// .env 通过 os.Setenv 写入环境变量，先用 t.Setenv 登记恢复，再删除
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if dotenv != "" {
+		if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotenv), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if yamlContent == "" {
+		return ""
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(yamlContent), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	const yamlContent = "ankr:\n  apiKey: yaml-key\nserver:\n  addr: \":7000\"\n  timeouts: 45s\n  maxConnections: 100\nlog:\n  format: text\n"
+
+	tests := []struct {
+		name           string
+		yaml           string
+		dotenv         string
+		env            map[string]string
+		overrides      []func(*Config)
+		wantAddr       string
+		wantTimeouts   time.Duration
+		wantMaxConns   int
+		wantFormat     string
+		wantAnkrAPIKey string
+	}{
+		{
+			name:           "defaults",
+			env:            map[string]string{"ANKR_API_KEY": "env-key"},
+			wantAddr:       ":8080",
+			wantTimeouts:   30 * time.Second,
+			wantMaxConns:   256 * 1024,
+			wantFormat:     "json",
+			wantAnkrAPIKey: "env-key",
+		},
+		{
+			name:           "yaml overrides defaults",
+			yaml:           yamlContent,
+			wantAddr:       ":7000",
+			wantTimeouts:   45 * time.Second,
+			wantMaxConns:   100,
+			wantFormat:     "text",
+			wantAnkrAPIKey: "yaml-key",
+		},
+		{
+			name:           ".env overrides yaml",
+			yaml:           yamlContent,
+			dotenv:         "SERVER_ADDR=:7100\nMAX_CONNECTIONS=200\nANKR_API_KEY=dotenv-key\n",
+			wantAddr:       ":7100",
+			wantTimeouts:   45 * time.Second,
+			wantMaxConns:   200,
+			wantFormat:     "text",
+			wantAnkrAPIKey: "dotenv-key",
+		},
+		{
+			name:           "environment overrides .env",
+			yaml:           yamlContent,
+			dotenv:         "SERVER_ADDR=:7100\nMAX_CONNECTIONS=200\nANKR_API_KEY=dotenv-key\n",
+			env:            map[string]string{"SERVER_ADDR": ":7200", "SERVER_TIMEOUTS": "1m", "ANKR_API_KEY": "env-key"},
+			wantAddr:       ":7200",
+			wantTimeouts:   time.Minute,
+			wantMaxConns:   200,
+			wantFormat:     "text",
+			wantAnkrAPIKey: "env-key",
+		},
+		{
+			// 空的环境变量不覆盖配置
+			name:           "empty environment variable",
+			yaml:           yamlContent,
+			env:            map[string]string{"SERVER_ADDR": ""},
+			wantAddr:       ":7000",
+			wantTimeouts:   45 * time.Second,
+			wantMaxConns:   100,
+			wantFormat:     "text",
+			wantAnkrAPIKey: "yaml-key",
+		},
+		{
+			name:           "overrides after environment",
+			yaml:           yamlContent,
+			env:            map[string]string{"SERVER_ADDR": ":7200"},
+			overrides:      []func(*Config){func(c *Config) { c.Server.Addr = ":7300" }},
+			wantAddr:       ":7300",
+			wantTimeouts:   45 * time.Second,
+			wantMaxConns:   100,
+			wantFormat:     "text",
+			wantAnkrAPIKey: "yaml-key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setup(t, tt.yaml, tt.dotenv)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			config, err := Load(path, tt.overrides...)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if config.Server.Addr != tt.wantAddr || config.Server.Timeouts != tt.wantTimeouts || config.Server.MaxConnections != tt.wantMaxConns {
+				t.Errorf("server = %s %s %d, want %s %s %d", config.Server.Addr, config.Server.Timeouts, config.Server.MaxConnections, tt.wantAddr, tt.wantTimeouts, tt.wantMaxConns)
+			}
+			if config.Log.Format != tt.wantFormat || config.Ankr.APIKey != tt.wantAnkrAPIKey {
+				t.Errorf("log format = %s, ankr key = %s, want %s, %s", config.Log.Format, config.Ankr.APIKey, tt.wantFormat, tt.wantAnkrAPIKey)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  map[string]string
+		// want 错误信息中应包含的内容
+		want []string
+	}{
+		{
+			name: "unparsable SERVER_TIMEOUTS",
+			env:  map[string]string{"ANKR_API_KEY": "key", "SERVER_TIMEOUTS": "30"},
+			want: []string{`invalid SERVER_TIMEOUTS "30"`},
+		},
+		{
+			name: "negative SERVER_TIMEOUTS",
+			env:  map[string]string{"ANKR_API_KEY": "key", "SERVER_TIMEOUTS": "-5s"},
+			want: []string{"SERVER_TIMEOUTS must not be negative"},
+		},
+		{
+			name: "unparsable MAX_CONNECTIONS",
+			env:  map[string]string{"ANKR_API_KEY": "key", "MAX_CONNECTIONS": "many"},
+			want: []string{`invalid MAX_CONNECTIONS "many"`},
+		},
+		{
+			name: "zero MAX_CONNECTIONS",
+			env:  map[string]string{"ANKR_API_KEY": "key", "MAX_CONNECTIONS": "0"},
+			want: []string{"MAX_CONNECTIONS must be positive, got 0"},
+		},
+		{
+			name: "negative maxConnections in yaml",
+			yaml: "ankr:\n  apiKey: key\nserver:\n  maxConnections: -1\n",
+			want: []string{"MAX_CONNECTIONS must be positive, got -1"},
+		},
+		{
+			name: "invalid yaml",
+			yaml: "server: [\n",
+			want: []string{"failed to parse config file"},
+		},
+		{
+			// 所有解析错误一次性返回
+			name: "all unparsable variables",
+			env:  map[string]string{"ANKR_API_KEY": "key", "SERVER_TIMEOUTS": "soon", "MAX_CONNECTIONS": "1.5"},
+			want: []string{"invalid SERVER_TIMEOUTS", "invalid MAX_CONNECTIONS"},
+		},
+		{
+			// 所有校验错误一次性返回
+			name: "all validation errors",
+			env:  map[string]string{"SERVER_TIMEOUTS": "-1s", "MAX_CONNECTIONS": "-1", "LOG_FORMAT": "xml"},
+			want: []string{
+				"SERVER_TIMEOUTS must not be negative",
+				"MAX_CONNECTIONS must be positive",
+				"ANKR_API_KEY or ANKR_API_URL is required",
+				`LOG_FORMAT must be json or text, got "xml"`,
+			},
+		},
+		{
+			name: "provider without API key",
+			env:  map[string]string{"ANKR_API_KEY": "key", "PROVIDER_DEFAULT": "alchemy|ankr"},
+			want: []string{`PROVIDER_DEFAULT: provider "alchemy" is unknown or has no API key`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setup(t, tt.yaml, "")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Load(path)
+			if err == nil {
+				t.Fatal("Load() should fail")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+
+	t.Run("provider with API key", func(t *testing.T) {
+		setup(t, "", "")
+		t.Setenv("ANKR_API_KEY", "key")
+		t.Setenv("PROVIDER_DEFAULT", "alchemy|ankr")
+		t.Setenv("ALCHEMY_API_KEY", "alchemy-key")
+		config, err := Load("")
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if len(config.Providers.Default) != 2 || config.Providers.Alchemy.APIKey != "alchemy-key" {
+			t.Errorf("providers = %+v, want alchemy|ankr with the alchemy key", config.Providers)
+		}
+	})
+
+	t.Run("missing config file", func(t *testing.T) {
+		setup(t, "", "")
+		if _, err := Load("does-not-exist.yaml"); err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+			t.Errorf("Load() error = %v, want failed to read config file", err)
+		}
+	})
+}
+
+func TestExampleConfig(t *testing.T) {
+	path, err := filepath.Abs("../../config.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup(t, "", "")
+	t.Setenv("ANKR_API_KEY", "key")
+	if _, err := Load(path); err != nil {
+		t.Errorf("config.example.yaml is invalid: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// 辅助函数：按字段的 env 标签用环境变量覆盖配置
+// 带 env 标签的结构体字段作为前缀，例如 Providers.Alchemy.APIKey 对应 ALCHEMY_API_KEY；返回所有无法解析的变量
+func applyEnv(target interface{}, prefix string) []error {
+	var errs []error
+	value := reflect.ValueOf(target).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		name := value.Type().Field(i).Tag.Get("env")
+		if name != "" && prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		if field.Kind() == reflect.Struct && !field.Addr().Type().Implements(textUnmarshalerType) {
+			if name == "" {
+				name = prefix
+			}
+			errs = append(errs, applyEnv(field.Addr().Interface(), name)...)
+			continue
+		}
+
+		if name == "" {
+			continue
+		}
+		raw, ok := os.LookupEnv(name)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setField(field, raw); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %v", name, raw, err))
+		}
+	}
+	return errs
+}
+
+// 辅助函数：把字符串解析为字段的类型
+func setField(field reflect.Value, raw string) error {
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(raw))
+	}
+
+	switch {
+	case field.Type() == durationType:
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
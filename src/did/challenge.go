@@ -0,0 +1,131 @@
+package did
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 关联操作类型
+const (
+	ActionLink   = "link"
+	ActionUnlink = "unlink"
+)
+
+// ErrChallengeNotFound 挑战不存在、已过期或已被使用
+var ErrChallengeNotFound = errors.New("challenge not found or expired")
+
+// Challenge 服务端签发的一次性签名挑战
+type Challenge struct {
+	Nonce     string
+	Action    string
+	DID       string
+	Address   string
+	Message   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// ChallengeStore 在内存中保存未使用的挑战，每个挑战只能消费一次
+// 挑战按签发顺序保存（有效期相同，签发顺序即过期顺序），过期的挑战在签发新挑战时从队首清理；
+// 超过 maxEntries 时丢弃最早签发的挑战，避免大量只申请不使用的请求耗尽内存
+type ChallengeStore struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	order      *list.List
+	challenges map[string]*list.Element
+}
+
+// NewChallengeStore 创建挑战存储，maxEntries 小于等于 0 时不限制数量
+func NewChallengeStore(ttl time.Duration, maxEntries int) *ChallengeStore {
+	return &ChallengeStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		challenges: make(map[string]*list.Element),
+	}
+}
+
+// Issue 为指定操作、DID 和地址签发新的挑战
+func (s *ChallengeStore) Issue(action string, did string, address string) (Challenge, error) {
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return Challenge{}, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	now := time.Now().UTC()
+	challenge := Challenge{
+		Nonce:     hex.EncodeToString(nonceBytes),
+		Action:    action,
+		DID:       did,
+		Address:   address,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	challenge.Message = challengeMessage(challenge)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 清理队首已过期的挑战，数量达到上限时再丢弃最早签发的挑战
+	for front := s.order.Front(); front != nil; front = s.order.Front() {
+		if !now.After(front.Value.(Challenge).ExpiresAt) && (s.maxEntries <= 0 || s.order.Len() < s.maxEntries) {
+			break
+		}
+		s.remove(front)
+	}
+	s.challenges[challenge.Nonce] = s.order.PushBack(challenge)
+
+	return challenge, nil
+}
+
+// Consume 取出并删除挑战，过期或不存在时返回 ErrChallengeNotFound
+func (s *ChallengeStore) Consume(nonce string) (Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.challenges[nonce]
+	if !ok {
+		return Challenge{}, ErrChallengeNotFound
+	}
+	s.remove(element)
+
+	challenge := element.Value.(Challenge)
+	if time.Now().After(challenge.ExpiresAt) {
+		return Challenge{}, ErrChallengeNotFound
+	}
+	return challenge, nil
+}
+
+// Len 返回未使用的挑战数量（包括尚未清理的过期挑战）
+func (s *ChallengeStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+// 删除挑战，调用方需持有锁
+func (s *ChallengeStore) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.challenges, element.Value.(Challenge).Nonce)
+}
+
+// 生成用户在钱包中 personal_sign 的消息文本
+func challengeMessage(c Challenge) string {
+	return fmt.Sprintf(
+		"Web3 Smartwatch wants you to %s a DID and a wallet address.\n\n"+
+			"DID: %s\n"+
+			"Address: %s\n"+
+			"Nonce: %s\n"+
+			"Issued At: %s\n"+
+			"Expiration Time: %s",
+		c.Action, c.DID, c.Address, c.Nonce,
+		c.IssuedAt.Format(time.RFC3339), c.ExpiresAt.Format(time.RFC3339),
+	)
+}
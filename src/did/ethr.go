@@ -0,0 +1,98 @@
+package did
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/web3-smart-wallet/src/services"
+)
+
+// ERC-1056 EthereumDIDRegistry 在各主要网络上的部署地址
+const ethrRegistryAddress = "0xdca7ef03e98e0dc2b855be647c39abe984fcf21b"
+
+// identityOwner(address) 的函数选择器
+const identityOwnerSelector = "0x8733d4e8"
+
+// EthrResolver 解析 did:ethr
+// 若为该网络配置了 RPC 节点，则通过 ERC-1056 注册表查询当前的 identity owner，
+// 否则按规范返回标识符本身的地址（未变更 owner 时二者一致）
+// 注册表查询通过共用的上游客户端发送（services.PostJSON），节点地址可能带有 API key，不能出现在返回给客户端的错误中
+type EthrResolver struct {
+	rpcURLs map[string]string
+}
+
+// NewEthrResolver 创建 did:ethr 解析器，rpcURLs 以网络名（如 "mainnet"）或十六进制链 ID（如 "0x1"）为键
+func NewEthrResolver(rpcURLs map[string]string) Resolver {
+	return &EthrResolver{
+		rpcURLs: rpcURLs,
+	}
+}
+
+func (r *EthrResolver) Resolve(ctx context.Context, did string) (string, error) {
+	method, id, err := Parse(did)
+	if err != nil {
+		return "", err
+	}
+	if method != "ethr" {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedMethod, method)
+	}
+
+	// did:ethr:<address> 或 did:ethr:<network>:<address>
+	network := "mainnet"
+	identifier := id
+	if idx := strings.LastIndex(id, ":"); idx >= 0 {
+		network = id[:idx]
+		identifier = id[idx+1:]
+	}
+	if network == "0x1" {
+		network = "mainnet"
+	}
+
+	if !addressRegex.MatchString(identifier) {
+		return "", fmt.Errorf("%w: only address identifiers are supported for did:ethr", ErrInvalidDID)
+	}
+
+	rpcURL, ok := r.rpcURLs[network]
+	if !ok || rpcURL == "" {
+		return identifier, nil
+	}
+
+	owner, err := r.identityOwner(ctx, rpcURL, identifier)
+	if err != nil {
+		return "", err
+	}
+	return owner, nil
+}
+
+// 调用注册表合约的 identityOwner(address)
+func (r *EthrResolver) identityOwner(ctx context.Context, rpcURL string, identity string) (string, error) {
+	data := identityOwnerSelector + strings.Repeat("0", 24) + strings.ToLower(strings.TrimPrefix(identity, "0x"))
+
+	payload := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "eth_call",
+		"params": []interface{}{
+			map[string]string{
+				"to":   ethrRegistryAddress,
+				"data": data,
+			},
+			"latest",
+		},
+		"id": 1,
+	}
+
+	var response struct {
+		Result string `json:"result"`
+	}
+	if err := services.PostJSON(ctx, rpcURL, payload, &response); err != nil {
+		return "", fmt.Errorf("failed to query did:ethr registry: %w", err)
+	}
+
+	// 返回值为 32 字节的 ABI 编码地址
+	result := strings.TrimPrefix(response.Result, "0x")
+	if len(result) != 64 {
+		return "", fmt.Errorf("unexpected identityOwner result: %s", response.Result)
+	}
+	return "0x" + result[24:], nil
+}
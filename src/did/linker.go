@@ -0,0 +1,103 @@
+package did
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+var (
+	// ErrInvalidAction 不支持的关联操作
+	ErrInvalidAction = errors.New("invalid action")
+	// ErrInvalidAddress 地址格式不正确
+	ErrInvalidAddress = errors.New("invalid Ethereum address format")
+	// ErrSignatureMismatch 签名恢复出的地址与提交的地址不一致
+	ErrSignatureMismatch = errors.New("signature was not produced by the address")
+	// ErrDIDAddressMismatch DID 可以解析，但解析结果是另一个地址
+	ErrDIDAddressMismatch = errors.New("DID resolves to a different address")
+	// ErrUnresolvableDID 关联时 DID 无法解析，无法确认它属于提交的地址
+	ErrUnresolvableDID = errors.New("DID could not be resolved")
+)
+
+// Linker 通过签名挑战验证地址所有权后，维护地址与 DID 的关联
+type Linker struct {
+	registry   Registry
+	resolver   Resolver
+	challenges *ChallengeStore
+}
+
+func NewLinker(registry Registry, resolver Resolver, challenges *ChallengeStore) *Linker {
+	return &Linker{
+		registry:   registry,
+		resolver:   resolver,
+		challenges: challenges,
+	}
+}
+
+// Challenge 为关联或解除关联签发挑战，用户需要用 address 对 Message 做 personal_sign
+func (l *Linker) Challenge(action string, did string, address string) (Challenge, error) {
+	if action != ActionLink && action != ActionUnlink {
+		return Challenge{}, fmt.Errorf("%w: %s", ErrInvalidAction, action)
+	}
+	if _, _, err := Parse(did); err != nil {
+		return Challenge{}, err
+	}
+	if !addressRegex.MatchString(address) {
+		return Challenge{}, ErrInvalidAddress
+	}
+
+	return l.challenges.Issue(action, did, address)
+}
+
+// Link 验证签名后关联 DID 与地址
+func (l *Linker) Link(ctx context.Context, did string, address string, nonce string, signature string) error {
+	if err := l.verify(ActionLink, did, address, nonce, signature); err != nil {
+		return err
+	}
+
+	// DID 必须解析到同一地址，防止把他人的 DID 登记到自己名下；
+	// 无法解析（包括上游暂时不可用）时无法确认归属，拒绝关联
+	resolved, err := l.resolver.Resolve(ctx, did)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnresolvableDID, err)
+	}
+	if !strings.EqualFold(resolved, address) {
+		return ErrDIDAddressMismatch
+	}
+
+	return l.registry.Link(address, did)
+}
+
+// Unlink 验证签名后解除 DID 与地址的关联
+func (l *Linker) Unlink(did string, address string, nonce string, signature string) error {
+	if err := l.verify(ActionUnlink, did, address, nonce, signature); err != nil {
+		return err
+	}
+
+	return l.registry.Unlink(address, did)
+}
+
+// 消费挑战并校验签名确实来自 address
+func (l *Linker) verify(action string, did string, address string, nonce string, signature string) error {
+	challenge, err := l.challenges.Consume(nonce)
+	if err != nil {
+		return err
+	}
+
+	if challenge.Action != action || challenge.DID != did || !strings.EqualFold(challenge.Address, address) {
+		return fmt.Errorf("%w: challenge was issued for a different request", ErrChallengeNotFound)
+	}
+
+	signer, err := utils.RecoverPersonalSignAddress(challenge.Message, signature)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(signer, address) {
+		return ErrSignatureMismatch
+	}
+
+	return nil
+}
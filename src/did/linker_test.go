@@ -0,0 +1,230 @@
+package did
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+// 测试钱包：私钥和对应的地址
+type testWallet struct {
+	key     *secp256k1.PrivateKey
+	address string
+}
+
+func newTestWallet(seed byte) testWallet {
+	key := secp256k1.PrivKeyFromBytes([]byte(strings.Repeat(string(rune(seed)), 32)))
+	hash := utils.Keccak256(key.PubKey().SerializeUncompressed()[1:])
+	return testWallet{key: key, address: "0x" + hex.EncodeToString(hash[12:])}
+}
+
+// personal_sign 格式的签名：r || s || v
+func (w testWallet) sign(message string) string {
+	compact := ecdsa.SignCompact(w.key, utils.HashPersonalMessage(message), false)
+	signature := make([]byte, 65)
+	copy(signature, compact[1:])
+	signature[64] = compact[0]
+	return "0x" + hex.EncodeToString(signature)
+}
+
+// 固定返回结果的解析器
+type stubResolver struct {
+	address string
+	err     error
+}
+
+func (r stubResolver) Resolve(context.Context, string) (string, error) {
+	return r.address, r.err
+}
+
+func TestLinkerLink(t *testing.T) {
+	const did = "did:web:example.com"
+	owner := newTestWallet(1)
+	other := newTestWallet(2)
+
+	tests := []struct {
+		name     string
+		resolver Resolver
+		// sign 返回提交的签名，可以修改挑战以模拟各种错误
+		sign    func(c Challenge) (nonce string, signature string)
+		address string
+		wantErr error
+	}{
+		{
+			name:     "linked",
+			resolver: stubResolver{address: strings.ToUpper(owner.address[:2]) + owner.address[2:]},
+			sign:     func(c Challenge) (string, string) { return c.Nonce, owner.sign(c.Message) },
+		},
+		{
+			name:     "signed by another wallet",
+			resolver: stubResolver{address: owner.address},
+			sign:     func(c Challenge) (string, string) { return c.Nonce, other.sign(c.Message) },
+			wantErr:  ErrSignatureMismatch,
+		},
+		{
+			name:     "signature over another message",
+			resolver: stubResolver{address: owner.address},
+			sign:     func(c Challenge) (string, string) { return c.Nonce, owner.sign(c.Message + "\n") },
+			wantErr:  ErrSignatureMismatch,
+		},
+		{
+			name:     "malformed signature",
+			resolver: stubResolver{address: owner.address},
+			sign:     func(c Challenge) (string, string) { return c.Nonce, "0x1234" },
+			wantErr:  utils.ErrInvalidSignature,
+		},
+		{
+			name:     "unknown nonce",
+			resolver: stubResolver{address: owner.address},
+			sign:     func(c Challenge) (string, string) { return "deadbeef", owner.sign(c.Message) },
+			wantErr:  ErrChallengeNotFound,
+		},
+		{
+			name:     "DID resolves to another address",
+			resolver: stubResolver{address: other.address},
+			sign:     func(c Challenge) (string, string) { return c.Nonce, owner.sign(c.Message) },
+			wantErr:  ErrDIDAddressMismatch,
+		},
+		{
+			name:     "DID cannot be resolved",
+			resolver: stubResolver{err: ErrNotFound},
+			sign:     func(c Challenge) (string, string) { return c.Nonce, owner.sign(c.Message) },
+			wantErr:  ErrUnresolvableDID,
+		},
+		{
+			name:     "resolver upstream failure",
+			resolver: stubResolver{err: errors.New("connection refused")},
+			sign:     func(c Challenge) (string, string) { return c.Nonce, owner.sign(c.Message) },
+			wantErr:  ErrUnresolvableDID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := NewFileRegistry("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			linker := NewLinker(registry, tt.resolver, NewChallengeStore(time.Minute, 10))
+
+			challenge, err := linker.Challenge(ActionLink, did, owner.address)
+			if err != nil {
+				t.Fatalf("Challenge: %v", err)
+			}
+			nonce, signature := tt.sign(challenge)
+
+			err = linker.Link(context.Background(), did, owner.address, nonce, signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Link error = %v, want %v", err, tt.wantErr)
+			}
+
+			linked, _ := registry.Address(did)
+			if wantLinked := tt.wantErr == nil; wantLinked != (linked == owner.address) {
+				t.Fatalf("registry address = %q, linked = %t", linked, wantLinked)
+			}
+		})
+	}
+}
+
+func TestLinkerChallengeIsSingleUse(t *testing.T) {
+	const did = "did:web:example.com"
+	owner := newTestWallet(1)
+	registry, _ := NewFileRegistry("")
+	linker := NewLinker(registry, stubResolver{address: owner.address}, NewChallengeStore(time.Minute, 10))
+
+	challenge, err := linker.Challenge(ActionLink, did, owner.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := owner.sign(challenge.Message)
+	if err := linker.Link(context.Background(), did, owner.address, challenge.Nonce, signature); err != nil {
+		t.Fatalf("first Link: %v", err)
+	}
+	if err := linker.Link(context.Background(), did, owner.address, challenge.Nonce, signature); !errors.Is(err, ErrChallengeNotFound) {
+		t.Fatalf("replayed Link error = %v, want ErrChallengeNotFound", err)
+	}
+
+	// 解除关联的挑战不能用于关联，反之亦然
+	unlink, _ := linker.Challenge(ActionUnlink, did, owner.address)
+	if err := linker.Link(context.Background(), did, owner.address, unlink.Nonce, owner.sign(unlink.Message)); !errors.Is(err, ErrChallengeNotFound) {
+		t.Fatalf("Link with unlink challenge error = %v, want ErrChallengeNotFound", err)
+	}
+}
+
+func TestLinkerChallenge(t *testing.T) {
+	linker := NewLinker(nil, nil, NewChallengeStore(time.Minute, 10))
+
+	tests := []struct {
+		name    string
+		action  string
+		did     string
+		address string
+		wantErr error
+	}{
+		{name: "link", action: ActionLink, did: "did:web:example.com", address: testAddress},
+		{name: "unlink", action: ActionUnlink, did: "did:web:example.com", address: testAddress},
+		{name: "unknown action", action: "transfer", did: "did:web:example.com", address: testAddress, wantErr: ErrInvalidAction},
+		{name: "invalid DID", action: ActionLink, did: "example.com", address: testAddress, wantErr: ErrInvalidDID},
+		{name: "invalid address", action: ActionLink, did: "did:web:example.com", address: "0x1234", wantErr: ErrInvalidAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge, err := linker.Challenge(tt.action, tt.did, tt.address)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !strings.Contains(challenge.Message, "Nonce: "+challenge.Nonce) {
+				t.Fatalf("message does not contain the nonce: %q", challenge.Message)
+			}
+		})
+	}
+}
+
+func TestChallengeStore(t *testing.T) {
+	t.Run("evicts the oldest challenge at the cap", func(t *testing.T) {
+		store := NewChallengeStore(time.Minute, 3)
+		var nonces []string
+		for range 5 {
+			challenge, err := store.Issue(ActionLink, "did:web:example.com", testAddress)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nonces = append(nonces, challenge.Nonce)
+		}
+
+		if store.Len() != 3 {
+			t.Fatalf("Len = %d, want 3", store.Len())
+		}
+		for i, nonce := range nonces {
+			_, err := store.Consume(nonce)
+			if evicted := i < 2; evicted != errors.Is(err, ErrChallengeNotFound) {
+				t.Fatalf("challenge %d: error = %v, evicted = %t", i, err, evicted)
+			}
+		}
+	})
+
+	t.Run("expired challenges are rejected and cleaned up", func(t *testing.T) {
+		store := NewChallengeStore(time.Millisecond, 0)
+		expired, _ := store.Issue(ActionLink, "did:web:example.com", testAddress)
+		time.Sleep(5 * time.Millisecond)
+
+		if _, err := store.Consume(expired.Nonce); !errors.Is(err, ErrChallengeNotFound) {
+			t.Fatalf("Consume expired error = %v, want ErrChallengeNotFound", err)
+		}
+
+		store.Issue(ActionLink, "did:web:example.com", testAddress)
+		time.Sleep(5 * time.Millisecond)
+		store.Issue(ActionLink, "did:web:example.com", testAddress)
+		if store.Len() != 1 {
+			t.Fatalf("Len = %d, want 1 after expired challenges are cleaned up", store.Len())
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package did
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PKHResolver 解析 did:pkh，地址直接编码在 CAIP-10 账户标识中
+// 例如 did:pkh:eip155:1:0x742d35Cc6634C0532925a3b844Bc454e4438f44e
+type PKHResolver struct{}
+
+func NewPKHResolver() Resolver {
+	return &PKHResolver{}
+}
+
+func (r *PKHResolver) Resolve(_ context.Context, did string) (string, error) {
+	method, id, err := Parse(did)
+	if err != nil {
+		return "", err
+	}
+	if method != "pkh" {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedMethod, method)
+	}
+
+	// CAIP-10: namespace:reference:account_address
+	parts := strings.Split(id, ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: malformed did:pkh account id %s", ErrInvalidDID, id)
+	}
+
+	// 只支持 EVM 链的账户
+	if parts[0] != "eip155" {
+		return "", fmt.Errorf("%w: unsupported did:pkh namespace %s", ErrNotFound, parts[0])
+	}
+	if !addressRegex.MatchString(parts[2]) {
+		return "", fmt.Errorf("%w: invalid address in %s", ErrInvalidDID, did)
+	}
+
+	return parts[2], nil
+}
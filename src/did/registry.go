@@ -0,0 +1,187 @@
+package did
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAlreadyLinked DID 已经关联到另一个地址
+var ErrAlreadyLinked = errors.New("DID is already linked to another address")
+
+// Link 表示一条地址与 DID 的关联记录
+type Link struct {
+	Address  string    `json:"address"`
+	DID      string    `json:"did"`
+	LinkedAt time.Time `json:"linkedAt"`
+}
+
+// Registry 保存用户登记的地址与 DID 的双向关联
+// 一个地址可以关联多个 DID，一个 DID 只能关联一个地址
+type Registry interface {
+	Link(address string, did string) error
+	Unlink(address string, did string) error
+	// DIDs 返回地址关联的所有 DID，按关联时间排序；没有关联时返回 ErrNotFound
+	DIDs(address string) ([]string, error)
+	// Address 返回 DID 关联的地址；没有关联时返回 ErrNotFound
+	Address(did string) (string, error)
+}
+
+// FileRegistry 基于 JSON 文件持久化的关联表，path 为空时只保存在内存中
+type FileRegistry struct {
+	mu    sync.RWMutex
+	path  string
+	links []Link
+}
+
+type registryFile struct {
+	Links []Link `json:"links"`
+}
+
+// NewFileRegistry 创建关联表，并从 path 加载已有记录（文件不存在时视为空表）
+func NewFileRegistry(path string) (*FileRegistry, error) {
+	r := &FileRegistry{
+		path:  path,
+		links: make([]Link, 0),
+	}
+
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DID registry: %v", err)
+	}
+
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode DID registry: %v", err)
+	}
+	r.links = append(r.links, file.Links...)
+
+	return r, nil
+}
+
+func (r *FileRegistry) Link(address string, did string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, link := range r.links {
+		if link.DID != did {
+			continue
+		}
+		if strings.EqualFold(link.Address, address) {
+			// 已经关联，重复登记不做处理
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrAlreadyLinked, did)
+	}
+
+	links := append(append(make([]Link, 0, len(r.links)+1), r.links...), Link{
+		Address:  strings.ToLower(address),
+		DID:      did,
+		LinkedAt: time.Now().UTC(),
+	})
+
+	return r.replace(links)
+}
+
+func (r *FileRegistry) Unlink(address string, did string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, link := range r.links {
+		if link.DID == did && strings.EqualFold(link.Address, address) {
+			links := append(append(make([]Link, 0, len(r.links)-1), r.links[:i]...), r.links[i+1:]...)
+			return r.replace(links)
+		}
+	}
+
+	return fmt.Errorf("%w: %s is not linked to %s", ErrNotFound, did, address)
+}
+
+func (r *FileRegistry) DIDs(address string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	links := make([]Link, 0)
+	for _, link := range r.links {
+		if strings.EqualFold(link.Address, address) {
+			links = append(links, link)
+		}
+	}
+
+	if len(links) == 0 {
+		return nil, fmt.Errorf("%w: no DID linked to %s", ErrNotFound, address)
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		return links[i].LinkedAt.Before(links[j].LinkedAt)
+	})
+
+	dids := make([]string, len(links))
+	for i, link := range links {
+		dids[i] = link.DID
+	}
+	return dids, nil
+}
+
+func (r *FileRegistry) Address(did string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, link := range r.links {
+		if link.DID == did {
+			return link.Address, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrNotFound, did)
+}
+
+// 先持久化新的关联表，成功后再替换内存中的记录，调用方需持有写锁
+func (r *FileRegistry) replace(links []Link) error {
+	if err := r.save(links); err != nil {
+		return err
+	}
+	r.links = links
+	return nil
+}
+
+// 将关联表写入临时文件后原子替换
+func (r *FileRegistry) save(links []Link) error {
+	if r.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(registryFile{Links: links}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode DID registry: %v", err)
+	}
+
+	if dir := filepath.Dir(r.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create DID registry directory: %v", err)
+		}
+	}
+
+	tmpPath := r.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write DID registry: %v", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		return fmt.Errorf("failed to write DID registry: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package did
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testAddress = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+
+func TestFileRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "did_registry.json")
+	registry, err := NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := registry.Link(testAddress, "did:web:example.com"); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if err := registry.Link(strings.ToLower(testAddress), "did:web:example.com"); err != nil {
+		t.Fatalf("repeated Link: %v", err)
+	}
+	if err := registry.Link("0x1111111111111111111111111111111111111111", "did:web:example.com"); !errors.Is(err, ErrAlreadyLinked) {
+		t.Fatalf("Link to another address error = %v, want ErrAlreadyLinked", err)
+	}
+
+	// 重新加载后关联仍然存在
+	reloaded, err := NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dids, err := reloaded.DIDs(testAddress)
+	if err != nil || len(dids) != 1 || dids[0] != "did:web:example.com" {
+		t.Fatalf("DIDs after reload = %v, %v", dids, err)
+	}
+
+	if err := reloaded.Unlink(testAddress, "did:web:example.com"); err != nil {
+		t.Fatalf("Unlink: %v", err)
+	}
+	if _, err := reloaded.Address("did:web:example.com"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Address after Unlink error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,66 @@
+package did
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidDID DID 格式不正确
+	ErrInvalidDID = errors.New("invalid DID")
+	// ErrUnsupportedMethod 不支持的 DID 方法
+	ErrUnsupportedMethod = errors.New("unsupported DID method")
+	// ErrNotFound DID 无法解析到钱包地址
+	ErrNotFound = errors.New("DID not found")
+)
+
+// 以太坊地址正则表达式
+var addressRegex = regexp.MustCompile("^0x[a-fA-F0-9]{40}$")
+
+// Resolver 将 DID 解析为关联的以太坊地址
+type Resolver interface {
+	Resolve(ctx context.Context, did string) (string, error)
+}
+
+// MethodResolver 根据 DID 方法将解析请求分发给对应的解析器
+type MethodResolver struct {
+	methods map[string]Resolver
+}
+
+// NewMethodResolver 创建一个空的方法分发解析器
+func NewMethodResolver() *MethodResolver {
+	return &MethodResolver{
+		methods: make(map[string]Resolver),
+	}
+}
+
+// Register 为指定的 DID 方法（如 "pkh"、"ethr"、"web"）注册解析器
+func (r *MethodResolver) Register(method string, resolver Resolver) {
+	r.methods[method] = resolver
+}
+
+func (r *MethodResolver) Resolve(ctx context.Context, did string) (string, error) {
+	method, _, err := Parse(did)
+	if err != nil {
+		return "", err
+	}
+
+	resolver, ok := r.methods[method]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedMethod, method)
+	}
+
+	return resolver.Resolve(ctx, did)
+}
+
+// Parse 将 DID 拆分为方法名和方法特定标识符
+func Parse(did string) (string, string, error) {
+	parts := strings.SplitN(did, ":", 3)
+	if len(parts) != 3 || parts[0] != "did" || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidDID, did)
+	}
+	return parts[1], parts[2], nil
+}
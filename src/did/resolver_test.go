@@ -0,0 +1,176 @@
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/web3-smart-wallet/src/services"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		did        string
+		wantMethod string
+		wantID     string
+		wantErr    bool
+	}{
+		{did: "did:pkh:eip155:1:" + testAddress, wantMethod: "pkh", wantID: "eip155:1:" + testAddress},
+		{did: "did:web:example.com", wantMethod: "web", wantID: "example.com"},
+		{did: "did:web", wantErr: true},
+		{did: "did::example.com", wantErr: true},
+		{did: "did:web:", wantErr: true},
+		{did: "uri:web:example.com", wantErr: true},
+		{did: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.did, func(t *testing.T) {
+			method, id, err := Parse(tt.did)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDID) {
+					t.Fatalf("error = %v, want ErrInvalidDID", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if method != tt.wantMethod || id != tt.wantID {
+				t.Fatalf("Parse = (%s, %s), want (%s, %s)", method, id, tt.wantMethod, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestMethodResolver(t *testing.T) {
+	resolver := NewMethodResolver()
+	resolver.Register("pkh", NewPKHResolver())
+	resolver.Register("ethr", NewEthrResolver(nil))
+
+	tests := []struct {
+		name    string
+		did     string
+		want    string
+		wantErr error
+	}{
+		{name: "pkh", did: "did:pkh:eip155:1:" + testAddress, want: testAddress},
+		{name: "ethr", did: "did:ethr:" + testAddress, want: testAddress},
+		{name: "unregistered method", did: "did:key:z6Mk", wantErr: ErrUnsupportedMethod},
+		{name: "invalid", did: "not-a-did", wantErr: ErrInvalidDID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.Resolve(context.Background(), tt.did)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("address = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPKHResolver(t *testing.T) {
+	tests := []struct {
+		name    string
+		did     string
+		want    string
+		wantErr error
+	}{
+		{name: "mainnet", did: "did:pkh:eip155:1:" + testAddress, want: testAddress},
+		{name: "base", did: "did:pkh:eip155:8453:" + testAddress, want: testAddress},
+		{name: "non-EVM namespace", did: "did:pkh:solana:4sGjMW1sUnHzSxGspuhpqLDx6wiyjNtZ:CKg5d12Jhpej1JqtmxLJgaFqqeYjxgPqToJ4LBdvG9Ev", wantErr: ErrNotFound},
+		{name: "malformed account id", did: "did:pkh:eip155:" + testAddress, wantErr: ErrInvalidDID},
+		{name: "invalid address", did: "did:pkh:eip155:1:0x1234", wantErr: ErrInvalidDID},
+		{name: "other method", did: "did:ethr:" + testAddress, wantErr: ErrUnsupportedMethod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPKHResolver().Resolve(context.Background(), tt.did)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("address = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEthrResolver(t *testing.T) {
+	const owner = "0x1111111111111111111111111111111111111111"
+
+	// 模拟 ERC-1056 注册表，identityOwner 返回 owner
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+			Params []json.RawMessage
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var call struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		json.Unmarshal(req.Params[0], &call)
+		if req.Method != "eth_call" || call.To != ethrRegistryAddress || !strings.HasPrefix(call.Data, identityOwnerSelector) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "error": map[string]interface{}{"code": -32000, "message": "unexpected call"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "result": "0x" + strings.Repeat("0", 24) + strings.TrimPrefix(owner, "0x")})
+	}))
+	defer node.Close()
+
+	resolver := NewEthrResolver(map[string]string{"mainnet": node.URL})
+
+	tests := []struct {
+		name    string
+		did     string
+		want    string
+		wantErr error
+	}{
+		{name: "registry owner", did: "did:ethr:" + testAddress, want: owner},
+		{name: "chain id 0x1 is mainnet", did: "did:ethr:0x1:" + testAddress, want: owner},
+		{name: "network without rpc returns identifier", did: "did:ethr:sepolia:" + testAddress, want: testAddress},
+		{name: "public key identifier", did: "did:ethr:0x02b97c30de767f084ce3080168ee293053ba33b235d7116a3263d29f1450936b71", wantErr: ErrInvalidDID},
+		{name: "other method", did: "did:pkh:eip155:1:" + testAddress, wantErr: ErrUnsupportedMethod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.Resolve(context.Background(), tt.did)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("address = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 注册表查询失败时返回上游错误，由调用方归类为 502/503/504
+func TestEthrResolverUpstreamError(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "error": map[string]interface{}{"code": -32602, "message": "invalid argument 0"}})
+	}))
+	defer node.Close()
+
+	resolver := NewEthrResolver(map[string]string{"mainnet": node.URL})
+	_, err := resolver.Resolve(context.Background(), "did:ethr:"+testAddress)
+
+	var upstream *services.UpstreamError
+	if !errors.As(err, &upstream) || upstream.Method != "eth_call" {
+		t.Fatalf("error = %v, want an eth_call *services.UpstreamError", err)
+	}
+	if strings.Contains(err.Error(), node.URL) {
+		t.Fatalf("error %q contains the node URL", err)
+	}
+}
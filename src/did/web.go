@@ -0,0 +1,130 @@
+package did
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/web3-smart-wallet/src/services"
+)
+
+// Document 是 DID 文档中解析地址所需的部分
+type Document struct {
+	ID                 string               `json:"id"`
+	VerificationMethod []VerificationMethod `json:"verificationMethod"`
+}
+
+// VerificationMethod DID 文档中的验证方法
+type VerificationMethod struct {
+	ID                  string `json:"id"`
+	Type                string `json:"type"`
+	Controller          string `json:"controller"`
+	BlockchainAccountID string `json:"blockchainAccountId,omitempty"`
+	EthereumAddress     string `json:"ethereumAddress,omitempty"`
+}
+
+// DID 文档大小上限，文档只需包含验证方法，正常情况下只有几 KB
+const maxDocumentSize = 1 << 20
+
+// WebResolver 解析 did:web，从域名下托管的 did.json 中读取以太坊账户
+// 文档地址由用户决定，通过共用上游客户端的公网请求获取（services.GetPublicJSON），只允许连接公网 IP
+type WebResolver struct {
+	scheme string
+	// fetch 获取并解析文档，测试时替换为访问本地服务的实现
+	fetch func(ctx context.Context, url string, out interface{}) error
+}
+
+// NewWebResolver 创建 did:web 解析器，scheme 通常为 "https"
+func NewWebResolver(scheme string) Resolver {
+	if scheme == "" {
+		scheme = "https"
+	}
+	return &WebResolver{
+		scheme: scheme,
+		fetch: func(ctx context.Context, url string, out interface{}) error {
+			return services.GetPublicJSON(ctx, url, "did_web_document", maxDocumentSize, out)
+		},
+	}
+}
+
+func (r *WebResolver) Resolve(ctx context.Context, did string) (string, error) {
+	documentURL, err := r.documentURL(did)
+	if err != nil {
+		return "", err
+	}
+
+	var document Document
+	if err := r.fetch(ctx, documentURL, &document); err != nil {
+		var upstream *services.UpstreamError
+		if errors.As(err, &upstream) && (upstream.Status == http.StatusNotFound || upstream.Status == http.StatusGone) {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, did)
+		}
+		if errors.Is(err, services.ErrNonPublicAddress) {
+			return "", fmt.Errorf("%w: did:web host of %s is not a public address", ErrNotFound, did)
+		}
+		return "", fmt.Errorf("failed to fetch DID document: %w", err)
+	}
+
+	if document.ID != did {
+		return "", fmt.Errorf("%w: DID document id %q does not match %q", ErrNotFound, document.ID, did)
+	}
+
+	address := document.Address()
+	if address == "" {
+		return "", fmt.Errorf("%w: no ethereum account in DID document for %s", ErrNotFound, did)
+	}
+	return address, nil
+}
+
+// 按 did:web 规范将 DID 转换为文档 URL
+// did:web:example.com            -> https://example.com/.well-known/did.json
+// did:web:example.com:user:alice -> https://example.com/user/alice/did.json
+func (r *WebResolver) documentURL(did string) (string, error) {
+	method, id, err := Parse(did)
+	if err != nil {
+		return "", err
+	}
+	if method != "web" {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedMethod, method)
+	}
+
+	segments := strings.Split(id, ":")
+	for i, segment := range segments {
+		decoded, err := url.PathUnescape(segment)
+		if err != nil || decoded == "" {
+			return "", fmt.Errorf("%w: %s", ErrInvalidDID, did)
+		}
+		segments[i] = decoded
+	}
+
+	host := segments[0]
+	if strings.ContainsAny(host, "/?#@") {
+		return "", fmt.Errorf("%w: invalid did:web host %s", ErrInvalidDID, host)
+	}
+
+	path := "/.well-known"
+	if len(segments) > 1 {
+		path = "/" + strings.Join(segments[1:], "/")
+	}
+
+	return fmt.Sprintf("%s://%s%s/did.json", r.scheme, host, path), nil
+}
+
+// Address 返回文档中第一个 EVM 账户地址，没有则返回空字符串
+func (d Document) Address() string {
+	for _, method := range d.VerificationMethod {
+		if method.EthereumAddress != "" && addressRegex.MatchString(method.EthereumAddress) {
+			return method.EthereumAddress
+		}
+
+		// CAIP-10: eip155:<chainId>:<address>
+		parts := strings.Split(method.BlockchainAccountID, ":")
+		if len(parts) == 3 && parts[0] == "eip155" && addressRegex.MatchString(parts[2]) {
+			return parts[2]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/web3-smart-wallet/src/services"
+)
+
+func TestWebResolverDocumentURL(t *testing.T) {
+	resolver := &WebResolver{scheme: "https"}
+
+	tests := []struct {
+		did     string
+		want    string
+		wantErr error
+	}{
+		{did: "did:web:example.com", want: "https://example.com/.well-known/did.json"},
+		{did: "did:web:example.com:user:alice", want: "https://example.com/user/alice/did.json"},
+		{did: "did:web:localhost%3A8443", want: "https://localhost:8443/.well-known/did.json"},
+		{did: "did:web:example.com::alice", wantErr: ErrInvalidDID},
+		{did: "did:web:evil.com%2F@example.com", wantErr: ErrInvalidDID},
+		{did: "did:web:example.com%zz", wantErr: ErrInvalidDID},
+		{did: "did:ethr:" + testAddress, wantErr: ErrUnsupportedMethod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.did, func(t *testing.T) {
+			got, err := resolver.documentURL(tt.did)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("url = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebResolverResolve(t *testing.T) {
+	const did = "did:web:example.com"
+
+	document := func(id string, methods ...VerificationMethod) func(context.Context, string, interface{}) error {
+		return func(_ context.Context, url string, out interface{}) error {
+			if url != "https://example.com/.well-known/did.json" {
+				return fmt.Errorf("unexpected url %s", url)
+			}
+			data, _ := json.Marshal(Document{ID: id, VerificationMethod: methods})
+			return json.Unmarshal(data, out)
+		}
+	}
+	failing := func(err error) func(context.Context, string, interface{}) error {
+		return func(context.Context, string, interface{}) error {
+			return err
+		}
+	}
+
+	tests := []struct {
+		name      string
+		fetch     func(context.Context, string, interface{}) error
+		want      string
+		wantErr   error
+		wantOther bool // 期望非哨兵错误（上游故障）
+	}{
+		{
+			name:  "ethereumAddress",
+			fetch: document(did, VerificationMethod{ID: did + "#owner", EthereumAddress: testAddress}),
+			want:  testAddress,
+		},
+		{
+			name:  "blockchainAccountId",
+			fetch: document(did, VerificationMethod{ID: did + "#key-1", BlockchainAccountID: "eip155:1:" + testAddress}),
+			want:  testAddress,
+		},
+		{
+			name:    "no ethereum account",
+			fetch:   document(did, VerificationMethod{ID: did + "#key-1", BlockchainAccountID: "solana:mainnet:abc"}),
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "document for another DID",
+			fetch:   document("did:web:other.com", VerificationMethod{EthereumAddress: testAddress}),
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "document not found",
+			fetch:   failing(&services.UpstreamError{Kind: services.UpstreamInvalidParams, Status: http.StatusNotFound, Err: errors.New("unexpected status 404")}),
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "non-public host",
+			fetch:   failing(fmt.Errorf("request failed: %w", services.ErrNonPublicAddress)),
+			wantErr: ErrNotFound,
+		},
+		{
+			name:      "upstream failure",
+			fetch:     failing(&services.UpstreamError{Kind: services.UpstreamFailed, Status: http.StatusBadGateway, Err: errors.New("unexpected status 502")}),
+			wantOther: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &WebResolver{scheme: "https", fetch: tt.fetch}
+			got, err := resolver.Resolve(context.Background(), did)
+			switch {
+			case tt.wantOther:
+				if err == nil || errors.Is(err, ErrNotFound) {
+					t.Fatalf("error = %v, want a non-ErrNotFound error", err)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("address = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 默认的 fetch 只连接公网 IP，指向本机的 did:web 不会被请求
+func TestWebResolverRejectsLoopback(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	did := "did:web:" + strings.ReplaceAll(host, ":", "%3A")
+
+	_, err := NewWebResolver("http").Resolve(context.Background(), did)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("error = %v, want ErrNotFound", err)
+	}
+	if requested {
+		t.Fatal("loopback DID document was requested")
+	}
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/utils"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader 请求 ID 头，客户端或网关传入时沿用，否则生成新的
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware 为每个请求生成带 request_id、method、path 的 logger 并放入 UserContext，
+// 请求结束后记录一条访问日志；/health 和 /ready 的访问日志使用 debug 级别，避免探针刷屏
+func Middleware(logger *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		requestID := c.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Set(RequestIDHeader, requestID)
+
+		requestLogger := logger.With(
+			slog.String("request_id", requestID),
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+		)
+		// 追踪中间件在前面时，日志中带上 trace_id 便于和链路关联
+		if spanContext := trace.SpanContextFromContext(c.UserContext()); spanContext.IsValid() {
+			requestLogger = requestLogger.With(slog.String("trace_id", spanContext.TraceID().String()))
+		}
+		c.SetUserContext(WithLogger(c.UserContext(), requestLogger))
+
+		// 先交给 ErrorHandler 生成响应，访问日志才能拿到最终的状态码
+		_ = utils.HandleNext(c)
+
+		status := c.Response().StatusCode()
+		level := slog.LevelInfo
+		switch {
+		case c.Path() == "/health" || c.Path() == "/ready":
+			// 关闭期间 /ready 返回 503 是预期行为，不记为错误
+			level = slog.LevelDebug
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		}
+
+		// 下游中间件（如认证）可能向 UserContext 中的 logger 添加了字段
+		FromContext(c.UserContext()).LogAttrs(c.UserContext(), level, "request",
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("ip", c.IP()),
+		)
+		return nil
+	}
+}
+
+// 辅助函数：生成 16 字节的随机请求 ID
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
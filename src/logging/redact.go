@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const redacted = "[REDACTED]"
+
+// 值整体脱敏的字段名（小写）
+var sensitiveKeys = map[string]bool{
+	"api_key":       true,
+	"apikey":        true,
+	"x-api-key":     true,
+	"authorization": true,
+	"cookie":        true,
+	"password":      true,
+	"secret":        true,
+	"signature":     true,
+}
+
+// 字符串中需要脱敏的片段
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// Ankr 等服务把 API key 放在 URL 路径中，例如 https://rpc.ankr.com/multichain/<key>
+	{regexp.MustCompile(`(rpc\.ankr\.com/[A-Za-z0-9_-]+/)[A-Za-z0-9]+`), "${1}" + redacted},
+	// Alchemy 的 JSON-RPC 接口 /v2/<key> 和 NFT 接口 /nft/v3/<key>/getNFTsForOwner
+	{regexp.MustCompile(`(alchemy\.com/(?:v2|nft/v[0-9]+)/)[A-Za-z0-9_-]+`), "${1}" + redacted},
+	// 请求头形式的 key：Authorization: Bearer <key>（Covalent）、X-API-Key: <key>（Moralis）
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`), "${1}" + redacted},
+	{regexp.MustCompile(`(?i)(x-api-key["']?\s*[:=]\s*["']?)[^\s"',}]+`), "${1}" + redacted},
+	// Covalent key（cqt_ 前缀）和 Moralis key（JWT）
+	{regexp.MustCompile(`cqt_[A-Za-z0-9]+`), "cqt_" + redacted},
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), redacted},
+	// URL 查询参数中的 key
+	{regexp.MustCompile(`(?i)((?:api[_-]?key|key|token)=)[^&\s"]+`), "${1}" + redacted},
+	// 本服务签发的 API key
+	{regexp.MustCompile(`wsk_[0-9a-fA-F]+`), "wsk_" + redacted},
+	// 钱包地址只保留前后 4 位；交易哈希（64 位）不受影响
+	{regexp.MustCompile(`\b0x([0-9a-fA-F]{4})[0-9a-fA-F]{32}([0-9a-fA-F]{4})\b`), "0x${1}…${2}"},
+}
+
+// Redact 是 slog.HandlerOptions.ReplaceAttr，对敏感字段和字符串中的 API key、钱包地址脱敏
+func Redact(groups []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, RedactString(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, RedactString(err.Error()))
+		}
+		if values, ok := attr.Value.Any().([]string); ok {
+			redactedValues := make([]string, len(values))
+			for i, value := range values {
+				redactedValues[i] = RedactString(value)
+			}
+			return slog.Any(attr.Key, redactedValues)
+		}
+	}
+	return attr
+}
+
+// 通过 AddSecrets 登记的密钥，出现在任何位置都会被替换
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// AddSecrets 登记配置中的上游 API key 等密钥，不依赖 URL 格式，任何出现的位置都会脱敏；
+// 过短的值（少于 8 个字符）会误伤正常内容，忽略
+func AddSecrets(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, value := range values {
+		if len(value) >= 8 {
+			secrets = append(secrets, value)
+		}
+	}
+}
+
+// RedactString 对字符串中的 API key 和钱包地址脱敏
+func RedactString(value string) string {
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		value = strings.ReplaceAll(value, secret, redacted)
+	}
+	secretsMu.RUnlock()
+
+	for _, r := range redactions {
+		value = r.pattern.ReplaceAllString(value, r.replacement)
+	}
+	return value
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/web3-smart-wallet/src/breaker"
+	"github.com/web3-smart-wallet/src/cache"
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+// Registry 应用使用的指标注册表，包含 Go 运行时和进程指标
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	httpRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	})
+
+	upstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_requests_total",
+		Help: "Upstream calls by method (JSON-RPC method such as ankr_getAccountBalance, or host for REST APIs) and outcome.",
+	}, []string{"method", "outcome"})
+	upstreamRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "upstream_request_duration_seconds",
+		Help:    "Upstream call latency by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		httpRequestsInFlight,
+		upstreamRequests,
+		upstreamRequestDuration,
+	)
+}
+
+// 未匹配到任何路由（如 404 或被中间件拒绝）的请求使用的 route 标签
+const unmatchedRoute = "unmatched"
+
+// Middleware 记录请求数、延迟和正在处理的请求数，route 标签使用路由模板（如 /api/user/:address/balance）
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		httpRequestsInFlight.Inc()
+		defer httpRequestsInFlight.Dec()
+
+		// 先交给 ErrorHandler 生成响应，才能拿到最终的状态码
+		_ = utils.HandleNext(c)
+
+		// 中间件（app.Use）的路由路径为 "/"，说明请求没有到达任何业务路由
+		route := unmatchedRoute
+		if r := c.Route(); r != nil && r.Path != "/" {
+			route = r.Path
+		}
+		status := strconv.Itoa(c.Response().StatusCode())
+		httpRequests.WithLabelValues(route, c.Method(), status).Inc()
+		httpRequestDuration.WithLabelValues(route, c.Method(), status).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
+
+// Handler 输出 Registry 中的所有指标
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
+
+// ObserveUpstream 记录一次上游调用，outcome 为 "ok" 或错误类型
+func ObserveUpstream(method string, duration time.Duration, outcome string) {
+	upstreamRequests.WithLabelValues(method, outcome).Inc()
+	upstreamRequestDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// RegisterCacheStats 导出缓存命中统计，每次抓取时读取 stats 的快照
+func RegisterCacheStats(stats *cache.Stats) {
+	Registry.MustRegister(&cacheCollector{stats: stats})
+}
+
+var (
+	cacheHitsDesc     = prometheus.NewDesc("cache_hits_total", "Cache hits by route.", []string{"route"}, nil)
+	cacheMissesDesc   = prometheus.NewDesc("cache_misses_total", "Cache misses by route.", []string{"route"}, nil)
+	cacheHitRatioDesc = prometheus.NewDesc("cache_hit_ratio", "Cache hit ratio by route since start.", []string{"route"}, nil)
+)
+
+// cacheCollector 把 cache.Stats 中已有的计数转换为指标
+type cacheCollector struct {
+	stats *cache.Stats
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheHitRatioDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for route, counts := range c.stats.Snapshot() {
+		hitRatio := 0.0
+		if total := counts.Hits + counts.Misses; total > 0 {
+			hitRatio = float64(counts.Hits) / float64(total)
+		}
+		ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(counts.Hits), route)
+		ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(counts.Misses), route)
+		ch <- prometheus.MustNewConstMetric(cacheHitRatioDesc, prometheus.GaugeValue, hitRatio, route)
+	}
+}
+
+// RegisterBreakers 导出上游熔断器的状态和拒绝次数，每次抓取时读取快照
+func RegisterBreakers(breakers *breaker.Set) {
+	Registry.MustRegister(&breakerCollector{breakers: breakers})
+}
+
+var (
+	breakerStateDesc    = prometheus.NewDesc("circuit_breaker_state", "Circuit breaker state by upstream: 0 closed, 1 half-open, 2 open.", []string{"name"}, nil)
+	breakerRejectedDesc = prometheus.NewDesc("circuit_breaker_rejected_total", "Requests rejected by an open circuit breaker.", []string{"name"}, nil)
+)
+
+// breakerCollector 把 breaker.Set 的状态转换为指标
+type breakerCollector struct {
+	breakers *breaker.Set
+}
+
+func (c *breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- breakerStateDesc
+	ch <- breakerRejectedDesc
+}
+
+func (c *breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, status := range c.breakers.Snapshot() {
+		ch <- prometheus.MustNewConstMetric(breakerStateDesc, prometheus.GaugeValue, float64(status.State), status.Name)
+		ch <- prometheus.MustNewConstMetric(breakerRejectedDesc, prometheus.CounterValue, float64(status.Rejected), status.Name)
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/apierror"
+	"github.com/web3-smart-wallet/src/auth"
+	"github.com/web3-smart-wallet/src/logging"
+)
+
+// Config 限流中间件配置
+type Config struct {
+	Store Store
+	// KeyLimits 按 API key 限流，请求未通过认证（如关闭认证时）时不生效
+	KeyLimits Limits
+	// IPLimits 按客户端 IP 限流
+	IPLimits Limits
+	// ExemptPaths 不限流的路径
+	ExemptPaths []string
+}
+
+// IPMiddleware 按客户端 IP 限流，令牌耗尽时返回 429
+// 需注册在认证中间件之前，使用大量无效 key 的请求同样受 IP 限额约束
+func IPMiddleware(config Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if auth.IsExempt(c.Path(), config.ExemptPaths) {
+			return c.Next()
+		}
+		return take(c, config.Store, "ip", c.IP(), config.IPLimits)
+	}
+}
+
+// KeyMiddleware 按 API key 限流，令牌耗尽时返回 429，需注册在认证中间件之后
+// 响应中的 X-RateLimit-* 头取剩余次数最少的维度
+func KeyMiddleware(config Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := auth.FromContext(c)
+		if key == nil || auth.IsExempt(c.Path(), config.ExemptPaths) {
+			return c.Next()
+		}
+		return take(c, config.Store, "key", key.ID, config.KeyLimits)
+	}
+}
+
+// 辅助函数：从一个维度的令牌桶中取令牌，允许时继续处理请求
+func take(c *fiber.Ctx, store Store, dimension string, id string, limits Limits) error {
+	path := c.Path()
+	limit, bucket := limits.match(path)
+	if !limit.Enabled() {
+		return c.Next()
+	}
+
+	result, err := store.Take(c.UserContext(), fmt.Sprintf("%s:%s:%s", dimension, id, bucket), limit)
+	if err != nil {
+		// 存储不可用时放行，避免限流存储故障导致整个服务不可用
+		logging.FromContext(c.UserContext()).Warn("rate limit store error", "error", err)
+		return c.Next()
+	}
+
+	// 前一个维度已经写入的剩余次数更少时保留前一个维度的响应头
+	if remaining, err := strconv.Atoi(c.GetRespHeader("X-RateLimit-Remaining")); err != nil || !result.Allowed || result.Remaining < remaining {
+		c.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	}
+	if !result.Allowed {
+		return apierror.RateLimited(max(time.Second, result.RetryAfter))
+	}
+	return c.Next()
+}
+
+// 辅助函数：时长向上取整为秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
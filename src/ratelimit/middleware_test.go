@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/apierror"
+	"github.com/web3-smart-wallet/src/auth"
+)
+
+// 总是失败的限流存储
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("redis: connection refused")
+}
+
+func TestMiddleware(t *testing.T) {
+	keys, _ := auth.NewFileKeyStore("")
+	apiKey, _, _ := keys.Create("test")
+
+	newApp := func(config Config) *fiber.App {
+		app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+		app.Use(IPMiddleware(config))
+		app.Use(auth.Middleware(keys, auth.DefaultExemptPaths...))
+		app.Use(KeyMiddleware(config))
+		app.Get("/*", func(c *fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+		return app
+	}
+	limits := func(value string) Limits {
+		l, err := ParseLimits(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		path   string
+		key    string
+		// wantStatuses 连续请求的期望状态码
+		wantStatuses  []int
+		wantRemaining string
+	}{
+		{
+			name:          "key limit",
+			config:        Config{Store: NewMemoryStore(), KeyLimits: limits("2/1h"), IPLimits: limits("100/1h")},
+			path:          "/api/user/0x1",
+			key:           apiKey,
+			wantStatuses:  []int{200, 200, 429},
+			wantRemaining: "0",
+		},
+		{
+			name:          "ip limit also applies to invalid keys",
+			config:        Config{Store: NewMemoryStore(), KeyLimits: limits("100/1h"), IPLimits: limits("2/1h")},
+			path:          "/api/user/0x1",
+			key:           "wsk_invalid",
+			wantStatuses:  []int{401, 401, 429},
+			wantRemaining: "0",
+		},
+		{
+			name:          "route override",
+			config:        Config{Store: NewMemoryStore(), KeyLimits: limits("100/1h,/api/user/*/portfolio=1/1h")},
+			path:          "/api/user/0x1/portfolio",
+			key:           apiKey,
+			wantStatuses:  []int{200, 429},
+			wantRemaining: "0",
+		},
+		{
+			name:          "headers show the tighter dimension",
+			config:        Config{Store: NewMemoryStore(), KeyLimits: limits("10/1h"), IPLimits: limits("5/1h")},
+			path:          "/api/user/0x1",
+			key:           apiKey,
+			wantStatuses:  []int{200},
+			wantRemaining: "4",
+		},
+		{
+			name:         "exempt path",
+			config:       Config{Store: NewMemoryStore(), IPLimits: limits("1/1h"), ExemptPaths: []string{"/health"}},
+			path:         "/health",
+			wantStatuses: []int{200, 200, 200},
+		},
+		{
+			name:         "store failure lets requests through",
+			config:       Config{Store: failingStore{}, KeyLimits: limits("1/1h"), IPLimits: limits("1/1h")},
+			path:         "/api/user/0x1",
+			key:          apiKey,
+			wantStatuses: []int{200, 200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newApp(tt.config)
+			for i, want := range tt.wantStatuses {
+				req := httptest.NewRequest("GET", tt.path, nil)
+				if tt.key != "" {
+					req.Header.Set(auth.HeaderName, tt.key)
+				}
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != want {
+					t.Fatalf("request %d: status = %d, want %d", i, resp.StatusCode, want)
+				}
+				if i < len(tt.wantStatuses)-1 {
+					continue
+				}
+				if got := resp.Header.Get("X-RateLimit-Remaining"); got != tt.wantRemaining {
+					t.Fatalf("X-RateLimit-Remaining = %q, want %q", got, tt.wantRemaining)
+				}
+				if want == fiber.StatusTooManyRequests {
+					if retryAfter := resp.Header.Get("Retry-After"); retryAfter == "" || retryAfter == "0" {
+						t.Fatalf("Retry-After = %q, want at least 1 second", retryAfter)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCeilSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{d: 0, want: 0},
+		{d: time.Millisecond, want: 1},
+		{d: time.Second, want: 1},
+		{d: 1500 * time.Millisecond, want: 2},
+	}
+	for _, tt := range tests {
+		if got := ceilSeconds(tt.d); got != tt.want {
+			t.Errorf("ceilSeconds(%s) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
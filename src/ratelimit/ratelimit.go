@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+// Limit 令牌桶配置：桶容量为 Requests，每个 Period 补满一次，零值表示不限制
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Result 一次取令牌的结果
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter 被拒绝时距离下一个令牌可用的时间
+	RetryAfter time.Duration
+	// Reset 距离令牌桶补满的时间
+	Reset time.Duration
+}
+
+// Store 保存令牌桶状态，可以是进程内内存，也可以是 Redis 等多副本共享的存储
+type Store interface {
+	// Take 从 key 对应的令牌桶中取一个令牌，ctx 为请求的 context，客户端断开时停止等待存储
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Enabled 是否启用限流
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// String 返回 "100/1m0s" 格式
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// 每秒补充的令牌数
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// 根据取令牌后桶中剩余的令牌数计算结果
+func (l Limit) result(allowed bool, tokens float64) Result {
+	rate := l.rate()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     l.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(l.Requests) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return result
+}
+
+// RouteLimit 匹配 Pattern 的路由使用独立的令牌桶和限额
+type RouteLimit struct {
+	// Pattern 按路径段匹配，"*" 匹配任意一段，例如 "/api/user/*/portfolio"
+	Pattern string
+	Limit   Limit
+}
+
+// Limits 一个维度（API key 或 IP）的限流配置
+type Limits struct {
+	Default Limit
+	Routes  []RouteLimit
+}
+
+// ParseLimits 解析 "600/1m,/api/user/*/portfolio=60/1m" 格式的配置，
+// 不带 "=" 的一项为默认限额，其余为按路由覆盖的限额（按顺序匹配）
+func ParseLimits(value string) (Limits, error) {
+	var limits Limits
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pattern, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			limit, err := ParseLimit(entry)
+			if err != nil {
+				return Limits{}, err
+			}
+			limits.Default = limit
+			continue
+		}
+
+		pattern = strings.TrimSpace(pattern)
+		if !strings.HasPrefix(pattern, "/") {
+			return Limits{}, fmt.Errorf("invalid route pattern %q", pattern)
+		}
+		limit, err := ParseLimit(spec)
+		if err != nil {
+			return Limits{}, err
+		}
+		limits.Routes = append(limits.Routes, RouteLimit{Pattern: pattern, Limit: limit})
+	}
+	return limits, nil
+}
+
+// UnmarshalText 支持从配置文件和环境变量中按 ParseLimits 的格式读取
+func (l *Limits) UnmarshalText(text []byte) error {
+	limits, err := ParseLimits(string(text))
+	if err != nil {
+		return err
+	}
+	*l = limits
+	return nil
+}
+
+// ParseLimit 解析 "100/1m" 格式的限额，"0" 或 "off" 表示不限制
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "0" || value == "off" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<period>", value)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad request count", value)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad period", value)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// match 返回 path 适用的限额和令牌桶名称，未匹配任何路由时使用默认限额（桶名为 "*"）
+func (l Limits) match(path string) (Limit, string) {
+	for _, route := range l.Routes {
+		if utils.MatchPath(route.Pattern, path) {
+			return route.Limit, route.Pattern
+		}
+	}
+	return l.Default, "*"
+}
+
+// 辅助函数：秒数转换为时长，负数视为 0
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
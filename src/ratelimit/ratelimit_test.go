@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limits
+		wantErr bool
+	}{
+		{value: "600/1m", want: Limits{Default: Limit{Requests: 600, Period: time.Minute}}},
+		{
+			value: "600/1m, /api/user/*/portfolio=60/1m",
+			want: Limits{
+				Default: Limit{Requests: 600, Period: time.Minute},
+				Routes:  []RouteLimit{{Pattern: "/api/user/*/portfolio", Limit: Limit{Requests: 60, Period: time.Minute}}},
+			},
+		},
+		{value: "off", want: Limits{}},
+		{value: "", want: Limits{}},
+		{value: "600", wantErr: true},
+		{value: "-1/1m", wantErr: true},
+		{value: "10/0s", wantErr: true},
+		{value: "10/soon", wantErr: true},
+		{value: "api/user=10/1m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLimits(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Default != tt.want.Default || len(got.Routes) != len(tt.want.Routes) {
+				t.Fatalf("ParseLimits = %+v, want %+v", got, tt.want)
+			}
+			for i := range got.Routes {
+				if got.Routes[i] != tt.want.Routes[i] {
+					t.Fatalf("route %d = %+v, want %+v", i, got.Routes[i], tt.want.Routes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLimitsMatch(t *testing.T) {
+	limits, _ := ParseLimits("600/1m,/api/user/*/portfolio=60/1m")
+
+	tests := []struct {
+		path       string
+		wantBucket string
+		wantLimit  int
+	}{
+		{path: "/api/user/0xabc/portfolio", wantBucket: "/api/user/*/portfolio", wantLimit: 60},
+		{path: "/api/user/0xabc", wantBucket: "*", wantLimit: 600},
+		{path: "/api/user/0xabc/portfolio/extra", wantBucket: "*", wantLimit: 600},
+	}
+	for _, tt := range tests {
+		limit, bucket := limits.match(tt.path)
+		if bucket != tt.wantBucket || limit.Requests != tt.wantLimit {
+			t.Errorf("match(%s) = %d %s, want %d %s", tt.path, limit.Requests, bucket, tt.wantLimit, tt.wantBucket)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit Limit
+		// takes 连续取令牌的次数，期望前 allowed 次通过
+		takes   int
+		allowed int
+	}{
+		{name: "burst up to capacity", limit: Limit{Requests: 3, Period: time.Hour}, takes: 5, allowed: 3},
+		{name: "single token", limit: Limit{Requests: 1, Period: time.Hour}, takes: 2, allowed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i := range tt.takes {
+				result, err := store.Take(context.Background(), "key:1:*", tt.limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				wantAllowed := i < tt.allowed
+				if result.Allowed != wantAllowed {
+					t.Fatalf("take %d: allowed = %t, want %t", i, result.Allowed, wantAllowed)
+				}
+				if result.Limit != tt.limit.Requests {
+					t.Fatalf("take %d: limit = %d, want %d", i, result.Limit, tt.limit.Requests)
+				}
+				if wantRemaining := max(tt.allowed-i-1, 0); result.Remaining != wantRemaining {
+					t.Fatalf("take %d: remaining = %d, want %d", i, result.Remaining, wantRemaining)
+				}
+				if !result.Allowed {
+					// 每个令牌需要 Period/Requests 补充
+					perToken := tt.limit.Period / time.Duration(tt.limit.Requests)
+					if result.RetryAfter <= 0 || result.RetryAfter > perToken {
+						t.Fatalf("take %d: RetryAfter = %s, want (0, %s]", i, result.RetryAfter, perToken)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreRefill(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 2, Period: 100 * time.Millisecond}
+
+	for range 2 {
+		store.Take(context.Background(), "ip:1.2.3.4:*", limit)
+	}
+	if result, _ := store.Take(context.Background(), "ip:1.2.3.4:*", limit); result.Allowed {
+		t.Fatal("bucket should be empty")
+	}
+	// 其他令牌桶不受影响
+	if result, _ := store.Take(context.Background(), "ip:5.6.7.8:*", limit); !result.Allowed {
+		t.Fatal("separate bucket should not be limited")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if result, _ := store.Take(context.Background(), "ip:1.2.3.4:*", limit); !result.Allowed {
+		t.Fatal("bucket should have refilled one token")
+	}
+}
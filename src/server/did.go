@@ -0,0 +1,105 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/apierror"
+	"github.com/web3-smart-wallet/src/did"
+	"github.com/web3-smart-wallet/src/tracing"
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+func (s Server) PostApiDidChallenge(c *fiber.Ctx) error {
+	span := tracing.StartHandler(c, "Server.PostApiDidChallenge")
+	defer span.End()
+
+	var body api.PostApiDidChallengeJSONRequestBody
+	if err := c.BodyParser(&body); err != nil {
+		return apierror.Validation("", "invalid_request", "Invalid request body")
+	}
+
+	// 签发挑战
+	challenge, err := s.didLinker.Challenge(string(body.Action), body.Did, body.Address)
+	if err != nil {
+		return linkError(err)
+	}
+
+	return c.JSON(api.DIDChallenge{
+		Action:    challenge.Action,
+		Address:   challenge.Address,
+		Did:       challenge.DID,
+		ExpiresAt: challenge.ExpiresAt,
+		Message:   challenge.Message,
+		Nonce:     challenge.Nonce,
+	})
+}
+
+func (s Server) PostApiDidLink(c *fiber.Ctx) error {
+	span := tracing.StartHandler(c, "Server.PostApiDidLink")
+	defer span.End()
+
+	var body api.PostApiDidLinkJSONRequestBody
+	if err := c.BodyParser(&body); err != nil {
+		return apierror.Validation("", "invalid_request", "Invalid request body")
+	}
+
+	// 验证签名并保存关联
+	if err := s.didLinker.Link(c.UserContext(), body.Did, body.Address, body.Nonce, body.Signature); err != nil {
+		return linkError(err)
+	}
+
+	return c.JSON(api.DIDLink{
+		Address: body.Address,
+		Did:     body.Did,
+		Linked:  true,
+	})
+}
+
+func (s Server) PostApiDidUnlink(c *fiber.Ctx) error {
+	span := tracing.StartHandler(c, "Server.PostApiDidUnlink")
+	defer span.End()
+
+	var body api.PostApiDidUnlinkJSONRequestBody
+	if err := c.BodyParser(&body); err != nil {
+		return apierror.Validation("", "invalid_request", "Invalid request body")
+	}
+
+	// 验证签名并解除关联
+	if err := s.didLinker.Unlink(body.Did, body.Address, body.Nonce, body.Signature); err != nil {
+		return linkError(err)
+	}
+
+	return c.JSON(api.DIDLink{
+		Address: body.Address,
+		Did:     body.Did,
+		Linked:  false,
+	})
+}
+
+// 辅助函数：将关联流程中的错误转换为对应的 API 错误
+func linkError(err error) error {
+	switch {
+	case errors.Is(err, did.ErrInvalidDID), errors.Is(err, did.ErrInvalidAddress), errors.Is(err, did.ErrInvalidAction):
+		return apierror.Validation("", "invalid_request", err.Error())
+	case errors.Is(err, did.ErrChallengeNotFound):
+		return apierror.Validation("nonce", "invalid_challenge", err.Error())
+	case errors.Is(err, utils.ErrInvalidSignature), errors.Is(err, did.ErrSignatureMismatch):
+		return apierror.Unauthorized("invalid_signature", err.Error())
+	case errors.Is(err, did.ErrDIDAddressMismatch), errors.Is(err, did.ErrAlreadyLinked):
+		return apierror.Conflict("did_conflict", err.Error())
+	case errors.Is(err, did.ErrUnresolvableDID):
+		// 解析失败的原因可能包含 did:web 等上游的内部信息，只写入日志
+		if errors.Is(err, did.ErrNotFound) || errors.Is(err, did.ErrUnsupportedMethod) {
+			return apierror.Validation("did", "did_unresolvable", "The DID could not be resolved to an address")
+		}
+		e := apierror.UpstreamFailed("did_resolution_failed", "The DID could not be resolved, please retry later")
+		e.Err = err
+		return e
+	case errors.Is(err, did.ErrNotFound):
+		return apierror.NotFound("link_not_found", err.Error())
+	default:
+		return apierror.Internal(err)
+	}
+}
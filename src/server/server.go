@@ -1,67 +1,142 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/apierror"
+	"github.com/web3-smart-wallet/src/did"
+	"github.com/web3-smart-wallet/src/logging"
 	"github.com/web3-smart-wallet/src/services"
+	"github.com/web3-smart-wallet/src/tracing"
 )
 
 // 添加地址验证正则表达式
 var addressRegex = regexp.MustCompile("^0x[a-fA-F0-9]{40}$")
 
 type Server struct {
-	ankrService services.AnkrServiceInterface
-	nftService  services.NFTServiceInterface
+	ankrService        services.AnkrServiceInterface
+	nftService         services.NFTServiceInterface
+	portfolioService   services.PortfolioServiceInterface
+	transactionService services.TransactionServiceInterface
+	transferService    services.TransferServiceInterface
+	didResolver        did.Resolver
+	didRegistry        did.Registry
+	didLinker          *did.Linker
+	defaultPageSize    int
 }
 
-func NewServer(ankrService services.AnkrServiceInterface, nftService services.NFTServiceInterface) api.ServerInterface {
+func NewServer(ankrService services.AnkrServiceInterface, nftService services.NFTServiceInterface, portfolioService services.PortfolioServiceInterface, transactionService services.TransactionServiceInterface, transferService services.TransferServiceInterface, didResolver did.Resolver, didRegistry did.Registry, didLinker *did.Linker, defaultPageSize int) api.ServerInterface {
 	return &Server{
-		ankrService: ankrService,
-		nftService:  nftService,
+		ankrService:        ankrService,
+		nftService:         nftService,
+		portfolioService:   portfolioService,
+		transactionService: transactionService,
+		transferService:    transferService,
+		didResolver:        didResolver,
+		didRegistry:        didRegistry,
+		didLinker:          didLinker,
+		defaultPageSize:    defaultPageSize,
 	}
 }
 
 func (s Server) GetHealth(c *fiber.Ctx) error {
+	span := tracing.StartHandler(c, "Server.GetHealth")
+	defer span.End()
+
 	return c.JSON(fiber.Map{
 		"status": "OK",
 	})
 }
 
 func (s Server) GetApiSearchAddressAddress(c *fiber.Ctx, address string) error {
-	//TODO implement me
-	panic("implement me")
+	span := tracing.StartHandler(c, "Server.GetApiSearchAddressAddress")
+	defer span.End()
+
+	// 验证地址格式
+	if !addressRegex.MatchString(address) {
+		return apierror.Validation("address", "invalid_address", "Invalid Ethereum address format")
+	}
+
+	// 查询地址关联的 DID
+	dids, err := s.didRegistry.DIDs(address)
+	if err != nil {
+		if errors.Is(err, did.ErrNotFound) {
+			return apierror.NotFound("address_not_found", "No DID linked to this address")
+		}
+		return apierror.Internal(err)
+	}
+
+	// 返回响应
+	return c.JSON(fiber.Map{
+		"address": address,
+		"did":     dids[0],
+		"dids":    dids,
+	})
 }
 
-func (s Server) GetApiSearchDidDid(c *fiber.Ctx, did string) error {
-	//TODO implement me
-	panic("implement me")
+func (s Server) GetApiSearchDidDid(c *fiber.Ctx, didParam string) error {
+	span := tracing.StartHandler(c, "Server.GetApiSearchDidDid")
+	defer span.End()
+
+	// 解析 DID 对应的钱包地址，解析器无法处理时再查询用户登记的关联
+	address, err := s.didResolver.Resolve(c.UserContext(), didParam)
+	if errors.Is(err, did.ErrNotFound) || errors.Is(err, did.ErrUnsupportedMethod) {
+		if linked, lookupErr := s.didRegistry.Address(didParam); lookupErr == nil {
+			address, err = linked, nil
+		}
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, did.ErrInvalidDID), errors.Is(err, did.ErrUnsupportedMethod):
+			return apierror.Validation("did", "invalid_did", err.Error())
+		case errors.Is(err, did.ErrNotFound):
+			return apierror.NotFound("did_not_found", err.Error())
+		default:
+			// did:web 文档或 did:ethr 注册表查询失败，按上游错误类型返回 502/503/504
+			return apierror.From(err)
+		}
+	}
+
+	// 返回响应
+	return c.JSON(fiber.Map{
+		"did":     didParam,
+		"address": address,
+	})
 }
 
 func (s Server) GetApiUserAddress(c *fiber.Ctx, address string, params api.GetApiUserAddressParams) error {
+	span := tracing.StartHandler(c, "Server.GetApiUserAddress")
+	defer span.End()
+
 	// 验证地址格式
 	if !addressRegex.MatchString(address) {
-		return c.Status(fiber.StatusBadRequest).JSON(api.Error{
-			Code:    "invalid_address",
-			Message: "Invalid Ethereum address format",
-		})
+		return apierror.Validation("address", "invalid_address", "Invalid Ethereum address format")
+	}
+
+	// 解析链参数
+	chains, err := resolveChains(params.Chain)
+	if err != nil {
+		return apierror.Validation("chain", "invalid_chain", err.Error())
 	}
 
 	// 获取分页参数
 	pageToken := c.Query("pageToken", "")
-	pageSize := 10 // 默认每页10个
+	pageSize := s.defaultPageSize
 
 	// 调用服务获取代币列表
-	tokens, nextPageToken, err := s.ankrService.GetTokenList(address, pageToken, pageSize)
+	ctx, source := services.WithSource(c.UserContext())
+	tokens, nextPageToken, err := s.ankrService.GetTokenList(ctx, address, chains, pageToken, pageSize)
+	setDataProvider(c, source)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(api.Error{
-			Code:    "internal_server_error",
-			Message: err.Error(),
-		})
+		return apierror.From(err)
 	}
 
 	// 构建下一页的完整URL
@@ -70,6 +145,11 @@ func (s Server) GetApiUserAddress(c *fiber.Ctx, address string, params api.GetAp
 		// 获取当前请求的基本URL
 		baseUrl := fmt.Sprintf("%s://%s%s", c.Protocol(), c.Hostname(), c.Path())
 		nextPageUrl = fmt.Sprintf("%s?pageToken=%s", baseUrl, nextPageToken)
+
+		// 如果有chain参数，也添加到URL中
+		if params.Chain != nil {
+			nextPageUrl = fmt.Sprintf("%s&chain=%s", nextPageUrl, strings.Join(chains, ","))
+		}
 	}
 
 	// 返回响应
@@ -82,15 +162,19 @@ func (s Server) GetApiUserAddress(c *fiber.Ctx, address string, params api.GetAp
 }
 
 func (s Server) GetApiUserAddressNfts(c *fiber.Ctx, address string, params api.GetApiUserAddressNftsParams) error {
+	span := tracing.StartHandler(c, "Server.GetApiUserAddressNfts")
+	defer span.End()
+
 	// 验证地址格式
 	if !addressRegex.MatchString(address) {
-		return c.Status(fiber.StatusBadRequest).JSON(api.Error{
-			Code:    "invalid_address",
-			Message: "Invalid Ethereum address format",
-		})
+		return apierror.Validation("address", "invalid_address", "Invalid Ethereum address format")
 	}
 
-	fmt.Printf("Fetching NFTs for address: %s\n", address)
+	// 解析链参数
+	chains, err := resolveChains(params.Chain)
+	if err != nil {
+		return apierror.Validation("chain", "invalid_chain", err.Error())
+	}
 
 	// 获取 pageToken 参数
 	pageToken := c.Query("pageToken", "")
@@ -101,40 +185,18 @@ func (s Server) GetApiUserAddressNfts(c *fiber.Ctx, address string, params api.G
 		includeMetadata = *params.IncludeMetadata
 	}
 
-	fmt.Printf("Include metadata: %v, PageToken: %s\n", includeMetadata, pageToken)
+	logger := logging.FromContext(c.UserContext())
+	logger.Debug("fetching NFTs", "address", address, "chains", chains, "include_metadata", includeMetadata, "page_token", pageToken)
 
-	nfts, nextPageToken, err := s.nftService.GetNFTs(address, includeMetadata, pageToken)
+	ctx, source := services.WithSource(c.UserContext())
+	nfts, nextPageToken, err := s.nftService.GetNFTs(ctx, address, chains, includeMetadata, pageToken)
+	setDataProvider(c, source)
 	if err != nil {
-		// 只在错误时打印请求信息
-		fmt.Println("=== Request Headers ===")
-		headers := make(map[string]string)
-		c.Request().Header.VisitAll(func(key, value []byte) {
-			fmt.Printf("%s: %s\n", string(key), string(value))
-			headers[string(key)] = string(value)
-		})
-		fmt.Println("=== End Request Headers ===")
-
-		fmt.Println("=== Full Request Info ===")
-		fmt.Printf("Method: %s\n", c.Method())
-		fmt.Printf("Path: %s\n", c.Path())
-		fmt.Printf("Query Parameters:\n")
-		c.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
-			fmt.Printf("  %s: %s\n", string(key), string(value))
-		})
-		fmt.Printf("Address Parameter: %s\n", address)
-		if params.IncludeMetadata != nil {
-			fmt.Printf("Include Metadata: %v\n", *params.IncludeMetadata)
-		}
-		fmt.Println("=== End Request Info ===")
-
-		fmt.Printf("Error fetching NFTs: %v\n", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(api.Error{
-			Code:    "internal_server_error",
-			Message: err.Error(),
-		})
+		logger.Error("failed to fetch NFTs", "address", address, "chains", chains, "error", err)
+		return apierror.From(err)
 	}
 
-	fmt.Printf("Found %d NFTs for address %s\n", len(nfts), address)
+	logger.Debug("fetched NFTs", "address", address, "count", len(nfts))
 
 	// 构建下一页的完整URL
 	var nextPageUrl string
@@ -147,6 +209,11 @@ func (s Server) GetApiUserAddressNfts(c *fiber.Ctx, address string, params api.G
 		if params.IncludeMetadata != nil {
 			nextPageUrl = fmt.Sprintf("%s&includeMetadata=%t", nextPageUrl, *params.IncludeMetadata)
 		}
+
+		// 如果有chain参数，也添加到URL中
+		if params.Chain != nil {
+			nextPageUrl = fmt.Sprintf("%s&chain=%s", nextPageUrl, strings.Join(chains, ","))
+		}
 	}
 
 	// 返回响应
@@ -159,12 +226,18 @@ func (s Server) GetApiUserAddressNfts(c *fiber.Ctx, address string, params api.G
 }
 
 func (s Server) GetApiUserAddressBalance(c *fiber.Ctx, address string, params api.GetApiUserAddressBalanceParams) error {
+	span := tracing.StartHandler(c, "Server.GetApiUserAddressBalance")
+	defer span.End()
+
 	// 验证地址格式
 	if !addressRegex.MatchString(address) {
-		return c.Status(fiber.StatusBadRequest).JSON(api.Error{
-			Code:    "invalid_address",
-			Message: "Invalid Ethereum address format",
-		})
+		return apierror.Validation("address", "invalid_address", "Invalid Ethereum address format")
+	}
+
+	// 解析链参数
+	chains, err := resolveChains(params.Chain)
+	if err != nil {
+		return apierror.Validation("chain", "invalid_chain", err.Error())
 	}
 
 	// 获取代币余额
@@ -172,15 +245,14 @@ func (s Server) GetApiUserAddressBalance(c *fiber.Ctx, address string, params ap
 
 	// 获取分页参数
 	pageToken := c.Query("pageToken", "")
-	pageSize := 10 // 默认每页10个
+	pageSize := s.defaultPageSize
 
 	// 调用服务获取代币信息
-	tokens, nextPageToken, err := s.ankrService.GetTokens(address, includeZeroBalance, pageToken, pageSize)
+	ctx, source := services.WithSource(c.UserContext())
+	tokens, nextPageToken, err := s.ankrService.GetTokens(ctx, address, chains, includeZeroBalance, pageToken, pageSize)
+	setDataProvider(c, source)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(api.Error{
-			Code:    "internal_server_error",
-			Message: err.Error(),
-		})
+		return apierror.From(err)
 	}
 
 	// 构建下一页的完整URL
@@ -194,6 +266,11 @@ func (s Server) GetApiUserAddressBalance(c *fiber.Ctx, address string, params ap
 		if includeZeroBalance {
 			nextPageUrl = fmt.Sprintf("%s&includeZeroBalance=true", nextPageUrl)
 		}
+
+		// 如果有chain参数，也添加到URL中
+		if params.Chain != nil {
+			nextPageUrl = fmt.Sprintf("%s&chain=%s", nextPageUrl, strings.Join(chains, ","))
+		}
 	}
 
 	// 返回响应
@@ -205,6 +282,193 @@ func (s Server) GetApiUserAddressBalance(c *fiber.Ctx, address string, params ap
 	})
 }
 
+func (s Server) GetApiUserAddressPortfolio(c *fiber.Ctx, address string, params api.GetApiUserAddressPortfolioParams) error {
+	span := tracing.StartHandler(c, "Server.GetApiUserAddressPortfolio")
+	defer span.End()
+
+	// 验证地址格式
+	if !addressRegex.MatchString(address) {
+		return apierror.Validation("address", "invalid_address", "Invalid Ethereum address format")
+	}
+
+	// 解析链参数
+	chains, err := resolveChains(params.Chain)
+	if err != nil {
+		return apierror.Validation("chain", "invalid_chain", err.Error())
+	}
+
+	// 返回的主要持仓数量，默认5个
+	top := 5
+	if params.Top != nil {
+		if *params.Top < 1 || *params.Top > 50 {
+			return apierror.Validation("top", "invalid_top", "top must be between 1 and 50")
+		}
+		top = *params.Top
+	}
+
+	// 汇总全部分页的资产
+	ctx, source := services.WithSource(c.UserContext())
+	portfolio, err := s.portfolioService.GetPortfolio(ctx, address, chains, top)
+	setDataProvider(c, source)
+	if err != nil {
+		return apierror.From(err)
+	}
+
+	// 返回响应
+	return c.JSON(portfolio)
+}
+
+func (s Server) GetApiUserAddressTransactions(c *fiber.Ctx, address string, params api.GetApiUserAddressTransactionsParams) error {
+	span := tracing.StartHandler(c, "Server.GetApiUserAddressTransactions")
+	defer span.End()
+
+	// 验证地址格式
+	if !addressRegex.MatchString(address) {
+		return apierror.Validation("address", "invalid_address", "Invalid Ethereum address format")
+	}
+
+	// 解析链参数
+	chains, err := resolveChains(params.Chain)
+	if err != nil {
+		return apierror.Validation("chain", "invalid_chain", err.Error())
+	}
+
+	// 获取分页参数
+	pageToken := c.Query("pageToken", "")
+	pageSize := s.defaultPageSize
+
+	// 调用服务获取交易记录
+	ctx, source := services.WithSource(c.UserContext())
+	transactions, nextPageToken, err := s.transactionService.GetTransactions(ctx, address, chains, pageToken, pageSize)
+	setDataProvider(c, source)
+	if err != nil {
+		return apierror.From(err)
+	}
+
+	// 构建下一页的完整URL
+	var nextPageUrl string
+	if nextPageToken != "" {
+		// 获取当前请求的基本URL
+		baseUrl := fmt.Sprintf("%s://%s%s", c.Protocol(), c.Hostname(), c.Path())
+		nextPageUrl = fmt.Sprintf("%s?pageToken=%s", baseUrl, nextPageToken)
+
+		// 如果有chain参数，也添加到URL中
+		if params.Chain != nil {
+			nextPageUrl = fmt.Sprintf("%s&chain=%s", nextPageUrl, strings.Join(chains, ","))
+		}
+	}
+
+	// 返回响应
+	return c.JSON(fiber.Map{
+		"address":       address,
+		"transactions":  transactions,
+		"nextPageToken": nextPageToken,
+		"nextPageUrl":   nextPageUrl,
+	})
+}
+
+func (s Server) GetApiUserAddressTransfers(c *fiber.Ctx, address string, params api.GetApiUserAddressTransfersParams) error {
+	span := tracing.StartHandler(c, "Server.GetApiUserAddressTransfers")
+	defer span.End()
+
+	// 验证地址格式
+	if !addressRegex.MatchString(address) {
+		return apierror.Validation("address", "invalid_address", "Invalid Ethereum address format")
+	}
+
+	// 解析链参数
+	chains, err := resolveChains(params.Chain)
+	if err != nil {
+		return apierror.Validation("chain", "invalid_chain", err.Error())
+	}
+
+	// 筛选条件
+	filter := services.TransferFilter{
+		Since: params.Since,
+		Until: params.Until,
+	}
+	if params.Contract != nil {
+		if !addressRegex.MatchString(*params.Contract) {
+			return apierror.Validation("contract", "invalid_contract", "Invalid contract address format")
+		}
+		filter.Contract = *params.Contract
+	}
+	if params.Direction != nil {
+		if *params.Direction != api.GetApiUserAddressTransfersParamsDirectionIn && *params.Direction != api.GetApiUserAddressTransfersParamsDirectionOut {
+			return apierror.Validation("direction", "invalid_direction", "direction must be in or out")
+		}
+		filter.Direction = api.TransactionDirection(*params.Direction)
+	}
+	if filter.Since != nil && filter.Until != nil && filter.Since.After(*filter.Until) {
+		return apierror.Validation("since", "invalid_time_range", "since must not be after until")
+	}
+
+	// 获取分页参数
+	pageToken := c.Query("pageToken", "")
+	pageSize := s.defaultPageSize
+
+	// 调用服务获取转账记录
+	ctx, source := services.WithSource(c.UserContext())
+	transfers, nextPageToken, err := s.transferService.GetTransfers(ctx, address, chains, filter, pageToken, pageSize)
+	setDataProvider(c, source)
+	if err != nil {
+		return apierror.From(err)
+	}
+
+	// 构建下一页的完整URL
+	var nextPageUrl string
+	if nextPageToken != "" {
+		// 获取当前请求的基本URL
+		baseUrl := fmt.Sprintf("%s://%s%s", c.Protocol(), c.Hostname(), c.Path())
+		nextPageUrl = fmt.Sprintf("%s?pageToken=%s", baseUrl, nextPageToken)
+
+		// 筛选条件也添加到URL中
+		if params.Contract != nil {
+			nextPageUrl = fmt.Sprintf("%s&contract=%s", nextPageUrl, *params.Contract)
+		}
+		if params.Direction != nil {
+			nextPageUrl = fmt.Sprintf("%s&direction=%s", nextPageUrl, *params.Direction)
+		}
+		if params.Since != nil {
+			nextPageUrl = fmt.Sprintf("%s&since=%s", nextPageUrl, url.QueryEscape(params.Since.Format(time.RFC3339)))
+		}
+		if params.Until != nil {
+			nextPageUrl = fmt.Sprintf("%s&until=%s", nextPageUrl, url.QueryEscape(params.Until.Format(time.RFC3339)))
+		}
+
+		// 如果有chain参数，也添加到URL中
+		if params.Chain != nil {
+			nextPageUrl = fmt.Sprintf("%s&chain=%s", nextPageUrl, strings.Join(chains, ","))
+		}
+	}
+
+	// 返回响应
+	return c.JSON(fiber.Map{
+		"address":       address,
+		"transfers":     transfers,
+		"nextPageToken": nextPageToken,
+		"nextPageUrl":   nextPageUrl,
+	})
+}
+
+// 辅助函数：解析 chain 查询参数，未指定时使用默认链
+func resolveChains(chain *string) ([]string, error) {
+	if chain == nil {
+		return services.ParseChains("")
+	}
+	return services.ParseChains(*chain)
+}
+
+// 辅助函数：在响应头中返回实际应答的数据提供方，以及应答缺少的数据
+func setDataProvider(c *fiber.Ctx, source *services.Source) {
+	if name := source.String(); name != "" {
+		c.Set("X-Data-Provider", name)
+	}
+	if partial := source.Partial(); partial != "" {
+		c.Set("X-Data-Partial", partial)
+	}
+}
+
 // 辅助函数：格式化代币余额
 func formatBalance(hexBalance string, decimals int) string {
 	// 移除 0x 前缀
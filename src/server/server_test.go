@@ -0,0 +1,492 @@
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/apierror"
+	"github.com/web3-smart-wallet/src/did"
+	"github.com/web3-smart-wallet/src/logging"
+	"github.com/web3-smart-wallet/src/services"
+	"github.com/web3-smart-wallet/src/services/fakes"
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+// 没有任何资产的地址
+const emptyWallet = "0x0000000000000000000000000000000000000001"
+
+// newTestApp 按 main.go 的方式组装服务，Ankr Advanced API 使用 ankrURL，原生币余额使用假 JSON-RPC 节点，
+// did:ethr 的注册表查询使用 ethrURL
+func newTestApp(t *testing.T, ankrURL string, ethrURL string) *fiber.App {
+	t.Helper()
+
+	// 不重试，上游失败的用例直接返回
+	services.ConfigureUpstreamClient(services.ClientOptions{Timeout: 5 * time.Second, MaxIdleConnsPerHost: 4}, nil)
+
+	rpc := fakes.NewRPCServer()
+	t.Cleanup(rpc.Close)
+	rpcEndpoints := make(map[string]string)
+	for _, chain := range services.SupportedChains() {
+		rpcEndpoints[chain.ID] = rpc.URL
+	}
+
+	// polygon 由 Moralis 提供，跨提供方的分页游标由路由编码
+	moralis := fakes.NewMoralisServer()
+	t.Cleanup(moralis.Close)
+	ankrService := services.NewAnkrService(ankrURL)
+	nftService := services.NewNFTService(ankrURL)
+	router, err := services.NewProviderRouter(
+		map[string]services.Provider{
+			"ankr":    services.NewAnkrProvider(ankrService, nftService),
+			"moralis": services.NewMoralisProvider(moralis.URL, fakes.APIKey),
+		},
+		[]string{"ankr"}, map[string][]string{"polygon": {"moralis"}}, services.FailoverOptions{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priceService := services.NewAnkrPriceService(ankrURL)
+	tokens := services.NewNativeTokenService(router, services.NewRPCBalanceService(rpcEndpoints, nil, ""), priceService, false)
+
+	resolver := did.NewMethodResolver()
+	resolver.Register("pkh", did.NewPKHResolver())
+	resolver.Register("ethr", did.NewEthrResolver(map[string]string{"mainnet": ethrURL}))
+	registry, err := did.NewFileRegistry(filepath.Join(t.TempDir(), "did_registry.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	linker := did.NewLinker(registry, resolver, did.NewChallengeStore(time.Minute, 100))
+
+	server := NewServer(
+		tokens,
+		router,
+		services.NewPortfolioService(tokens, priceService),
+		services.NewTransactionService(ankrURL),
+		services.NewTransferService(ankrURL, tokens),
+		resolver, registry, linker, 10,
+	)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+	app.Use(logging.Middleware(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	api.RegisterHandlers(app, server)
+	return app
+}
+
+// 返回固定状态码的上游
+func newStatusServer(t *testing.T, status int, headers map[string]string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, value := range headers {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"error":"upstream failure with internal details"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// 发送请求，返回状态码、响应头和解析后的响应体
+func do(t *testing.T, app *fiber.App, method string, path string, body interface{}) (int, http.Header, map[string]interface{}) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var decoded map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("%s %s: invalid response body: %v", method, path, err)
+	}
+	return resp.StatusCode, resp.Header, decoded
+}
+
+// 辅助函数：检查 api.Error 响应的状态码、code 和 details.reason，details 中必须带请求 ID
+func checkError(t *testing.T, status int, body map[string]interface{}, wantStatus int, wantCode string, wantReason string) {
+	t.Helper()
+	if status != wantStatus || body["code"] != wantCode {
+		t.Fatalf("status = %d, body = %v, want %d %s", status, body, wantStatus, wantCode)
+	}
+	if message, _ := body["message"].(string); message == "" {
+		t.Errorf("error message is empty: %v", body)
+	}
+	details, _ := body["details"].(map[string]interface{})
+	if details["requestId"] == nil || details["requestId"] == "" {
+		t.Errorf("details = %v, want a request ID", details)
+	}
+	if wantReason != "" && details["reason"] != wantReason {
+		t.Errorf("reason = %v, want %s", details["reason"], wantReason)
+	}
+}
+
+// 辅助函数：取出响应中列表字段各元素的 key 字段
+func field(body map[string]interface{}, list string, key string) []string {
+	items, _ := body[list].([]interface{})
+	values := make([]string, len(items))
+	for i, item := range items {
+		values[i], _ = item.(map[string]interface{})[key].(string)
+	}
+	return values
+}
+
+func TestDataRoutes(t *testing.T) {
+	ankr := fakes.NewAnkrServer()
+	defer ankr.Close()
+	app := newTestApp(t, ankr.URL, "")
+
+	user := "/api/user/" + fakes.Wallet
+
+	t.Run("balance pages", func(t *testing.T) {
+		status, header, body := do(t, app, "GET", user+"/balance?chain=base", nil)
+		if status != http.StatusOK || header.Get("X-Data-Provider") != "ankr" {
+			t.Fatalf("status = %d, provider = %q, body = %v", status, header.Get("X-Data-Provider"), body)
+		}
+		if got := strings.Join(field(body, "tokens", "symbol"), ","); got != "ETH,USDC" {
+			t.Errorf("first page = %s, want ETH,USDC", got)
+		}
+		next, _ := body["nextPageToken"].(string)
+		if next == "" || !strings.Contains(body["nextPageUrl"].(string), "chain=base") {
+			t.Fatalf("nextPageToken = %q, nextPageUrl = %v", next, body["nextPageUrl"])
+		}
+
+		status, _, body = do(t, app, "GET", user+"/balance?chain=base&pageToken="+next, nil)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, body = %v", status, body)
+		}
+		if got := strings.Join(field(body, "tokens", "symbol"), ","); got != "DEGEN" || body["nextPageToken"] != "" {
+			t.Errorf("second page = %s, next %v, want DEGEN and no next page", got, body["nextPageToken"])
+		}
+	})
+
+	t.Run("nfts", func(t *testing.T) {
+		status, _, body := do(t, app, "GET", user+"/nfts?chain=base", nil)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, body = %v", status, body)
+		}
+		if got := field(body, "nfts", "contractAddress"); len(got) != 1 || got[0] != "0x2867a6dfb2c15f789c3bf0b5547ac4117850a060" {
+			t.Errorf("nfts = %v", got)
+		}
+	})
+
+	t.Run("portfolio", func(t *testing.T) {
+		status, _, body := do(t, app, "GET", user+"/portfolio?chain=base&top=2", nil)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, body = %v", status, body)
+		}
+		if body["totalUsd"] != "1508.31" || body["tokenCount"] != float64(3) {
+			t.Errorf("totalUsd = %v, tokenCount = %v, want 1508.31, 3", body["totalUsd"], body["tokenCount"])
+		}
+		if got := strings.Join(field(body, "topHoldings", "symbol"), ","); got != "ETH,USDC" {
+			t.Errorf("top holdings = %s, want ETH,USDC", got)
+		}
+	})
+
+	t.Run("transactions", func(t *testing.T) {
+		status, _, body := do(t, app, "GET", user+"/transactions?chain=base", nil)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, body = %v", status, body)
+		}
+		if got := strings.Join(field(body, "transactions", "direction"), ","); got != "out,in" || body["nextPageToken"] == "" {
+			t.Errorf("directions = %s, next %v, want out,in and a next page", got, body["nextPageToken"])
+		}
+	})
+
+	t.Run("transfers", func(t *testing.T) {
+		status, _, body := do(t, app, "GET", user+"/transfers?chain=base&direction=in", nil)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, body = %v", status, body)
+		}
+		for _, direction := range field(body, "transfers", "direction") {
+			if direction != "in" {
+				t.Errorf("transfers = %v, want only incoming", body["transfers"])
+			}
+		}
+		if len(field(body, "transfers", "direction")) == 0 {
+			t.Errorf("transfers = %v, want incoming transfers", body["transfers"])
+		}
+	})
+
+	t.Run("empty wallet", func(t *testing.T) {
+		status, _, body := do(t, app, "GET", "/api/user/"+emptyWallet+"/balance?chain=base", nil)
+		if status != http.StatusOK || len(field(body, "tokens", "symbol")) != 0 {
+			t.Errorf("status = %d, body = %v, want no tokens", status, body)
+		}
+	})
+}
+
+func TestDataRouteErrors(t *testing.T) {
+	ankr := fakes.NewAnkrServer()
+	defer ankr.Close()
+	app := newTestApp(t, ankr.URL, "")
+
+	user := "/api/user/" + fakes.Wallet
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantCode   string
+		wantReason string
+	}{
+		{name: "invalid address", path: "/api/user/0x1234/balance", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_address"},
+		{name: "invalid address on nfts", path: "/api/user/not-an-address/nfts", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_address"},
+		{name: "unsupported chain", path: user + "/balance?chain=dogecoin", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_chain"},
+		{name: "invalid page token", path: user + "/balance?chain=base,polygon&pageToken=garbage", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_page_token"},
+		{name: "top out of range", path: user + "/portfolio?top=0", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_top"},
+		{name: "invalid transaction chain", path: user + "/transactions?chain=dogecoin", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_chain"},
+		{name: "invalid transfer direction", path: user + "/transfers?direction=sideways", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_direction"},
+		{name: "invalid transfer contract", path: user + "/transfers?contract=0x12", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_contract"},
+		{name: "inverted time range", path: user + "/transfers?since=2025-02-01T00:00:00Z&until=2025-01-01T00:00:00Z", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_time_range"},
+		{name: "malformed query parameter", path: user + "/portfolio?top=many", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_request"},
+		{name: "unknown route", path: "/api/unknown", wantStatus: 404, wantCode: apierror.CodeNotFound, wantReason: "route_not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, body := do(t, app, "GET", tt.path, nil)
+			checkError(t, status, body, tt.wantStatus, tt.wantCode, tt.wantReason)
+		})
+	}
+}
+
+func TestUpstreamErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		headers        map[string]string
+		wantStatus     int
+		wantCode       string
+		wantRetryAfter string
+	}{
+		{name: "upstream failure", status: http.StatusBadGateway, wantStatus: 502, wantCode: apierror.CodeUpstreamError},
+		{name: "upstream auth failure", status: http.StatusUnauthorized, wantStatus: 502, wantCode: apierror.CodeUpstreamError},
+		{name: "upstream rate limit", status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "7"}, wantStatus: 429, wantCode: apierror.CodeUpstreamRateLimited, wantRetryAfter: "7"},
+		{name: "upstream timeout", status: http.StatusGatewayTimeout, wantStatus: 504, wantCode: apierror.CodeTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, newStatusServer(t, tt.status, tt.headers), "")
+			for _, route := range []string{"balance", "nfts", "portfolio", "transactions", "transfers"} {
+				status, header, body := do(t, app, "GET", "/api/user/"+fakes.Wallet+"/"+route+"?chain=base", nil)
+				checkError(t, status, body, tt.wantStatus, tt.wantCode, "")
+				if header.Get("Retry-After") != tt.wantRetryAfter {
+					t.Errorf("%s: Retry-After = %q, want %q", route, header.Get("Retry-After"), tt.wantRetryAfter)
+				}
+				// 上游返回的原始内容不能出现在响应中
+				if message, _ := body["message"].(string); strings.Contains(message, "internal details") {
+					t.Errorf("%s: message leaks the upstream body: %s", route, message)
+				}
+			}
+		})
+	}
+
+	// 回放服务中没有录制的请求返回 404，按上游错误处理
+	replay, err := fakes.NewReplayServer(fakes.AnkrFixtures())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+	app := newTestApp(t, replay.URL, "")
+	status, _, body := do(t, app, "GET", "/api/user/"+emptyWallet+"/transactions?chain=base", nil)
+	checkError(t, status, body, 502, apierror.CodeUpstreamError, "upstream_error")
+}
+
+// 测试钱包：私钥和对应的地址
+type testWallet struct {
+	key     *secp256k1.PrivateKey
+	address string
+}
+
+func newTestWallet(seed byte) testWallet {
+	key := secp256k1.PrivKeyFromBytes(bytes.Repeat([]byte{seed}, 32))
+	hash := utils.Keccak256(key.PubKey().SerializeUncompressed()[1:])
+	return testWallet{key: key, address: "0x" + hex.EncodeToString(hash[12:])}
+}
+
+// personal_sign 格式的签名：r || s || v
+func (w testWallet) sign(message string) string {
+	compact := ecdsa.SignCompact(w.key, utils.HashPersonalMessage(message), false)
+	signature := make([]byte, 65)
+	copy(signature, compact[1:])
+	signature[64] = compact[0]
+	return "0x" + hex.EncodeToString(signature)
+}
+
+func TestDIDRoutes(t *testing.T) {
+	ankr := fakes.NewAnkrServer()
+	defer ankr.Close()
+	app := newTestApp(t, ankr.URL, newStatusServer(t, http.StatusBadGateway, nil))
+
+	owner := newTestWallet(1)
+	other := newTestWallet(2)
+	pkh := "did:pkh:eip155:1:" + owner.address
+
+	// 签发挑战并签名，返回关联或解除关联的请求体
+	signed := func(t *testing.T, action string, didValue string, signer testWallet) map[string]string {
+		t.Helper()
+		status, _, body := do(t, app, "POST", "/api/did/challenge", map[string]string{"action": action, "did": didValue, "address": owner.address})
+		if status != http.StatusOK {
+			t.Fatalf("challenge status = %d, body = %v", status, body)
+		}
+		return map[string]string{
+			"did":       didValue,
+			"address":   owner.address,
+			"nonce":     body["nonce"].(string),
+			"signature": signer.sign(body["message"].(string)),
+		}
+	}
+
+	t.Run("search did", func(t *testing.T) {
+		status, _, body := do(t, app, "GET", "/api/search/did/"+pkh, nil)
+		if status != http.StatusOK || !strings.EqualFold(body["address"].(string), owner.address) {
+			t.Errorf("status = %d, body = %v, want %s", status, body, owner.address)
+		}
+	})
+
+	t.Run("search did errors", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			did        string
+			wantStatus int
+			wantCode   string
+			wantReason string
+		}{
+			{name: "invalid did", did: "not-a-did", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_did"},
+			{name: "unsupported method", did: "did:key:z6Mk", wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_did"},
+			// did:ethr 注册表查询失败返回 502，不暴露上游细节
+			{name: "registry failure", did: "did:ethr:" + owner.address, wantStatus: 502, wantCode: apierror.CodeUpstreamError, wantReason: "upstream_error"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, _, body := do(t, app, "GET", "/api/search/did/"+tt.did, nil)
+				checkError(t, status, body, tt.wantStatus, tt.wantCode, tt.wantReason)
+			})
+		}
+	})
+
+	t.Run("search unlinked address", func(t *testing.T) {
+		status, _, body := do(t, app, "GET", "/api/search/address/"+owner.address, nil)
+		checkError(t, status, body, 404, apierror.CodeNotFound, "address_not_found")
+
+		status, _, body = do(t, app, "GET", "/api/search/address/0x12", nil)
+		checkError(t, status, body, 400, apierror.CodeValidation, "invalid_address")
+	})
+
+	t.Run("link errors", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			path       string
+			body       func(t *testing.T) interface{}
+			wantStatus int
+			wantCode   string
+			wantReason string
+		}{
+			{
+				name: "invalid action",
+				path: "/api/did/challenge",
+				body: func(t *testing.T) interface{} {
+					return map[string]string{"action": "transfer", "did": pkh, "address": owner.address}
+				},
+				wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_request",
+			},
+			{
+				name: "invalid address",
+				path: "/api/did/challenge",
+				body: func(t *testing.T) interface{} {
+					return map[string]string{"action": "link", "did": pkh, "address": "0x12"}
+				},
+				wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_request",
+			},
+			{
+				name: "unknown nonce",
+				path: "/api/did/link",
+				body: func(t *testing.T) interface{} {
+					return map[string]string{"did": pkh, "address": owner.address, "nonce": "unknown", "signature": "0x00"}
+				},
+				wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_challenge",
+			},
+			{
+				name:       "signature from another wallet",
+				path:       "/api/did/link",
+				body:       func(t *testing.T) interface{} { return signed(t, "link", pkh, other) },
+				wantStatus: 401, wantCode: apierror.CodeUnauthorized, wantReason: "invalid_signature",
+			},
+			{
+				// 解析失败无法确认 DID 归属，按上游错误返回
+				name:       "unresolvable did",
+				path:       "/api/did/link",
+				body:       func(t *testing.T) interface{} { return signed(t, "link", "did:ethr:"+owner.address, owner) },
+				wantStatus: 502, wantCode: apierror.CodeUpstreamError, wantReason: "did_resolution_failed",
+			},
+			{
+				name:       "did of another address",
+				path:       "/api/did/link",
+				body:       func(t *testing.T) interface{} { return signed(t, "link", "did:pkh:eip155:1:"+other.address, owner) },
+				wantStatus: 409, wantCode: apierror.CodeConflict, wantReason: "did_conflict",
+			},
+			{
+				name:       "unlink without link",
+				path:       "/api/did/unlink",
+				body:       func(t *testing.T) interface{} { return signed(t, "unlink", pkh, owner) },
+				wantStatus: 404, wantCode: apierror.CodeNotFound, wantReason: "link_not_found",
+			},
+			{
+				name:       "malformed body",
+				path:       "/api/did/link",
+				body:       func(t *testing.T) interface{} { return "not an object" },
+				wantStatus: 400, wantCode: apierror.CodeValidation, wantReason: "invalid_request",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, _, body := do(t, app, "POST", tt.path, tt.body(t))
+				checkError(t, status, body, tt.wantStatus, tt.wantCode, tt.wantReason)
+			})
+		}
+	})
+
+	t.Run("link and unlink", func(t *testing.T) {
+		request := signed(t, "link", pkh, owner)
+		status, _, body := do(t, app, "POST", "/api/did/link", request)
+		if status != http.StatusOK || body["linked"] != true {
+			t.Fatalf("link status = %d, body = %v", status, body)
+		}
+		// 挑战只能使用一次
+		status, _, body = do(t, app, "POST", "/api/did/link", request)
+		checkError(t, status, body, 400, apierror.CodeValidation, "invalid_challenge")
+
+		status, _, body = do(t, app, "GET", "/api/search/address/"+owner.address, nil)
+		if status != http.StatusOK || body["did"] != pkh {
+			t.Fatalf("search address status = %d, body = %v, want %s", status, body, pkh)
+		}
+
+		status, _, body = do(t, app, "POST", "/api/did/unlink", signed(t, "unlink", pkh, owner))
+		if status != http.StatusOK || body["linked"] != false {
+			t.Fatalf("unlink status = %d, body = %v", status, body)
+		}
+		status, _, body = do(t, app, "GET", "/api/search/address/"+owner.address, nil)
+		checkError(t, status, body, 404, apierror.CodeNotFound, "address_not_found")
+	})
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/logging"
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+// AlchemyDefaultBaseURL Alchemy 接口地址模板，{network} 会被替换为网络名
+const AlchemyDefaultBaseURL = "https://{network}.g.alchemy.com"
+
+// 链标识到 Alchemy 网络名的映射
+var alchemyNetworks = map[string]string{
+	"eth":      "eth-mainnet",
+	"base":     "base-mainnet",
+	"arbitrum": "arb-mainnet",
+	"optimism": "opt-mainnet",
+	"polygon":  "polygon-mainnet",
+}
+
+// AlchemyProvider 通过 Alchemy Token API、Prices API 和 NFT API 查询资产
+type AlchemyProvider struct {
+	baseURL string
+	apiKey  string
+}
+
+// NewAlchemyProvider 创建 Alchemy 提供方，baseURL 为空时使用 AlchemyDefaultBaseURL
+func NewAlchemyProvider(baseURL string, apiKey string) Provider {
+	if baseURL == "" {
+		baseURL = AlchemyDefaultBaseURL
+	}
+	return &AlchemyProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+	}
+}
+
+func (p *AlchemyProvider) Name() string {
+	return "alchemy"
+}
+
+func (p *AlchemyProvider) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.Token, string, error) {
+		return p.getTokens(ctx, address, chain, includeZeroBalance, token, pageSize, true)
+	})
+}
+
+func (p *AlchemyProvider) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.Token, string, error) {
+		return p.getTokens(ctx, address, chain, true, token, pageSize, false)
+	})
+}
+
+func (p *AlchemyProvider) GetNFTs(ctx context.Context, address string, chains []string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.NFT, string, error) {
+		return p.getNFTs(ctx, address, chain, includeMetadata, token)
+	})
+}
+
+// 查询单条链上的 ERC20 余额，并批量补充代币元数据
+func (p *AlchemyProvider) getTokens(ctx context.Context, address string, chain string, includeZeroBalance bool, pageToken string, pageSize int, withBalance bool) ([]api.Token, string, error) {
+	network, ok := alchemyNetworks[chain]
+	if !ok {
+		return nil, "", fmt.Errorf("alchemy does not support chain %s", chain)
+	}
+	rpcURL := p.networkURL(network) + "/v2/" + p.apiKey
+
+	if pageSize <= 0 {
+		pageSize = 10 // 默认每页10个
+	}
+	options := map[string]interface{}{
+		"maxCount": pageSize,
+	}
+	if pageToken != "" {
+		options["pageKey"] = pageToken
+	}
+
+	payload := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "alchemy_getTokenBalances",
+		"params":  []interface{}{address, "erc20", options},
+		"id":      1,
+	}
+
+	var response struct {
+		Result struct {
+			TokenBalances []struct {
+				ContractAddress string `json:"contractAddress"`
+				TokenBalance    string `json:"tokenBalance"`
+			} `json:"tokenBalances"`
+			PageKey string `json:"pageKey"`
+		} `json:"result"`
+	}
+	if err := postJSON(ctx, rpcURL, nil, payload, &response); err != nil {
+		return nil, "", fmt.Errorf("alchemy api error: %w", err)
+	}
+
+	// 过滤零余额
+	balances := response.Result.TokenBalances[:0]
+	for _, balance := range response.Result.TokenBalances {
+		if !includeZeroBalance && isZeroHex(balance.TokenBalance) {
+			continue
+		}
+		balances = append(balances, balance)
+	}
+	if len(balances) == 0 {
+		return []api.Token{}, response.Result.PageKey, nil
+	}
+
+	// 使用 JSON-RPC 批量请求获取元数据
+	batch := make([]interface{}, len(balances))
+	for i, balance := range balances {
+		batch[i] = map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "alchemy_getTokenMetadata",
+			"params":  []interface{}{balance.ContractAddress},
+			"id":      i,
+		}
+	}
+	var metadata []struct {
+		ID     int `json:"id"`
+		Result struct {
+			Name     string `json:"name"`
+			Symbol   string `json:"symbol"`
+			Decimals *int   `json:"decimals"`
+		} `json:"result"`
+		Error *rpcError `json:"error"`
+	}
+	if err := postJSON(ctx, rpcURL, nil, batch, &metadata); err != nil {
+		return nil, "", fmt.Errorf("alchemy api error: %w", err)
+	}
+
+	tokens := make([]api.Token, len(balances))
+	for i, balance := range balances {
+		tokenType := api.ERC20
+		token := api.Token{
+			Address: balance.ContractAddress,
+			Type:    &tokenType,
+			Chain:   strPtr(chain),
+		}
+
+		decimals := 18
+		for _, m := range metadata {
+			if m.ID != i || m.Error != nil {
+				continue
+			}
+			token.Name = m.Result.Name
+			token.Symbol = m.Result.Symbol
+			if m.Result.Decimals != nil {
+				decimals = *m.Result.Decimals
+			}
+		}
+
+		if withBalance {
+			formatted := utils.FormatTokenBalance(balance.TokenBalance, decimals)
+			token.Balance = &formatted
+			token.Decimals = &decimals
+		}
+		tokens[i] = token
+	}
+
+	if withBalance {
+		if err := p.withPrices(ctx, network, tokens); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return tokens, response.Result.PageKey, nil
+}
+
+// Prices API 单次请求最多查询的合约数
+const alchemyPricesBatchSize = 25
+
+// 通过 Alchemy Prices API 补充代币价格和美元余额
+// 价格查询失败时仍然返回余额，并标记结果缺少价格；调用方已取消时返回错误
+func (p *AlchemyProvider) withPrices(ctx context.Context, network string, tokens []api.Token) error {
+	prices, err := p.getPrices(ctx, network, tokens)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		logging.FromContext(ctx).Warn("alchemy price lookup failed, returning balances without prices", "network", network, "error", err)
+		recordPartial(ctx, PartialPrices)
+		return nil
+	}
+
+	for i, token := range tokens {
+		price, ok := prices[strings.ToLower(token.Address)]
+		if !ok {
+			continue
+		}
+		tokens[i].TokenPrice = strPtr(price)
+		if token.Balance != nil {
+			tokens[i].BalanceUsd = strPtr(multiplyDecimal(*token.Balance, price))
+		}
+	}
+	return nil
+}
+
+// 查询合约的美元价格，返回小写合约地址到价格的映射，没有报价的合约不在结果中
+func (p *AlchemyProvider) getPrices(ctx context.Context, network string, tokens []api.Token) (map[string]string, error) {
+	// Prices API 不区分网络子域名，统一使用 api 子域名
+	requestURL := p.networkURL("api") + "/prices/v1/" + p.apiKey + "/tokens/by-address"
+
+	prices := make(map[string]string, len(tokens))
+	for start := 0; start < len(tokens); start += alchemyPricesBatchSize {
+		batch := tokens[start:min(start+alchemyPricesBatchSize, len(tokens))]
+		addresses := make([]map[string]string, len(batch))
+		for i, token := range batch {
+			addresses[i] = map[string]string{"network": network, "address": token.Address}
+		}
+
+		var response struct {
+			Data []struct {
+				Address string `json:"address"`
+				Prices  []struct {
+					Currency string `json:"currency"`
+					Value    string `json:"value"`
+				} `json:"prices"`
+			} `json:"data"`
+		}
+		if err := postJSON(ctx, requestURL, nil, map[string]interface{}{"addresses": addresses}, &response); err != nil {
+			return nil, fmt.Errorf("alchemy prices api error: %w", err)
+		}
+
+		for _, item := range response.Data {
+			for _, price := range item.Prices {
+				if strings.EqualFold(price.Currency, "usd") && price.Value != "" {
+					prices[strings.ToLower(item.Address)] = price.Value
+					break
+				}
+			}
+		}
+	}
+	return prices, nil
+}
+
+// 查询单条链上的 NFT
+func (p *AlchemyProvider) getNFTs(ctx context.Context, address string, chain string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+	network, ok := alchemyNetworks[chain]
+	if !ok {
+		return nil, "", fmt.Errorf("alchemy does not support chain %s", chain)
+	}
+
+	query := url.Values{}
+	query.Set("owner", address)
+	query.Set("withMetadata", strconv.FormatBool(includeMetadata))
+	if pageToken != "" {
+		query.Set("pageKey", pageToken)
+	}
+	requestURL := fmt.Sprintf("%s/nft/v3/%s/getNFTsForOwner?%s", p.networkURL(network), p.apiKey, query.Encode())
+
+	var response struct {
+		OwnedNfts []struct {
+			Contract struct {
+				Address         string `json:"address"`
+				Name            string `json:"name"`
+				TokenType       string `json:"tokenType"`
+				OpenSeaMetadata struct {
+					CollectionName string `json:"collectionName"`
+				} `json:"openSeaMetadata"`
+			} `json:"contract"`
+			TokenID     string `json:"tokenId"`
+			TokenType   string `json:"tokenType"`
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			TokenURI    string `json:"tokenUri"`
+			Image       struct {
+				CachedURL   string `json:"cachedUrl"`
+				OriginalURL string `json:"originalUrl"`
+			} `json:"image"`
+			Raw struct {
+				Metadata struct {
+					Attributes []nftAttribute `json:"attributes"`
+				} `json:"metadata"`
+			} `json:"raw"`
+		} `json:"ownedNfts"`
+		PageKey string `json:"pageKey"`
+	}
+	if err := getJSON(ctx, requestURL, nil, &response); err != nil {
+		return nil, "", fmt.Errorf("alchemy api error: %w", err)
+	}
+
+	nfts := make([]api.NFT, 0)
+	for _, owned := range response.OwnedNfts {
+		tokenType := owned.TokenType
+		if tokenType == "" {
+			tokenType = owned.Contract.TokenType
+		}
+		// 只处理ERC721和ERC1155类型的token
+		if tokenType != "ERC721" && tokenType != "ERC1155" {
+			continue
+		}
+
+		image := owned.Image.CachedURL
+		if image == "" {
+			image = owned.Image.OriginalURL
+		}
+		collection := owned.Contract.OpenSeaMetadata.CollectionName
+		if collection == "" {
+			collection = owned.Contract.Name
+		}
+
+		nftType := api.NFTType(tokenType)
+		traits := toNFTTraits(owned.Raw.Metadata.Attributes)
+		nfts = append(nfts, api.NFT{
+			ContractAddress: strPtr(owned.Contract.Address),
+			TokenId:         strPtr(owned.TokenID),
+			Type:            &nftType,
+			Name:            strPtr(owned.Name),
+			Description:     strPtr(owned.Description),
+			Image:           strPtr(image),
+			Attributes:      &traits,
+			Collection:      strPtr(collection),
+			TokenUri:        strPtr(owned.TokenURI),
+			Chain:           strPtr(chain),
+		})
+	}
+
+	return nfts, response.PageKey, nil
+}
+
+func (p *AlchemyProvider) networkURL(network string) string {
+	return strings.ReplaceAll(p.baseURL, "{network}", network)
+}
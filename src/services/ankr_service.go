@@ -1,10 +1,8 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 
 	"github.com/web3-smart-wallet/src/api"
 )
@@ -14,8 +12,8 @@ type AnkrService struct {
 }
 
 type AnkrServiceInterface interface {
-	GetTokens(address string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error)
-	GetTokenList(address string, pageToken string, pageSize int) ([]api.Token, string, error)
+	GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error)
+	GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error)
 }
 
 func NewAnkrService(apiURL string) AnkrServiceInterface {
@@ -24,10 +22,10 @@ func NewAnkrService(apiURL string) AnkrServiceInterface {
 	}
 }
 
-func (s *AnkrService) GetTokens(address string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+func (s *AnkrService) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
 	// 构建请求体
 	params := map[string]interface{}{
-		"blockchain":      "base",
+		"blockchain":      chains,
 		"walletAddress":   address,
 		"onlyWhitelisted": false,
 	}
@@ -50,29 +48,11 @@ func (s *AnkrService) GetTokens(address string, includeZeroBalance bool, pageTok
 		"id":      1,
 	}
 
-	// 发送请求
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to marshal request: %v", err)
-	}
-
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", s.apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch tokens: %v", err)
-	}
-	defer resp.Body.Close()
-
 	// 解析响应
 	var response struct {
 		Result struct {
 			Assets []struct {
+				Blockchain  string `json:"blockchain"`
 				TokenName   string `json:"tokenName"`
 				TokenSymbol string `json:"tokenSymbol"`
 				Address     string `json:"contractAddress"`
@@ -84,18 +64,10 @@ func (s *AnkrService) GetTokens(address string, includeZeroBalance bool, pageTok
 			} `json:"assets"`
 			NextPageToken string `json:"nextPageToken"`
 		} `json:"result"`
-		Error *struct {
-			Code    int    `json:"code"`
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, "", fmt.Errorf("failed to decode response: %v", err)
 	}
 
-	if response.Error != nil && response.Error.Message != "" {
-		return nil, "", fmt.Errorf("ankr api error: %s", response.Error.Message)
+	if err := postJSON(ctx, s.apiURL, nil, payload, &response); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch tokens: %w", err)
 	}
 
 	// 转换为 API 响应格式
@@ -117,6 +89,7 @@ func (s *AnkrService) GetTokens(address string, includeZeroBalance bool, pageTok
 			Name:       asset.TokenName,
 			Symbol:     asset.TokenSymbol,
 			Type:       &tokenType,
+			Chain:      &asset.Blockchain,
 			Balance:    &asset.Balance,
 			Decimals:   &asset.Decimals,
 			TokenPrice: &asset.TokenPrice,
@@ -129,10 +102,10 @@ func (s *AnkrService) GetTokens(address string, includeZeroBalance bool, pageTok
 	return tokens, response.Result.NextPageToken, nil
 }
 
-func (s *AnkrService) GetTokenList(address string, pageToken string, pageSize int) ([]api.Token, string, error) {
+func (s *AnkrService) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
 	// 构建请求体
 	params := map[string]interface{}{
-		"blockchain":      "base",
+		"blockchain":      chains,
 		"walletAddress":   address,
 		"onlyWhitelisted": false,
 	}
@@ -155,29 +128,11 @@ func (s *AnkrService) GetTokenList(address string, pageToken string, pageSize in
 		"id":      1,
 	}
 
-	// 发送请求
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to marshal request: %v", err)
-	}
-
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", s.apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch tokens: %v", err)
-	}
-	defer resp.Body.Close()
-
 	// 解析响应 - 更新结构体以包含更多字段
 	var response struct {
 		Result struct {
 			Assets []struct {
+				Blockchain  string `json:"blockchain"`
 				TokenName   string `json:"tokenName"`
 				TokenSymbol string `json:"tokenSymbol"`
 				Address     string `json:"contractAddress"`
@@ -191,18 +146,10 @@ func (s *AnkrService) GetTokenList(address string, pageToken string, pageSize in
 			} `json:"assets"`
 			NextPageToken string `json:"nextPageToken"`
 		} `json:"result"`
-		Error *struct {
-			Code    int    `json:"code"`
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, "", fmt.Errorf("failed to decode response: %v", err)
 	}
 
-	if response.Error != nil && response.Error.Message != "" {
-		return nil, "", fmt.Errorf("ankr api error: %s", response.Error.Message)
+	if err := postJSON(ctx, s.apiURL, nil, payload, &response); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch tokens: %w", err)
 	}
 
 	// 转换为 API 响应格式
@@ -218,6 +165,7 @@ func (s *AnkrService) GetTokenList(address string, pageToken string, pageSize in
 			Name:    asset.TokenName,
 			Symbol:  asset.TokenSymbol,
 			Type:    &tokenType,
+			Chain:   &asset.Blockchain,
 		}
 
 		tokens[i] = token
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/breaker"
+	"github.com/web3-smart-wallet/src/cache"
+	"github.com/web3-smart-wallet/src/logging"
+)
+
+// 缓存统计中使用的路由名称
+const (
+	CacheRouteTokens  = "tokens"
+	CacheRouteBalance = "balance"
+	CacheRouteNFTs    = "nfts"
+	// CacheRoutePriceHistory 历史价格（资产汇总的 24 小时变化）
+	CacheRoutePriceHistory = "price_history"
+)
+
+// 历史价格按小时分桶缓存：同一小时内的查询共用一次上游结果，桶内价格不会再变化，
+// 目标时间每小时进入新的桶，保留两小时即可
+const priceHistoryTTL = 2 * time.Hour
+
+// CacheTTLs 各路由的缓存有效期，为 0 表示该路由不缓存
+type CacheTTLs struct {
+	Tokens  time.Duration // /api/user/{address}
+	Balance time.Duration // /api/user/{address}/balance
+	NFTs    time.Duration // /api/user/{address}/nfts
+	// Stale 过期结果的额外保留时间，上游熔断时用于降级返回，为 0 表示不保留
+	Stale time.Duration
+}
+
+// CachedAnkrService 为 AnkrServiceInterface 增加结果缓存
+type CachedAnkrService struct {
+	next  AnkrServiceInterface
+	store cache.Store
+	ttls  CacheTTLs
+	stats *cache.Stats
+}
+
+// CachedNFTService 为 NFTServiceInterface 增加结果缓存
+type CachedNFTService struct {
+	next  NFTServiceInterface
+	store cache.Store
+	ttls  CacheTTLs
+	stats *cache.Stats
+}
+
+// CachedPriceService 为 PriceServiceInterface 的历史价格增加按（链、合约、小时）缓存，当前价格不缓存
+type CachedPriceService struct {
+	next  PriceServiceInterface
+	store cache.Store
+	stats *cache.Stats
+}
+
+// 缓存中保存的一页代币结果
+type cachedTokenPage struct {
+	Tokens        []api.Token `json:"tokens"`
+	NextPageToken string      `json:"nextPageToken"`
+}
+
+// 缓存中保存的一页 NFT 结果
+type cachedNFTPage struct {
+	NFTs          []api.NFT `json:"nfts"`
+	NextPageToken string    `json:"nextPageToken"`
+}
+
+func NewCachedAnkrService(next AnkrServiceInterface, store cache.Store, ttls CacheTTLs, stats *cache.Stats) AnkrServiceInterface {
+	return &CachedAnkrService{
+		next:  next,
+		store: store,
+		ttls:  ttls,
+		stats: stats,
+	}
+}
+
+func NewCachedNFTService(next NFTServiceInterface, store cache.Store, ttls CacheTTLs, stats *cache.Stats) NFTServiceInterface {
+	return &CachedNFTService{
+		next:  next,
+		store: store,
+		ttls:  ttls,
+		stats: stats,
+	}
+}
+
+func NewCachedPriceService(next PriceServiceInterface, store cache.Store, stats *cache.Stats) PriceServiceInterface {
+	return &CachedPriceService{
+		next:  next,
+		store: store,
+		stats: stats,
+	}
+}
+
+func (s *CachedAnkrService) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	key := cacheKey(CacheRouteBalance, address, chains, pageToken, fmt.Sprintf("size=%d", pageSize), fmt.Sprintf("zero=%t", includeZeroBalance))
+
+	var page cachedTokenPage
+	if s.ttls.Balance > 0 && getCached(ctx, s.store, s.stats, CacheRouteBalance, key, &page) {
+		return page.Tokens, page.NextPageToken, nil
+	}
+
+	tokens, nextPageToken, err := s.next.GetTokens(ctx, address, chains, includeZeroBalance, pageToken, pageSize)
+	if err != nil {
+		if s.ttls.Balance > 0 && getStale(ctx, s.store, err, CacheRouteBalance, key, &page) {
+			return page.Tokens, page.NextPageToken, nil
+		}
+		return nil, "", err
+	}
+
+	// 缺少价格的结果不缓存，下次请求重新查询上游
+	if s.ttls.Balance > 0 && !isPartial(ctx) {
+		setCached(ctx, s.store, key, cachedTokenPage{Tokens: tokens, NextPageToken: nextPageToken}, s.ttls.Balance, s.ttls.Stale)
+	}
+	return tokens, nextPageToken, nil
+}
+
+func (s *CachedAnkrService) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	key := cacheKey(CacheRouteTokens, address, chains, pageToken, fmt.Sprintf("size=%d", pageSize))
+
+	var page cachedTokenPage
+	if s.ttls.Tokens > 0 && getCached(ctx, s.store, s.stats, CacheRouteTokens, key, &page) {
+		return page.Tokens, page.NextPageToken, nil
+	}
+
+	tokens, nextPageToken, err := s.next.GetTokenList(ctx, address, chains, pageToken, pageSize)
+	if err != nil {
+		if s.ttls.Tokens > 0 && getStale(ctx, s.store, err, CacheRouteTokens, key, &page) {
+			return page.Tokens, page.NextPageToken, nil
+		}
+		return nil, "", err
+	}
+
+	if s.ttls.Tokens > 0 {
+		setCached(ctx, s.store, key, cachedTokenPage{Tokens: tokens, NextPageToken: nextPageToken}, s.ttls.Tokens, s.ttls.Stale)
+	}
+	return tokens, nextPageToken, nil
+}
+
+func (s *CachedNFTService) GetNFTs(ctx context.Context, address string, chains []string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+	key := cacheKey(CacheRouteNFTs, address, chains, pageToken, fmt.Sprintf("metadata=%t", includeMetadata))
+
+	var page cachedNFTPage
+	if s.ttls.NFTs > 0 && getCached(ctx, s.store, s.stats, CacheRouteNFTs, key, &page) {
+		return page.NFTs, page.NextPageToken, nil
+	}
+
+	nfts, nextPageToken, err := s.next.GetNFTs(ctx, address, chains, includeMetadata, pageToken)
+	if err != nil {
+		if s.ttls.NFTs > 0 && getStale(ctx, s.store, err, CacheRouteNFTs, key, &page) {
+			return page.NFTs, page.NextPageToken, nil
+		}
+		return nil, "", err
+	}
+
+	if s.ttls.NFTs > 0 {
+		setCached(ctx, s.store, key, cachedNFTPage{NFTs: nfts, NextPageToken: nextPageToken}, s.ttls.NFTs, s.ttls.Stale)
+	}
+	return nfts, nextPageToken, nil
+}
+
+func (s *CachedPriceService) GetTokenPrice(ctx context.Context, chain string, contractAddress string) (string, error) {
+	return s.next.GetTokenPrice(ctx, chain, contractAddress)
+}
+
+// GetTokenPriceAt 把 at 向下取整到小时后查询，同一小时内的查询结果相同
+func (s *CachedPriceService) GetTokenPriceAt(ctx context.Context, chain string, contractAddress string, at time.Time) (string, error) {
+	hour := at.Truncate(time.Hour)
+	key := strings.Join([]string{CacheRoutePriceHistory, chain, strings.ToLower(contractAddress), fmt.Sprint(hour.Unix())}, "|")
+
+	var price string
+	if getCached(ctx, s.store, s.stats, CacheRoutePriceHistory, key, &price) {
+		return price, nil
+	}
+
+	price, err := s.next.GetTokenPriceAt(ctx, chain, contractAddress, hour)
+	if err != nil {
+		return "", err
+	}
+	setCached(ctx, s.store, key, price, priceHistoryTTL, 0)
+	return price, nil
+}
+
+// 辅助函数：生成缓存键，地址不区分大小写
+func cacheKey(route string, address string, chains []string, pageToken string, flags ...string) string {
+	parts := []string{route, strings.ToLower(address), strings.Join(chains, ","), pageToken}
+	parts = append(parts, flags...)
+	return strings.Join(parts, "|")
+}
+
+// 辅助函数：读取缓存并记录命中情况，缓存后端出错时按未命中处理
+func getCached(ctx context.Context, store cache.Store, stats *cache.Stats, route string, key string, out interface{}) bool {
+	data, ok, err := store.Get(ctx, key)
+	if err != nil {
+		logging.FromContext(ctx).Warn("cache get failed", "route", route, "error", err)
+	}
+	if err != nil || !ok || json.Unmarshal(data, out) != nil {
+		stats.Miss(route)
+		return false
+	}
+
+	stats.Hit(route)
+	recordSource(ctx, "cache")
+	return true
+}
+
+// 辅助函数：上游熔断时读取过期的缓存结果，应答来源记为 "stale-cache"
+func getStale(ctx context.Context, store cache.Store, err error, route string, key string, out interface{}) bool {
+	if !errors.Is(err, breaker.ErrOpen) {
+		return false
+	}
+	data, ok, getErr := store.Get(ctx, staleKey(key))
+	if getErr != nil || !ok || json.Unmarshal(data, out) != nil {
+		return false
+	}
+
+	logging.FromContext(ctx).Warn("serving stale cache, upstream circuit is open", "route", route, "error", err)
+	recordSource(ctx, "stale-cache")
+	return true
+}
+
+// 辅助函数：写入缓存，失败只记录日志不影响请求；stale 大于 0 时额外保存一份降级用的副本
+func setCached(ctx context.Context, store cache.Store, key string, value interface{}, ttl time.Duration, stale time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		logging.FromContext(ctx).Warn("cache encode failed", "error", err)
+		return
+	}
+	if err := store.Set(ctx, key, data, ttl); err != nil {
+		logging.FromContext(ctx).Warn("cache set failed", "error", err)
+	}
+	if stale > 0 {
+		if err := store.Set(ctx, staleKey(key), data, ttl+stale); err != nil {
+			logging.FromContext(ctx).Warn("cache set failed", "error", err)
+		}
+	}
+}
+
+// 辅助函数：降级副本的缓存键
+func staleKey(key string) string {
+	return "stale|" + key
+}
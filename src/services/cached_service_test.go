@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/breaker"
+	"github.com/web3-smart-wallet/src/cache"
+)
+
+// 记录调用次数的代币服务，partial 不为空时把结果标记为缺少数据
+type countingTokens struct {
+	calls   int
+	err     error
+	partial string
+}
+
+func (s *countingTokens) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, "", s.err
+	}
+	if s.partial != "" {
+		recordPartial(ctx, s.partial)
+	}
+	return []api.Token{{Symbol: fmt.Sprint("call", s.calls)}}, "next", nil
+}
+
+func (s *countingTokens) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return s.GetTokens(ctx, address, chains, true, pageToken, pageSize)
+}
+
+// 记录写入的有效期，并检查读写使用的是请求的 context
+type recordingStore struct {
+	*cache.MemoryStore
+	t    *testing.T
+	ttls map[string]time.Duration
+}
+
+type requestKey struct{}
+
+func newRecordingStore(t *testing.T) *recordingStore {
+	return &recordingStore{MemoryStore: cache.NewMemoryStore(0), t: t, ttls: make(map[string]time.Duration)}
+}
+
+func (s *recordingStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if ctx.Value(requestKey{}) == nil {
+		s.t.Errorf("Get(%s) without the request context", key)
+	}
+	return s.MemoryStore.Get(ctx, key)
+}
+
+func (s *recordingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ctx.Value(requestKey{}) == nil {
+		s.t.Errorf("Set(%s) without the request context", key)
+	}
+	s.ttls[key] = ttl
+	return s.MemoryStore.Set(ctx, key, value, ttl)
+}
+
+func requestContext() (context.Context, *Source) {
+	return WithSource(context.WithValue(context.Background(), requestKey{}, true))
+}
+
+func TestCacheKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{
+			name: "address ignores case",
+			a:    cacheKey(CacheRouteBalance, "0xABC", []string{"eth"}, "", "size=10"),
+			b:    cacheKey(CacheRouteBalance, "0xabc", []string{"eth"}, "", "size=10"),
+			same: true,
+		},
+		{
+			name: "route",
+			a:    cacheKey(CacheRouteBalance, "0xabc", []string{"eth"}, ""),
+			b:    cacheKey(CacheRouteTokens, "0xabc", []string{"eth"}, ""),
+		},
+		{
+			name: "chains",
+			a:    cacheKey(CacheRouteBalance, "0xabc", []string{"eth"}, ""),
+			b:    cacheKey(CacheRouteBalance, "0xabc", []string{"eth", "base"}, ""),
+		},
+		{
+			name: "page token",
+			a:    cacheKey(CacheRouteBalance, "0xabc", []string{"eth"}, ""),
+			b:    cacheKey(CacheRouteBalance, "0xabc", []string{"eth"}, "page2"),
+		},
+		{
+			name: "flags",
+			a:    cacheKey(CacheRouteBalance, "0xabc", []string{"eth"}, "", "zero=true"),
+			b:    cacheKey(CacheRouteBalance, "0xabc", []string{"eth"}, "", "zero=false"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.a == tt.b) != tt.same {
+				t.Fatalf("%q and %q: same %t, want %t", tt.a, tt.b, tt.a == tt.b, tt.same)
+			}
+		})
+	}
+}
+
+func TestCachedAnkrService(t *testing.T) {
+	ttls := CacheTTLs{Tokens: time.Minute, Balance: 30 * time.Second, Stale: time.Hour}
+
+	tests := []struct {
+		name      string
+		ttls      CacheTTLs
+		partial   string
+		list      bool // 调用 GetTokenList（tokens 路由），否则调用 GetTokens（balance 路由）
+		wantCalls int
+		wantStats cache.Counts
+		wantTTL   time.Duration
+		wantSrc   string
+	}{
+		{
+			name:      "balance served from cache",
+			ttls:      ttls,
+			wantCalls: 1,
+			wantStats: cache.Counts{Hits: 1, Misses: 1},
+			wantTTL:   30 * time.Second,
+			wantSrc:   "cache",
+		},
+		{
+			name:      "tokens use their own ttl",
+			ttls:      ttls,
+			list:      true,
+			wantCalls: 1,
+			wantStats: cache.Counts{Hits: 1, Misses: 1},
+			wantTTL:   time.Minute,
+			wantSrc:   "cache",
+		},
+		{
+			name:      "zero ttl disables the route",
+			ttls:      CacheTTLs{Tokens: time.Minute},
+			wantCalls: 2,
+		},
+		{
+			name:      "partial results are not cached",
+			ttls:      ttls,
+			partial:   PartialPrices,
+			wantCalls: 2,
+			wantStats: cache.Counts{Misses: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &countingTokens{partial: tt.partial}
+			store := newRecordingStore(t)
+			stats := cache.NewStats()
+			service := NewCachedAnkrService(next, store, tt.ttls, stats)
+
+			var source *Source
+			for range 2 {
+				var ctx context.Context
+				ctx, source = requestContext()
+				var err error
+				if tt.list {
+					_, _, err = service.GetTokenList(ctx, "0xabc", []string{"eth"}, "", 10)
+				} else {
+					_, _, err = service.GetTokens(ctx, "0xabc", []string{"eth"}, false, "", 10)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if next.calls != tt.wantCalls {
+				t.Fatalf("upstream calls = %d, want %d", next.calls, tt.wantCalls)
+			}
+			route := CacheRouteBalance
+			if tt.list {
+				route = CacheRouteTokens
+			}
+			if got := stats.Snapshot()[route]; got != tt.wantStats {
+				t.Fatalf("stats = %+v, want %+v", got, tt.wantStats)
+			}
+			if got := source.String(); got != tt.wantSrc {
+				t.Fatalf("source of the second call = %q, want %q", got, tt.wantSrc)
+			}
+			for key, ttl := range store.ttls {
+				if strings.HasPrefix(key, staleKey("")) {
+					if ttl != tt.wantTTL+tt.ttls.Stale {
+						t.Fatalf("stale ttl = %s, want %s", ttl, tt.wantTTL+tt.ttls.Stale)
+					}
+				} else if ttl != tt.wantTTL {
+					t.Fatalf("ttl = %s, want %s", ttl, tt.wantTTL)
+				}
+			}
+		})
+	}
+}
+
+func TestCachedAnkrServiceStale(t *testing.T) {
+	ttls := CacheTTLs{Balance: 30 * time.Second, Stale: time.Hour}
+	key := cacheKey(CacheRouteBalance, "0xabc", []string{"eth"}, "", "size=10", "zero=false")
+
+	tests := []struct {
+		name    string
+		stale   CacheTTLs
+		err     error
+		wantErr error
+		wantSrc string
+	}{
+		{name: "open circuit serves stale copy", stale: ttls, err: fmt.Errorf("ankr: %w", &breaker.OpenError{Name: "ankr", RetryAfter: time.Second}), wantSrc: "stale-cache"},
+		{name: "other errors are returned", stale: ttls, err: errUpstreamDown, wantErr: errUpstreamDown},
+		{name: "no stale copy without stale ttl", stale: CacheTTLs{Balance: 30 * time.Second}, err: breaker.ErrOpen, wantErr: breaker.ErrOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &countingTokens{}
+			store := newRecordingStore(t)
+			service := NewCachedAnkrService(next, store, tt.stale, cache.NewStats())
+
+			ctx, _ := requestContext()
+			if _, _, err := service.GetTokens(ctx, "0xabc", []string{"eth"}, false, "", 10); err != nil {
+				t.Fatal(err)
+			}
+
+			// 正常缓存过期后上游出错
+			store.MemoryStore.Set(ctx, key, nil, -time.Second)
+			next.err = tt.err
+			ctx, source := requestContext()
+			tokens, _, err := service.GetTokens(ctx, "0xabc", []string{"eth"}, false, "", 10)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (len(tokens) != 1 || tokens[0].Symbol != "call1") {
+				t.Fatalf("tokens = %v, want the first result", tokens)
+			}
+			if got := source.String(); got != tt.wantSrc {
+				t.Fatalf("source = %q, want %q", got, tt.wantSrc)
+			}
+		})
+	}
+}
+
+var errUpstreamDown = &UpstreamError{Kind: UpstreamFailed, Err: errors.New("unexpected status 502")}
+
+// 固定返回价格并记录查询时间的价格服务
+type recordingPrices struct {
+	at []time.Time
+}
+
+func (p *recordingPrices) GetTokenPrice(ctx context.Context, chain string, contractAddress string) (string, error) {
+	return "1", nil
+}
+
+func (p *recordingPrices) GetTokenPriceAt(ctx context.Context, chain string, contractAddress string, at time.Time) (string, error) {
+	p.at = append(p.at, at)
+	return fmt.Sprint(len(p.at)), nil
+}
+
+func TestCachedPriceServiceBucketsByHour(t *testing.T) {
+	next := &recordingPrices{}
+	stats := cache.NewStats()
+	service := NewCachedPriceService(next, newRecordingStore(t), stats)
+	ctx, _ := requestContext()
+	hour := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		contract string
+		at       time.Time
+		want     string
+	}{
+		{name: "first query", contract: "0xAA", at: hour.Add(5 * time.Minute), want: "1"},
+		{name: "same hour, other case", contract: "0xaa", at: hour.Add(50 * time.Minute), want: "1"},
+		{name: "next hour", contract: "0xaa", at: hour.Add(65 * time.Minute), want: "2"},
+		{name: "other token", contract: "0xbb", at: hour.Add(5 * time.Minute), want: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.GetTokenPriceAt(ctx, "eth", tt.contract, tt.at)
+			if err != nil || got != tt.want {
+				t.Fatalf("price = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	// 上游按整点查询
+	for _, at := range next.at {
+		if !at.Equal(at.Truncate(time.Hour)) {
+			t.Fatalf("upstream queried at %s, want a full hour", at)
+		}
+	}
+	if got := stats.Snapshot()[CacheRoutePriceHistory]; got != (cache.Counts{Hits: 1, Misses: 3}) {
+		t.Fatalf("stats = %+v", got)
+	}
+}
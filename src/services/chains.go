@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Chain 描述一条受支持的区块链
+type Chain struct {
+	ID             string // 链标识，与 Ankr 的 blockchain 参数一致，也是 API 中 chain 参数的取值
+	Name           string
+	ChainID        int64
+	NativeSymbol   string
+	NativeName     string
+	NativeDecimals int
+}
+
+// AllChains 表示查询所有受支持链的 chain 参数取值
+const AllChains = "all"
+
+// DefaultChain 未指定 chain 参数时使用的链
+const DefaultChain = "base"
+
+// 链注册表，顺序即 chain=all 时的查询顺序
+var chainRegistry = []Chain{
+	{ID: "eth", Name: "Ethereum", ChainID: 1, NativeSymbol: "ETH", NativeName: "Ether", NativeDecimals: 18},
+	{ID: "base", Name: "Base", ChainID: 8453, NativeSymbol: "ETH", NativeName: "Ether", NativeDecimals: 18},
+	{ID: "arbitrum", Name: "Arbitrum One", ChainID: 42161, NativeSymbol: "ETH", NativeName: "Ether", NativeDecimals: 18},
+	{ID: "optimism", Name: "OP Mainnet", ChainID: 10, NativeSymbol: "ETH", NativeName: "Ether", NativeDecimals: 18},
+	{ID: "polygon", Name: "Polygon PoS", ChainID: 137, NativeSymbol: "POL", NativeName: "Polygon Ecosystem Token", NativeDecimals: 18},
+}
+
+// SupportedChains 返回所有受支持的链
+func SupportedChains() []Chain {
+	chains := make([]Chain, len(chainRegistry))
+	copy(chains, chainRegistry)
+	return chains
+}
+
+// GetChain 根据链标识查找链信息
+func GetChain(id string) (Chain, bool) {
+	for _, chain := range chainRegistry {
+		if chain.ID == id {
+			return chain, true
+		}
+	}
+	return Chain{}, false
+}
+
+// ParseChains 解析 chain 查询参数，支持单个链、逗号分隔的列表以及 "all"
+// 空字符串返回默认链；结果去重并保持请求中的顺序
+func ParseChains(param string) ([]string, error) {
+	param = strings.TrimSpace(param)
+	if param == "" {
+		return []string{DefaultChain}, nil
+	}
+
+	if strings.EqualFold(param, AllChains) {
+		chains := make([]string, len(chainRegistry))
+		for i, chain := range chainRegistry {
+			chains[i] = chain.ID
+		}
+		return chains, nil
+	}
+
+	chains := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(param, ",") {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		if _, ok := GetChain(id); !ok {
+			return nil, fmt.Errorf("unsupported chain: %s", id)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		chains = append(chains, id)
+	}
+
+	if len(chains) == 0 {
+		return []string{DefaultChain}, nil
+	}
+	return chains, nil
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/web3-smart-wallet/src/breaker"
+	"github.com/web3-smart-wallet/src/logging"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ClientOptions 上游 HTTP 客户端配置
+type ClientOptions struct {
+	// Timeout 单次请求的超时，不包括重试
+	Timeout time.Duration
+	// MaxRetries 失败后的最大重试次数，0 表示不重试
+	MaxRetries int
+	// BackoffBase 第一次重试前的等待时间，之后每次翻倍，并在 [d/2, d] 之间随机抖动
+	BackoffBase time.Duration
+	// BackoffMax 单次等待时间的上限，上游 Retry-After 超过该值时不再重试
+	BackoffMax time.Duration
+	// MaxIdleConnsPerHost 每个上游主机保持的空闲 keep-alive 连接数
+	MaxIdleConnsPerHost int
+}
+
+// DefaultClientOptions 返回默认的上游客户端配置
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Timeout:             10 * time.Second,
+		MaxRetries:          2,
+		BackoffBase:         200 * time.Millisecond,
+		BackoffMax:          2 * time.Second,
+		MaxIdleConnsPerHost: 32,
+	}
+}
+
+// UpstreamClient 所有上游 REST 和 JSON-RPC 调用共用的客户端
+// 网络错误、429、5xx 和临时性的 JSON-RPC 错误按指数退避重试，并遵守上游的 Retry-After；
+// 每个上游方法有独立的熔断器，失败率过高时直接返回 *breaker.OpenError
+type UpstreamClient struct {
+	client *http.Client
+	// public 请求用户提供的地址（如 did:web 文档），只允许连接公网 IP
+	public   *http.Client
+	options  ClientOptions
+	breakers *breaker.Set
+}
+
+// NewUpstreamClient 创建上游客户端，连接池按主机复用 keep-alive 连接；breakers 为 nil 时不熔断
+func NewUpstreamClient(options ClientOptions, breakers *breaker.Set) *UpstreamClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+	if transport.MaxIdleConns < options.MaxIdleConnsPerHost {
+		transport.MaxIdleConns = options.MaxIdleConnsPerHost
+	}
+
+	return &UpstreamClient{
+		client:   &http.Client{Transport: transport},
+		public:   &http.Client{Transport: publicTransport(transport)},
+		options:  options,
+		breakers: breakers,
+	}
+}
+
+// 各服务共用的上游客户端，ConfigureUpstreamClient 在启动时替换
+var upstreamClient = NewUpstreamClient(DefaultClientOptions(), nil)
+
+// ConfigureUpstreamClient 按 options 重新创建共用的上游客户端，需在处理请求之前调用
+func ConfigureUpstreamClient(options ClientOptions, breakers *breaker.Set) {
+	upstreamClient = NewUpstreamClient(options, breakers)
+}
+
+// 临时性的 JSON-RPC 错误码，重试通常可以成功
+var transientRPCCodes = map[int]bool{
+	-32005: true, // limit exceeded，节点限流
+	-32603: true, // internal error
+}
+
+// retryableError 可以重试的失败，after 为上游 Retry-After 要求的等待时间
+type retryableError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// 发送请求并解析 JSON 响应，可重试的失败按退避策略重试；method 用于上游调用的指标和 span
+func (c *UpstreamClient) doJSON(req *http.Request, method string, out interface{}) error {
+	return c.do(req, method, requestOptions{client: c.client, circuit: c.breakers.Get(breakerName(method, req.URL.Host))}, out)
+}
+
+// requestOptions 单个请求使用的 HTTP 客户端、熔断器（nil 表示不熔断）和响应体大小上限（0 表示不限制）
+type requestOptions struct {
+	client   *http.Client
+	circuit  *breaker.Breaker
+	maxBytes int64
+}
+
+func (c *UpstreamClient) do(req *http.Request, method string, options requestOptions, out interface{}) error {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		var err error
+		if options.circuit != nil {
+			done, openErr := options.circuit.Allow()
+			if openErr != nil {
+				return openErr
+			}
+			err = c.attempt(ctx, req, method, attempt, options, out)
+			done(isUpstreamFailure(ctx, err))
+		} else {
+			err = c.attempt(ctx, req, method, attempt, options, out)
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			return err
+		}
+		if attempt >= c.options.MaxRetries || retryable.after > c.options.BackoffMax {
+			return retryable.err
+		}
+
+		wait := max(c.backoff(attempt), retryable.after)
+		// 剩余时间不够等待时直接返回，把时间留给调用方（例如切换到备用提供方）
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return retryable.err
+		}
+		logging.FromContext(ctx).Debug("retrying upstream call", "upstream_method", method, "attempt", attempt+1, "wait", wait.String(), "error", retryable.err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			// 同时包装 ctx 的错误，调用方（如超时中间件）可以判断失败是否由超时引起
+			return fmt.Errorf("%w: %w", ctx.Err(), retryable.err)
+		case <-timer.C:
+		}
+	}
+}
+
+// 发送一次请求，可以重试的失败返回 *retryableError
+func (c *UpstreamClient) attempt(ctx context.Context, req *http.Request, method string, attempt int, options requestOptions, out interface{}) (err error) {
+	ctx, call := startUpstream(ctx, method, req.URL.String())
+	if attempt > 0 {
+		call.span.SetAttributes(semconv.HTTPRequestResendCount(attempt))
+	}
+	outcome := "ok"
+	defer func() {
+		call.end(outcome, err)
+	}()
+
+	if c.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.options.Timeout)
+		defer cancel()
+	}
+	attemptReq := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return fmt.Errorf("failed to create request: %v", err)
+		}
+		attemptReq.Body = body
+	}
+
+	resp, err := options.client.Do(attemptReq)
+	if err != nil {
+		outcome = "network_error"
+		upstreamErr := &UpstreamError{Kind: networkErrorKind(err), Method: method, Err: fmt.Errorf("request failed: %w", err)}
+		// 地址被拒绝时重试也不会成功
+		if errors.Is(err, ErrNonPublicAddress) {
+			return upstreamErr
+		}
+		return c.networkError(req.Context(), upstreamErr)
+	}
+	defer resp.Body.Close()
+
+	var reader io.Reader = resp.Body
+	if options.maxBytes > 0 {
+		reader = io.LimitReader(resp.Body, options.maxBytes+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		outcome = "network_error"
+		return c.networkError(req.Context(), &UpstreamError{Kind: networkErrorKind(err), Method: method, Status: resp.StatusCode, Err: fmt.Errorf("failed to read response body: %w", err)})
+	}
+	if options.maxBytes > 0 && int64(len(body)) > options.maxBytes {
+		outcome = "too_large"
+		return &UpstreamError{Kind: UpstreamFailed, Method: method, Status: resp.StatusCode, Err: fmt.Errorf("response body exceeds %d bytes", options.maxBytes)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		outcome = "http_" + strconv.Itoa(resp.StatusCode)
+		logger := logging.FromContext(ctx)
+		logger.Warn("unexpected upstream response", "upstream_method", method, "status", resp.StatusCode)
+		logger.Debug("unexpected upstream response body", "upstream_method", method, "body", truncate(string(body), 2048))
+
+		statusErr := statusError(method, resp.StatusCode, parseRetryAfter(resp.Header.Get("Retry-After")))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+			return &retryableError{err: statusErr, after: statusErr.RetryAfter}
+		}
+		return statusErr
+	}
+
+	// 单个请求的 JSON-RPC 错误按类型返回 *UpstreamError，临时性错误重试；批量请求的错误由调用方逐个处理
+	var envelope struct {
+		Error *rpcError `json:"error"`
+	}
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error != nil && envelope.Error.Message != "" {
+		outcome = "rpc_error"
+		logging.FromContext(ctx).Warn("upstream rpc error", "upstream_method", method, "rpc_code", envelope.Error.Code, "rpc_message", truncate(envelope.Error.Message, 256))
+
+		rpcErr := &UpstreamError{
+			Kind:    rpcErrorKind(envelope.Error),
+			Method:  method,
+			Status:  resp.StatusCode,
+			RPCCode: envelope.Error.Code,
+			Err:     fmt.Errorf("rpc error %d: %s", envelope.Error.Code, envelope.Error.Message),
+		}
+		// 上游明确拒绝的请求（如不支持的链）即使使用临时性错误码，重试也不会成功，同样不计入熔断器
+		if transientRPCCodes[envelope.Error.Code] && rpcErr.Kind.transient() {
+			return &retryableError{err: rpcErr}
+		}
+		return rpcErr
+	}
+
+	decodeSpan := startDecode(ctx)
+	err = json.Unmarshal(body, out)
+	decodeSpan.End()
+	if err != nil {
+		outcome = "decode_error"
+		return &UpstreamError{Kind: UpstreamFailed, Method: method, Status: resp.StatusCode, Err: fmt.Errorf("failed to decode response: %w", err)}
+	}
+	return nil
+}
+
+// 辅助函数：熔断器名称，JSON-RPC 调用为 "方法@主机"，REST 调用为主机
+func breakerName(method string, host string) string {
+	if method == host {
+		return host
+	}
+	return method + "@" + host
+}
+
+// 辅助函数：是否计入熔断器的失败，可重试的错误和超时说明上游不健康，
+// 上游明确拒绝的请求（4xx、参数错误）和被调用方主动取消的请求不计入
+func isUpstreamFailure(ctx context.Context, err error) bool {
+	var retryable *retryableError
+	return errors.As(err, &retryable) || errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// 网络错误可以重试，调用方已经取消或超时的除外
+func (c *UpstreamClient) networkError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return &retryableError{err: err}
+}
+
+// 第 attempt 次重试前的等待时间：BackoffBase * 2^attempt，不超过 BackoffMax，在 [d/2, d] 之间随机抖动
+func (c *UpstreamClient) backoff(attempt int) time.Duration {
+	d := c.options.BackoffBase << attempt
+	if d <= 0 || d > c.options.BackoffMax {
+		d = c.options.BackoffMax
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// 辅助函数：解析 Retry-After，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// 辅助函数：截断过长的字符串，用于日志输出
+func truncate(value string, maxLength int) string {
+	if len(value) <= maxLength {
+		return value
+	}
+	return value[:maxLength] + "..."
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+// CovalentDefaultBaseURL Covalent (GoldRush) API 地址
+const CovalentDefaultBaseURL = "https://api.covalenthq.com"
+
+// 链标识到 Covalent 链名的映射
+var covalentChains = map[string]string{
+	"eth":      "eth-mainnet",
+	"base":     "base-mainnet",
+	"arbitrum": "arbitrum-mainnet",
+	"optimism": "optimism-mainnet",
+	"polygon":  "matic-mainnet",
+}
+
+// CovalentProvider 通过 Covalent balances_v2 和 balances_nft 接口查询资产
+// Covalent 一次返回全部余额，分页游标为本地偏移量
+type CovalentProvider struct {
+	baseURL string
+	apiKey  string
+}
+
+// NewCovalentProvider 创建 Covalent 提供方，baseURL 为空时使用 CovalentDefaultBaseURL
+func NewCovalentProvider(baseURL string, apiKey string) Provider {
+	if baseURL == "" {
+		baseURL = CovalentDefaultBaseURL
+	}
+	return &CovalentProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+	}
+}
+
+func (p *CovalentProvider) Name() string {
+	return "covalent"
+}
+
+func (p *CovalentProvider) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.Token, string, error) {
+		return p.getTokens(ctx, address, chain, includeZeroBalance, token, pageSize, true)
+	})
+}
+
+func (p *CovalentProvider) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.Token, string, error) {
+		return p.getTokens(ctx, address, chain, true, token, pageSize, false)
+	})
+}
+
+func (p *CovalentProvider) GetNFTs(ctx context.Context, address string, chains []string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.NFT, string, error) {
+		return p.getNFTs(ctx, address, chain, includeMetadata, token)
+	})
+}
+
+// 查询单条链上的代币余额（包含原生币），在本地分页
+func (p *CovalentProvider) getTokens(ctx context.Context, address string, chain string, includeZeroBalance bool, pageToken string, pageSize int, withBalance bool) ([]api.Token, string, error) {
+	covalentChain, ok := covalentChains[chain]
+	if !ok {
+		return nil, "", fmt.Errorf("covalent does not support chain %s", chain)
+	}
+	offset, err := parseOffset(pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if pageSize <= 0 {
+		pageSize = 10 // 默认每页10个
+	}
+
+	requestURL := fmt.Sprintf("%s/v1/%s/address/%s/balances_v2/?no-spam=true", p.baseURL, covalentChain, address)
+
+	var response struct {
+		Data struct {
+			Items []struct {
+				ContractDecimals     int      `json:"contract_decimals"`
+				ContractName         string   `json:"contract_name"`
+				ContractTickerSymbol string   `json:"contract_ticker_symbol"`
+				ContractAddress      string   `json:"contract_address"`
+				Type                 string   `json:"type"`
+				NativeToken          bool     `json:"native_token"`
+				Balance              string   `json:"balance"`
+				QuoteRate            *float64 `json:"quote_rate"`
+				Quote                *float64 `json:"quote"`
+			} `json:"items"`
+		} `json:"data"`
+		Error        bool   `json:"error"`
+		ErrorMessage string `json:"error_message"`
+	}
+	if err := getJSON(ctx, requestURL, p.headers(), &response); err != nil {
+		return nil, "", fmt.Errorf("covalent api error: %w", err)
+	}
+	if response.Error {
+		return nil, "", fmt.Errorf("covalent api error: %s", response.ErrorMessage)
+	}
+
+	tokens := make([]api.Token, 0)
+	for _, item := range response.Data.Items {
+		// NFT 通过 balances_nft 查询
+		if item.Type == "nft" {
+			continue
+		}
+		// 如果余额为0且不包含零余额，则跳过
+		if !includeZeroBalance && strings.TrimLeft(item.Balance, "0") == "" {
+			continue
+		}
+
+		tokenType := api.ERC20
+		tokenAddress := item.ContractAddress
+		if item.NativeToken {
+			tokenType = api.NATIVE
+			tokenAddress = ""
+		}
+
+		token := api.Token{
+			Address: tokenAddress,
+			Name:    item.ContractName,
+			Symbol:  item.ContractTickerSymbol,
+			Type:    &tokenType,
+			Chain:   strPtr(chain),
+		}
+		if withBalance {
+			decimals := item.ContractDecimals
+			token.Balance = strPtr(utils.FormatDecimalBalance(item.Balance, decimals))
+			token.Decimals = &decimals
+			if item.QuoteRate != nil {
+				token.TokenPrice = strPtr(strconv.FormatFloat(*item.QuoteRate, 'f', -1, 64))
+			}
+			if item.Quote != nil {
+				token.BalanceUsd = strPtr(strconv.FormatFloat(*item.Quote, 'f', -1, 64))
+			}
+		}
+		tokens = append(tokens, token)
+	}
+
+	page, next := paginate(tokens, offset, pageSize)
+	return page, next, nil
+}
+
+// 查询单条链上的 NFT，在本地分页
+func (p *CovalentProvider) getNFTs(ctx context.Context, address string, chain string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+	covalentChain, ok := covalentChains[chain]
+	if !ok {
+		return nil, "", fmt.Errorf("covalent does not support chain %s", chain)
+	}
+	offset, err := parseOffset(pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	requestURL := fmt.Sprintf("%s/v1/%s/address/%s/balances_nft/?no-spam=true&no-nft-asset-metadata=%t", p.baseURL, covalentChain, address, !includeMetadata)
+
+	var response struct {
+		Data struct {
+			Items []struct {
+				ContractName    string   `json:"contract_name"`
+				ContractAddress string   `json:"contract_address"`
+				SupportsErc     []string `json:"supports_erc"`
+				NftData         []struct {
+					TokenID      string `json:"token_id"`
+					TokenURL     string `json:"token_url"`
+					ExternalData *struct {
+						Name        string         `json:"name"`
+						Description string         `json:"description"`
+						Image       string         `json:"image"`
+						Attributes  []nftAttribute `json:"attributes"`
+					} `json:"external_data"`
+				} `json:"nft_data"`
+			} `json:"items"`
+		} `json:"data"`
+		Error        bool   `json:"error"`
+		ErrorMessage string `json:"error_message"`
+	}
+	if err := getJSON(ctx, requestURL, p.headers(), &response); err != nil {
+		return nil, "", fmt.Errorf("covalent api error: %w", err)
+	}
+	if response.Error {
+		return nil, "", fmt.Errorf("covalent api error: %s", response.ErrorMessage)
+	}
+
+	nfts := make([]api.NFT, 0)
+	for _, item := range response.Data.Items {
+		// 只处理ERC721和ERC1155类型的token
+		var nftType api.NFTType
+		for _, erc := range item.SupportsErc {
+			switch erc {
+			case "erc1155":
+				nftType = api.NFTTypeERC1155
+			case "erc721":
+				if nftType == "" {
+					nftType = api.NFTTypeERC721
+				}
+			}
+		}
+		if nftType == "" {
+			continue
+		}
+
+		for _, data := range item.NftData {
+			nftType := nftType
+			nft := api.NFT{
+				ContractAddress: strPtr(item.ContractAddress),
+				TokenId:         strPtr(data.TokenID),
+				Type:            &nftType,
+				Collection:      strPtr(item.ContractName),
+				TokenUri:        strPtr(data.TokenURL),
+				Chain:           strPtr(chain),
+			}
+			if data.ExternalData != nil {
+				traits := toNFTTraits(data.ExternalData.Attributes)
+				nft.Name = strPtr(data.ExternalData.Name)
+				nft.Description = strPtr(data.ExternalData.Description)
+				nft.Image = strPtr(data.ExternalData.Image)
+				nft.Attributes = &traits
+			}
+			nfts = append(nfts, nft)
+		}
+	}
+
+	// NFT 接口没有 pageSize 参数，与 Ankr 默认页大小保持一致
+	page, next := paginate(nfts, offset, 50)
+	return page, next, nil
+}
+
+func (p *CovalentProvider) headers() map[string]string {
+	return map[string]string{
+		"Authorization": "Bearer " + p.apiKey,
+	}
+}
+
+// 辅助函数：解析本地分页的偏移量游标
+func parseOffset(pageToken string) (int, error) {
+	if pageToken == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(pageToken)
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidPageToken
+	}
+	return offset, nil
+}
+
+// 辅助函数：从完整结果中截取一页，返回下一页的偏移量游标
+func paginate[T any](items []T, offset int, pageSize int) ([]T, string) {
+	if offset >= len(items) {
+		return []T{}, ""
+	}
+	end := offset + pageSize
+	if end >= len(items) {
+		return items[offset:], ""
+	}
+	return items[offset:end], strconv.Itoa(end)
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+)
+
+// FailoverOptions 故障转移与对冲请求的参数
+type FailoverOptions struct {
+	// AttemptTimeout 单个提供方的超时时间，超时后转向下一个提供方；0 表示不单独限制
+	AttemptTimeout time.Duration
+	// HedgeDelay 当前请求超过该时长仍未返回时，提前向下一个提供方发出对冲请求；0 表示不对冲
+	HedgeDelay time.Duration
+}
+
+// FailoverProvider 按顺序尝试多个提供方：主提供方出错或超时时转向备用提供方，
+// 并可以在主提供方响应较慢时发出对冲请求，采用最先成功的结果
+type FailoverProvider struct {
+	providers []Provider
+	options   FailoverOptions
+}
+
+// NewFailoverProvider 创建故障转移提供方，providers 的第一个为主提供方
+func NewFailoverProvider(providers []Provider, options FailoverOptions) Provider {
+	return &FailoverProvider{
+		providers: providers,
+		options:   options,
+	}
+}
+
+func (f *FailoverProvider) Name() string {
+	names := make([]string, len(f.providers))
+	for i, p := range f.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, "|")
+}
+
+func (f *FailoverProvider) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return runFailover(ctx, f, pageToken, func(ctx context.Context, p Provider, token string) ([]api.Token, string, error) {
+		return p.GetTokens(ctx, address, chains, includeZeroBalance, token, pageSize)
+	})
+}
+
+func (f *FailoverProvider) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return runFailover(ctx, f, pageToken, func(ctx context.Context, p Provider, token string) ([]api.Token, string, error) {
+		return p.GetTokenList(ctx, address, chains, token, pageSize)
+	})
+}
+
+func (f *FailoverProvider) GetNFTs(ctx context.Context, address string, chains []string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+	return runFailover(ctx, f, pageToken, func(ctx context.Context, p Provider, token string) ([]api.NFT, string, error) {
+		return p.GetNFTs(ctx, address, chains, includeMetadata, token)
+	})
+}
+
+// runFailover 执行一次带故障转移和对冲的查询
+// 分页游标只对生成它的提供方有效，所以有多个提供方时游标会附带提供方名称，
+// 后续页固定由该提供方处理
+func runFailover[T any](ctx context.Context, f *FailoverProvider, pageToken string, call func(ctx context.Context, p Provider, token string) ([]T, string, error)) ([]T, string, error) {
+	candidates := f.providers
+	token := pageToken
+	if len(f.providers) > 1 && pageToken != "" {
+		cursors, err := decodeCursor(pageToken)
+		if err != nil || len(cursors) != 1 {
+			return nil, "", ErrInvalidPageToken
+		}
+		for name, t := range cursors {
+			provider := f.find(name)
+			if provider == nil {
+				return nil, "", ErrInvalidPageToken
+			}
+			candidates = []Provider{provider}
+			token = t
+		}
+	}
+
+	type attemptResult struct {
+		provider Provider
+		source   *Source
+		items    []T
+		next     string
+		err      error
+	}
+
+	ctx, cancelAll := context.WithCancel(ctx)
+	defer cancelAll()
+
+	results := make(chan attemptResult, len(candidates))
+	// 每次尝试使用独立的来源记录器，只有被采用的结果才合并到请求的记录器中，
+	// 失败或被放弃的对冲请求记录的数据缺失不会影响最终应答
+	launch := func(p Provider) {
+		attemptCtx, source := WithSource(ctx)
+		cancel := context.CancelFunc(func() {})
+		if f.options.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(attemptCtx, f.options.AttemptTimeout)
+		}
+		go func() {
+			defer cancel()
+			items, next, err := call(attemptCtx, p, token)
+			results <- attemptResult{provider: p, source: source, items: items, next: next, err: err}
+		}()
+	}
+
+	// 对冲计时器
+	var hedge <-chan time.Time
+	if f.options.HedgeDelay > 0 && len(candidates) > 1 {
+		timer := time.NewTimer(f.options.HedgeDelay)
+		defer timer.Stop()
+		hedge = timer.C
+	}
+
+	launch(candidates[0])
+	launched, pending := 1, 1
+	errs := make([]error, 0, len(candidates))
+
+	for {
+		select {
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				mergeSource(ctx, result.source)
+				recordSource(ctx, result.provider.Name())
+				next := result.next
+				if len(f.providers) > 1 && next != "" {
+					next = encodeCursor(map[string]string{result.provider.Name(): next})
+				}
+				return result.items, next, nil
+			}
+
+			errs = append(errs, fmt.Errorf("%s: %w", result.provider.Name(), result.err))
+			if launched < len(candidates) {
+				launch(candidates[launched])
+				launched++
+				pending++
+			}
+			if pending == 0 {
+				if len(errs) == 1 {
+					return nil, "", errs[0]
+				}
+				return nil, "", fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+			}
+		case <-hedge:
+			hedge = nil
+			if launched < len(candidates) {
+				launch(candidates[launched])
+				launched++
+				pending++
+			}
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+	}
+}
+
+func (f *FailoverProvider) find(name string) Provider {
+	for _, p := range f.providers {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+const (
+	// PartialPrices 应答缺少代币价格和美元余额（价格接口不可用）
+	PartialPrices = "prices"
+	// PartialNativeBalance 应答缺少某些链的原生币余额（原生币余额查询失败）
+	PartialNativeBalance = "native_balance"
+)
+
+// Source 记录一次请求中实际应答的提供方，以及应答缺少的数据
+type Source struct {
+	mu      sync.Mutex
+	names   []string
+	partial []string
+}
+
+type sourceKey struct{}
+
+// WithSource 返回带有应答来源记录器的 context，服务层会把应答的提供方写入其中
+func WithSource(ctx context.Context) (context.Context, *Source) {
+	source := &Source{}
+	return context.WithValue(ctx, sourceKey{}, source), source
+}
+
+// String 返回去重后的提供方名称列表，以逗号分隔
+func (s *Source) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.names, ",")
+}
+
+// 辅助函数：记录应答的提供方
+func recordSource(ctx context.Context, name string) {
+	source, ok := ctx.Value(sourceKey{}).(*Source)
+	if !ok {
+		return
+	}
+
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	for _, n := range source.names {
+		if n == name {
+			return
+		}
+	}
+	source.names = append(source.names, name)
+}
+
+// 辅助函数：把一次尝试记录的提供方和缺少的数据合并到 ctx 的记录器中
+func mergeSource(ctx context.Context, attempt *Source) {
+	attempt.mu.Lock()
+	names, partial := slices.Clone(attempt.names), slices.Clone(attempt.partial)
+	attempt.mu.Unlock()
+
+	for _, name := range names {
+		recordSource(ctx, name)
+	}
+	for _, what := range partial {
+		recordPartial(ctx, what)
+	}
+}
+
+// Partial 返回应答缺少的数据（如 PartialPrices），以逗号分隔，为空表示数据完整
+func (s *Source) Partial() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.partial, ",")
+}
+
+// 辅助函数：记录应答缺少的数据
+func recordPartial(ctx context.Context, what string) {
+	source, ok := ctx.Value(sourceKey{}).(*Source)
+	if !ok {
+		return
+	}
+
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if !slices.Contains(source.partial, what) {
+		source.partial = append(source.partial, what)
+	}
+}
+
+// 辅助函数：本次请求的应答是否缺少数据，不完整的结果不写入缓存
+func isPartial(ctx context.Context) bool {
+	source, ok := ctx.Value(sourceKey{}).(*Source)
+	return ok && source.Partial() != ""
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/services/fakes"
+)
+
+// 返回固定结果的提供方，delay 模拟响应较慢的上游
+type stubProvider struct {
+	name  string
+	delay time.Duration
+	err   error
+	// partial 开始查询时记录的数据缺失，为空表示数据完整
+	partial string
+	// pages 以分页游标为键的代币页，值为代币 symbol 列表和下一页游标
+	pages map[string]stubPage
+}
+
+type stubPage struct {
+	symbols []string
+	next    string
+}
+
+func (p *stubProvider) Name() string {
+	return p.name
+}
+
+func (p *stubProvider) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	if p.partial != "" {
+		recordPartial(ctx, p.partial)
+	}
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+	if p.err != nil {
+		return nil, "", p.err
+	}
+	page, ok := p.pages[pageToken]
+	if !ok {
+		return nil, "", errors.New("unknown page token " + pageToken)
+	}
+	tokens := make([]api.Token, len(page.symbols))
+	for i, symbol := range page.symbols {
+		tokens[i] = api.Token{Symbol: symbol}
+	}
+	return tokens, page.next, nil
+}
+
+func (p *stubProvider) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return p.GetTokens(ctx, address, chains, true, pageToken, pageSize)
+}
+
+func (p *stubProvider) GetNFTs(ctx context.Context, address string, chains []string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+	return nil, "", p.err
+}
+
+func symbols(tokens []api.Token) []string {
+	result := make([]string, len(tokens))
+	for i, token := range tokens {
+		result[i] = token.Symbol
+	}
+	return result
+}
+
+func TestFailoverProvider(t *testing.T) {
+	upstreamDown := &UpstreamError{Kind: UpstreamFailed, Err: errors.New("unexpected status 502")}
+	pages := map[string]stubPage{"": {symbols: []string{"ETH"}, next: "p2"}, "p2": {symbols: []string{"USDC"}}}
+
+	tests := []struct {
+		name      string
+		providers []Provider
+		options   FailoverOptions
+		pageToken string
+		want      []string
+		wantNext  bool
+		wantSrc   string
+		// wantPartial 请求记录器中应有的数据缺失
+		wantPartial string
+		wantErr     error
+	}{
+		{
+			name:      "primary answers",
+			providers: []Provider{&stubProvider{name: "ankr", pages: pages}, &stubProvider{name: "alchemy", err: upstreamDown}},
+			want:      []string{"ETH"},
+			wantNext:  true,
+			wantSrc:   "ankr",
+		},
+		{
+			name:      "fails over to the backup",
+			providers: []Provider{&stubProvider{name: "ankr", err: upstreamDown}, &stubProvider{name: "alchemy", pages: pages}},
+			want:      []string{"ETH"},
+			wantNext:  true,
+			wantSrc:   "alchemy",
+		},
+		{
+			name:      "attempt timeout fails over",
+			providers: []Provider{&stubProvider{name: "ankr", delay: time.Second, pages: pages}, &stubProvider{name: "alchemy", pages: pages}},
+			options:   FailoverOptions{AttemptTimeout: 20 * time.Millisecond},
+			want:      []string{"ETH"},
+			wantNext:  true,
+			wantSrc:   "alchemy",
+		},
+		{
+			name:      "hedged request wins over a slow primary",
+			providers: []Provider{&stubProvider{name: "ankr", delay: time.Second, pages: pages}, &stubProvider{name: "alchemy", pages: pages}},
+			options:   FailoverOptions{HedgeDelay: 20 * time.Millisecond},
+			want:      []string{"ETH"},
+			wantNext:  true,
+			wantSrc:   "alchemy",
+		},
+		{
+			name:        "partial result of the winner is kept",
+			providers:   []Provider{&stubProvider{name: "alchemy", partial: PartialPrices, pages: pages}, &stubProvider{name: "ankr", pages: pages}},
+			want:        []string{"ETH"},
+			wantNext:    true,
+			wantSrc:     "alchemy",
+			wantPartial: PartialPrices,
+		},
+		{
+			name:      "partial result of a hedged loser is dropped",
+			providers: []Provider{&stubProvider{name: "alchemy", delay: time.Second, partial: PartialPrices, pages: pages}, &stubProvider{name: "ankr", pages: pages}},
+			options:   FailoverOptions{HedgeDelay: 20 * time.Millisecond},
+			want:      []string{"ETH"},
+			wantNext:  true,
+			wantSrc:   "ankr",
+		},
+		{
+			name:      "partial result of a failed primary is dropped",
+			providers: []Provider{&stubProvider{name: "alchemy", partial: PartialPrices, err: upstreamDown}, &stubProvider{name: "ankr", pages: pages}},
+			want:      []string{"ETH"},
+			wantNext:  true,
+			wantSrc:   "ankr",
+		},
+		{
+			name:      "cursor stays with the provider that issued it",
+			providers: []Provider{&stubProvider{name: "ankr", err: upstreamDown}, &stubProvider{name: "alchemy", pages: pages}},
+			pageToken: encodeCursor(map[string]string{"alchemy": "p2"}),
+			want:      []string{"USDC"},
+			wantSrc:   "alchemy",
+		},
+		{
+			name:      "cursor for an unknown provider",
+			providers: []Provider{&stubProvider{name: "ankr", pages: pages}, &stubProvider{name: "alchemy", pages: pages}},
+			pageToken: encodeCursor(map[string]string{"moralis": "p2"}),
+			wantErr:   ErrInvalidPageToken,
+		},
+		{
+			name:      "malformed cursor",
+			providers: []Provider{&stubProvider{name: "ankr", pages: pages}, &stubProvider{name: "alchemy", pages: pages}},
+			pageToken: "not-a-cursor!",
+			wantErr:   ErrInvalidPageToken,
+		},
+		{
+			name:      "all providers fail",
+			providers: []Provider{&stubProvider{name: "ankr", err: upstreamDown}, &stubProvider{name: "alchemy", err: upstreamDown}},
+			wantErr:   upstreamDown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, source := WithSource(context.Background())
+			provider := NewFailoverProvider(tt.providers, tt.options)
+
+			start := time.Now()
+			tokens, next, err := provider.GetTokens(ctx, fakes.Wallet, []string{"eth"}, false, tt.pageToken, 10)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if time.Since(start) > 500*time.Millisecond {
+				t.Fatalf("took %s, slow provider was waited for", time.Since(start))
+			}
+			if err != nil {
+				return
+			}
+			if got := symbols(tokens); len(got) != len(tt.want) || got[0] != tt.want[0] {
+				t.Fatalf("tokens = %v, want %v", got, tt.want)
+			}
+			if (next != "") != tt.wantNext {
+				t.Fatalf("next = %q, want next page %t", next, tt.wantNext)
+			}
+			if next != "" {
+				// 下一页游标必须由同一提供方处理
+				cursors, err := decodeCursor(next)
+				if err != nil || cursors[tt.wantSrc] == "" {
+					t.Fatalf("next cursor %q = %v, %v; want a cursor for %s", next, cursors, err, tt.wantSrc)
+				}
+			}
+			if got := source.String(); got != tt.wantSrc {
+				t.Fatalf("source = %q, want %q", got, tt.wantSrc)
+			}
+			if got := source.Partial(); got != tt.wantPartial {
+				t.Fatalf("partial = %q, want %q", got, tt.wantPartial)
+			}
+		})
+	}
+}
+
+// 主提供方的上游返回 5xx 时，通过真实的 HTTP 调用切换到备用提供方
+func TestFailoverProviderWithFakes(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	moralis := fakes.NewMoralisServer()
+	defer moralis.Close()
+
+	router, err := NewProviderRouter(map[string]Provider{
+		"ankr":    NewAnkrProvider(NewAnkrService(down.URL), NewNFTService(down.URL)),
+		"moralis": NewMoralisProvider(moralis.URL, fakes.APIKey),
+	}, []string{"ankr", "moralis"}, nil, FailoverOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, source := WithSource(context.Background())
+	tokens, next, err := router.GetTokens(ctx, fakes.Wallet, []string{"polygon"}, false, "", 10)
+	if err != nil {
+		t.Fatalf("GetTokens: %v", err)
+	}
+	if got := symbols(tokens); len(got) != 1 || got[0] != "POL" {
+		t.Fatalf("tokens = %v, want [POL]", got)
+	}
+	if source.String() != "moralis" {
+		t.Fatalf("source = %q, want moralis", source.String())
+	}
+
+	// 第二页直接由 Moralis 处理，不再请求 Ankr
+	tokens, next, err = router.GetTokens(ctx, fakes.Wallet, []string{"polygon"}, false, next, 10)
+	if err != nil || next != "" {
+		t.Fatalf("second page: next %q, error %v", next, err)
+	}
+	if got := symbols(tokens); len(got) != 1 || got[0] != "USDC" {
+		t.Fatalf("second page tokens = %v, want [USDC]", got)
+	}
+}
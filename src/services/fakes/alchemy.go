@@ -0,0 +1,134 @@
+package fakes
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// NewAlchemyServer 启动模拟 Alchemy 的服务
+// 对应的 baseURL 为 server.URL + "/{network}"，API key 为 APIKey
+func NewAlchemyServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 路径形如 /{network}/v2/{key}、/{network}/nft/v3/{key}/getNFTsForOwner 或 /api/prices/v1/{key}/tokens/by-address
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case len(parts) == 3 && parts[1] == "v2" && parts[2] == APIKey:
+			handleAlchemyRPC(w, r)
+		case len(parts) == 6 && parts[0] == "api" && parts[1] == "prices" && parts[3] == APIKey && parts[4] == "tokens" && parts[5] == "by-address":
+			handleAlchemyPrices(w, r)
+		case len(parts) == 5 && parts[1] == "nft" && parts[3] == APIKey && parts[4] == "getNFTsForOwner":
+			handleAlchemyNFTs(w, r)
+		default:
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid api key or path"})
+		}
+	}))
+}
+
+func handleAlchemyRPC(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	// 批量请求
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		var batch []rpcRequest
+		json.Unmarshal(body, &batch)
+		responses := make([]interface{}, len(batch))
+		for i, req := range batch {
+			responses[i] = alchemyRPCResponse(req)
+		}
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusOK, rpcError(nil, -32700, "parse error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, alchemyRPCResponse(req))
+}
+
+func alchemyRPCResponse(req rpcRequest) interface{} {
+	var params []json.RawMessage
+	json.Unmarshal(req.Params, &params)
+	first := ""
+	if len(params) > 0 {
+		json.Unmarshal(params[0], &first)
+	}
+
+	switch req.Method {
+	case "alchemy_getTokenBalances":
+		balances := []interface{}{}
+		if strings.EqualFold(first, Wallet) {
+			balances = append(balances,
+				map[string]string{"contractAddress": "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", "tokenBalance": "0x00000000000000000000000000000000000000000000000000000000004c4b40"},
+				map[string]string{"contractAddress": "0x6b175474e89094c44da98b954eedeac495271d0f", "tokenBalance": "0x0000000000000000000000000000000000000000000000000000000000000000"},
+			)
+		}
+		return rpcResult(req.ID, map[string]interface{}{"address": first, "tokenBalances": balances})
+	case "alchemy_getTokenMetadata":
+		metadata := map[string]map[string]interface{}{
+			"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48": {"name": "USD Coin", "symbol": "USDC", "decimals": 6},
+			"0x6b175474e89094c44da98b954eedeac495271d0f": {"name": "Dai Stablecoin", "symbol": "DAI", "decimals": 18},
+		}
+		if m, ok := metadata[strings.ToLower(first)]; ok {
+			return rpcResult(req.ID, m)
+		}
+		return rpcResult(req.ID, map[string]interface{}{"name": nil, "symbol": nil, "decimals": nil})
+	default:
+		return rpcError(req.ID, -32601, "method not found")
+	}
+}
+
+// 模拟的美元价格，没有报价的合约返回错误项
+var alchemyPrices = map[string]string{
+	"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48": "0.9999",
+	"0x6b175474e89094c44da98b954eedeac495271d0f": "1.0001",
+}
+
+func handleAlchemyPrices(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Addresses []struct {
+			Network string `json:"network"`
+			Address string `json:"address"`
+		} `json:"addresses"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+		return
+	}
+
+	data := make([]interface{}, len(req.Addresses))
+	for i, a := range req.Addresses {
+		item := map[string]interface{}{"network": a.Network, "address": a.Address, "prices": []interface{}{}, "error": nil}
+		if price, ok := alchemyPrices[strings.ToLower(a.Address)]; ok {
+			item["prices"] = []interface{}{map[string]string{"currency": "usd", "value": price, "lastUpdatedAt": "2025-01-01T00:00:00Z"}}
+		} else {
+			item["error"] = map[string]string{"message": "Token not found"}
+		}
+		data[i] = item
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+func handleAlchemyNFTs(w http.ResponseWriter, r *http.Request) {
+	nfts := []interface{}{}
+	if strings.EqualFold(r.URL.Query().Get("owner"), Wallet) {
+		nfts = append(nfts, map[string]interface{}{
+			"contract": map[string]interface{}{
+				"address": "0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d", "name": "BoredApeYachtClub", "tokenType": "ERC721",
+				"openSeaMetadata": map[string]interface{}{"collectionName": "Bored Ape Yacht Club"},
+			},
+			"tokenId": "42", "tokenType": "ERC721", "name": "#42", "description": "",
+			"tokenUri": "ipfs://QmeSjSinHpPnmXmspMjwiXyN6zS4E9zccariGR3jxcaWtq/42",
+			"image":    map[string]interface{}{"cachedUrl": "https://example.com/bayc/42.png"},
+			"raw": map[string]interface{}{"metadata": map[string]interface{}{
+				"attributes": []interface{}{map[string]interface{}{"trait_type": "Fur", "value": "Golden Brown"}},
+			}},
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ownedNfts": nfts, "pageKey": nil, "totalCount": len(nfts)})
+}
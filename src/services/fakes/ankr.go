@@ -0,0 +1,146 @@
+package fakes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// NewAnkrServer 启动模拟 Ankr Advanced API 的服务，
+// 支持 ankr_getAccountBalance、ankr_getNFTsByOwner、ankr_getTokenPrice、ankr_getTokenPriceHistory
+// ankr_getTransactionsByAddress、ankr_getTokenTransfers 和 ankr_getNftTransfers
+// 服务地址即 multichain 接口地址，无需 API key
+func NewAnkrServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusOK, rpcError(nil, -32700, "parse error"))
+			return
+		}
+
+		var params struct {
+			WalletAddress   string      `json:"walletAddress"`
+			PageToken       string      `json:"pageToken"`
+			Blockchain      interface{} `json:"blockchain"`
+			ContractAddress string      `json:"contractAddress"`
+			FromTimestamp   int64       `json:"fromTimestamp"`
+			ToTimestamp     int64       `json:"toTimestamp"`
+			Address         []string    `json:"address"`
+		}
+		json.Unmarshal(req.Params, &params)
+		owned := strings.EqualFold(params.WalletAddress, Wallet) ||
+			(len(params.Address) == 1 && strings.EqualFold(params.Address[0], Wallet))
+
+		switch req.Method {
+		case "ankr_getAccountBalance":
+			assets := []interface{}{}
+			nextPageToken := ""
+			if owned && params.PageToken == "" {
+				assets = append(assets,
+					map[string]interface{}{"blockchain": "base", "tokenName": "Ether", "tokenSymbol": "ETH", "tokenDecimals": 18, "tokenType": "NATIVE", "balance": "0.5", "balanceUsd": "1500", "tokenPrice": "3000"},
+					map[string]interface{}{"blockchain": "base", "tokenName": "USD Coin", "tokenSymbol": "USDC", "tokenDecimals": 6, "tokenType": "ERC20", "contractAddress": "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913", "balance": "5.10942", "balanceUsd": "5.1098800936437622513", "tokenPrice": "1.0000900481157865768"},
+				)
+				nextPageToken = "ankr-page-2"
+			} else if owned && params.PageToken == "ankr-page-2" {
+				assets = append(assets,
+					map[string]interface{}{"blockchain": "base", "tokenName": "Degen", "tokenSymbol": "DEGEN", "tokenDecimals": 18, "tokenType": "ERC20", "contractAddress": "0x4ed4e862860bed51a9570b96d89af5e1b0efefed", "balance": "1000", "balanceUsd": "3.2", "tokenPrice": "0.0032"},
+				)
+			}
+			writeJSON(w, http.StatusOK, rpcResult(req.ID, map[string]interface{}{
+				"assets":        assets,
+				"nextPageToken": nextPageToken,
+			}))
+		case "ankr_getNFTsByOwner":
+			assets := []interface{}{}
+			if owned {
+				assets = append(assets, map[string]interface{}{
+					"blockchain": "base", "name": "Popo-frog #1", "tokenId": "1", "tokenUrl": "https://example.com/meta/1",
+					"imageUrl": "https://example.com/img/1.png", "collectionName": "Popo-frog", "symbol": "POPO",
+					"contractType": "ERC1155", "contractAddress": "0x2867a6dfb2c15f789c3bf0b5547ac4117850a060", "quantity": "1",
+					"traits": []interface{}{map[string]interface{}{"trait_type": "Website", "value": "https://example.com"}},
+				})
+			}
+			writeJSON(w, http.StatusOK, rpcResult(req.ID, map[string]interface{}{
+				"owner":         params.WalletAddress,
+				"assets":        assets,
+				"nextPageToken": "",
+			}))
+		case "ankr_getTokenPrice":
+			// 原生币（不带合约地址）价格为 3000，其他代币为 1
+			usdPrice := "1"
+			if params.ContractAddress == "" {
+				usdPrice = "3000"
+			}
+			writeJSON(w, http.StatusOK, rpcResult(req.ID, map[string]interface{}{
+				"usdPrice":        usdPrice,
+				"blockchain":      params.Blockchain,
+				"contractAddress": params.ContractAddress,
+			}))
+		case "ankr_getTokenPriceHistory":
+			// 原生币历史价格为 2900，其他代币为 1
+			usdPrice := "1"
+			if params.ContractAddress == "" {
+				usdPrice = "2900"
+			}
+			writeJSON(w, http.StatusOK, rpcResult(req.ID, map[string]interface{}{
+				"quotes": []interface{}{
+					map[string]interface{}{"timestamp": params.ToTimestamp - 600, "blockHeight": 1, "usdPrice": usdPrice},
+				},
+			}))
+		case "ankr_getTransactionsByAddress":
+			transactions := []interface{}{}
+			nextPageToken := ""
+			if owned && params.PageToken == "" {
+				transactions = append(transactions,
+					// 转出 0.05 ETH，gasUsed 21000 * gasPrice 1 gwei
+					map[string]interface{}{"blockchain": "base", "hash": "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060", "from": strings.ToLower(Wallet), "to": "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913", "value": "0xb1a2bc2ec50000", "gasPrice": "0x3b9aca00", "gasUsed": "0x5208", "status": "0x1", "blockNumber": "0x1a2b3c", "timestamp": "0x67877a28"},
+					// 转入 1 ETH
+					map[string]interface{}{"blockchain": "base", "hash": "0x9d7f3c4e2b1a0f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a39281706", "from": "0x4ed4e862860bed51a9570b96d89af5e1b0efefed", "to": strings.ToLower(Wallet), "value": "0xde0b6b3a7640000", "gasPrice": "0x3b9aca00", "gasUsed": "0x5208", "status": "0x1", "blockNumber": "0x1a2b00", "timestamp": "0x67870000"},
+				)
+				nextPageToken = "ankr-tx-page-2"
+			} else if owned && params.PageToken == "ankr-tx-page-2" {
+				transactions = append(transactions,
+					// 执行失败的合约调用
+					map[string]interface{}{"blockchain": "base", "hash": "0x1111111111111111111111111111111111111111111111111111111111111111", "from": strings.ToLower(Wallet), "to": "0x4ed4e862860bed51a9570b96d89af5e1b0efefed", "value": "0x0", "gasPrice": "0x3b9aca00", "gasUsed": "0xc350", "status": "0x0", "blockNumber": "0x1a0000", "timestamp": "0x67800000"},
+				)
+			}
+			writeJSON(w, http.StatusOK, rpcResult(req.ID, map[string]interface{}{
+				"transactions":  transactions,
+				"nextPageToken": nextPageToken,
+			}))
+		case "ankr_getTokenTransfers", "ankr_getNftTransfers":
+			all := ankrTokenTransfers
+			if req.Method == "ankr_getNftTransfers" {
+				all = ankrNFTTransfers
+			}
+			transfers := []interface{}{}
+			if owned {
+				for _, transfer := range all {
+					timestamp := transfer["timestamp"].(int64)
+					if (params.FromTimestamp != 0 && timestamp < params.FromTimestamp) || (params.ToTimestamp != 0 && timestamp > params.ToTimestamp) {
+						continue
+					}
+					transfers = append(transfers, transfer)
+				}
+			}
+			writeJSON(w, http.StatusOK, rpcResult(req.ID, map[string]interface{}{
+				"transfers":     transfers,
+				"nextPageToken": "",
+			}))
+		default:
+			writeJSON(w, http.StatusOK, rpcError(req.ID, -32601, "method not found"))
+		}
+	}))
+}
+
+// Wallet 的 ERC20 转账记录，USDC 的 tokenDecimals 故意与余额接口不一致，用于验证元数据以余额接口为准
+var ankrTokenTransfers = []map[string]interface{}{
+	{"blockchain": "base", "fromAddress": "0x4ed4e862860bed51a9570b96d89af5e1b0efefed", "toAddress": strings.ToLower(Wallet), "contractAddress": "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913", "value": "0.00002551", "valueRawInteger": "25510000", "tokenName": "USDC", "tokenSymbol": "USDC", "tokenDecimals": 12, "transactionHash": "0xaaaa000000000000000000000000000000000000000000000000000000000001", "blockHeight": int64(1715100), "timestamp": int64(1736930000)},
+	{"blockchain": "base", "fromAddress": strings.ToLower(Wallet), "toAddress": "0x4ed4e862860bed51a9570b96d89af5e1b0efefed", "contractAddress": "0x4ed4e862860bed51a9570b96d89af5e1b0efefed", "value": "250", "valueRawInteger": "250000000000000000000", "tokenName": "Degen", "tokenSymbol": "DEGEN", "tokenDecimals": 18, "transactionHash": "0xaaaa000000000000000000000000000000000000000000000000000000000002", "blockHeight": int64(1714000), "timestamp": int64(1736800000)},
+}
+
+// Wallet 的 NFT 转账记录
+var ankrNFTTransfers = []map[string]interface{}{
+	{"blockchain": "base", "fromAddress": "0x0000000000000000000000000000000000000000", "toAddress": strings.ToLower(Wallet), "contractAddress": "0x2867a6dfb2c15f789c3bf0b5547ac4117850a060", "type": "ERC1155", "value": "1", "tokenId": "1", "collectionName": "Popo-frog", "collectionSymbol": "POPO", "transactionHash": "0xbbbb000000000000000000000000000000000000000000000000000000000001", "blockHeight": int64(1714500), "timestamp": int64(1736860000)},
+}
@@ -0,0 +1,55 @@
+package fakes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// NewCovalentServer 启动模拟 Covalent (GoldRush) API 的服务，请求需带 Authorization: Bearer APIKey
+func NewCovalentServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+APIKey {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"error": true, "error_message": "Invalid API key", "error_code": 401})
+			return
+		}
+
+		// 路径形如 /v1/{chain}/address/{address}/balances_v2/ 或 /v1/{chain}/address/{address}/balances_nft/
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 5 || parts[2] != "address" {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": true, "error_message": "not found", "error_code": 404})
+			return
+		}
+		owned := strings.EqualFold(parts[3], Wallet)
+
+		items := []interface{}{}
+		switch parts[4] {
+		case "balances_v2":
+			if owned {
+				items = append(items,
+					map[string]interface{}{"contract_decimals": 18, "contract_name": "Ether", "contract_ticker_symbol": "ETH", "contract_address": "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", "type": "cryptocurrency", "native_token": true, "balance": "250000000000000000", "quote_rate": 3000.0, "quote": 750.0},
+					map[string]interface{}{"contract_decimals": 18, "contract_name": "Arbitrum", "contract_ticker_symbol": "ARB", "contract_address": "0x912ce59144191c1204e64559fe8253a0e49e6548", "type": "cryptocurrency", "native_token": false, "balance": "100000000000000000000", "quote_rate": 0.8, "quote": 80.0},
+					map[string]interface{}{"contract_decimals": 6, "contract_name": "Tether USD", "contract_ticker_symbol": "USDT", "contract_address": "0xfd086bc7cd5c481dcc9c85ebe478a1c0b69fcbb9", "type": "stablecoin", "native_token": false, "balance": "0", "quote_rate": 1.0, "quote": 0.0},
+				)
+			}
+		case "balances_nft":
+			if owned {
+				items = append(items, map[string]interface{}{
+					"contract_name": "Arbitrum Odyssey", "contract_address": "0xfae39ec09730ca0f14262a636d2d7c5539353752", "supports_erc": []string{"erc20", "erc721"},
+					"nft_data": []interface{}{map[string]interface{}{
+						"token_id": "1001", "token_url": "https://example.com/odyssey/1001",
+						"external_data": map[string]interface{}{"name": "Odyssey #1001", "description": "", "image": "https://example.com/odyssey/1001.png", "attributes": []interface{}{}},
+					}},
+				})
+			}
+		default:
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": true, "error_message": "not found", "error_code": 404})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data":  map[string]interface{}{"address": parts[3], "chain_name": parts[1], "items": items},
+			"error": false,
+		})
+	}))
+}
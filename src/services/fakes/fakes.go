@@ -0,0 +1,48 @@
+// Package fakes 提供各数据提供方的 httptest 假服务，用于在不访问外部网络的情况下测试和演示服务层
+package fakes
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// APIKey 假服务接受的 API key
+const APIKey = "test-key"
+
+// Wallet 假服务返回数据所属的钱包地址，其他地址返回空结果
+const Wallet = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+
+// 辅助函数：以 JSON 格式写出响应
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// 辅助函数：JSON-RPC 成功响应
+func rpcResult(id interface{}, result interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"result":  result,
+	}
+}
+
+// 辅助函数：JSON-RPC 错误响应
+func rpcError(id interface{}, code int, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	}
+}
+
+// JSON-RPC 请求
+type rpcRequest struct {
+	ID     interface{}     `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
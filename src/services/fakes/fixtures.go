@@ -0,0 +1,20 @@
+package fakes
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed fixtures/ankr/*.json
+var ankrFixtures embed.FS
+
+// AnkrFixtures 内置的 Ankr Advanced API 交互，覆盖 Wallet 的余额、NFT、价格、交易和转账查询，用 NewReplayServer 回放
+// 这些文件由假服务 NewAnkrServer 生成，数据是人工构造的，不是真实 API 的录制结果；
+// 修改假服务后用 go test ./src/services/fakes -run TestAnkrFixtures -update 重新生成
+func AnkrFixtures() fs.FS {
+	fsys, err := fs.Sub(ankrFixtures, "fixtures/ankr")
+	if err != nil {
+		panic(err)
+	}
+	return fsys
+}
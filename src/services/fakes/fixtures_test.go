@@ -0,0 +1,123 @@
+package fakes
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "regenerate the built-in Ankr fixtures from NewAnkrServer")
+
+// 内置录制结果由 NewAnkrServer 生成，修改假服务后需要用 -update 重新生成：
+// go test ./src/services/fakes -run TestAnkrFixtures -update
+func TestAnkrFixtures(t *testing.T) {
+	fixtures, err := LoadFixtures(AnkrFixtures())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no built-in fixtures")
+	}
+
+	server := NewAnkrServer()
+	defer server.Close()
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Method+"-"+fixture.Key()[len(fixture.Method)+1:], func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": fixture.Method, "params": fixture.Params})
+			resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			response, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *update {
+				fixture.Status = resp.StatusCode
+				fixture.Response = response
+				if err := writeFixture("fixtures/ankr", fixture); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			if resp.StatusCode != fixture.Status {
+				t.Fatalf("status = %d, fixture has %d", resp.StatusCode, fixture.Status)
+			}
+			if got, want := withoutID(t, response), withoutID(t, fixture.Response); !reflect.DeepEqual(got, want) {
+				t.Fatalf("NewAnkrServer response differs from the fixture, regenerate with -update\ngot:  %v\nwant: %v", got, want)
+			}
+		})
+	}
+}
+
+// 辅助函数：解析 JSON-RPC 响应并去掉 id
+func withoutID(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	delete(envelope, "id")
+	return envelope
+}
+
+func TestReplayServer(t *testing.T) {
+	server, err := NewReplayServer(AnkrFixtures())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantID     float64
+	}{
+		{
+			name:       "params match regardless of key order and id",
+			body:       `{"jsonrpc":"2.0","id":7,"method":"ankr_getAccountBalance","params":{"walletAddress":"` + Wallet + `","pageSize":10,"onlyWhitelisted":false,"blockchain":["eth"]}}`,
+			wantStatus: http.StatusOK,
+			wantID:     7,
+		},
+		{
+			name:       "unknown params",
+			body:       `{"jsonrpc":"2.0","id":3,"method":"ankr_getAccountBalance","params":{"walletAddress":"0x0000000000000000000000000000000000000001"}}`,
+			wantStatus: http.StatusNotFound,
+			wantID:     3,
+		},
+		{
+			name:       "malformed request",
+			body:       `{`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(server.URL, "application/json", bytes.NewReader([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			var envelope map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+				t.Fatal(err)
+			}
+			if id, _ := envelope["id"].(float64); id != tt.wantID {
+				t.Fatalf("id = %v, want %v", envelope["id"], tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package fakes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// NewMoralisServer 启动模拟 Moralis Web3 Data API 的服务，请求需带 X-API-Key: APIKey
+func NewMoralisServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != APIKey {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Invalid key"})
+			return
+		}
+
+		// 路径形如 /api/v2.2/wallets/{address}/tokens 或 /api/v2.2/{address}/nft
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case len(parts) == 5 && parts[2] == "wallets" && parts[4] == "tokens":
+			handleMoralisTokens(w, r, parts[3])
+		case len(parts) == 4 && parts[3] == "nft":
+			handleMoralisNFTs(w, r, parts[2])
+		default:
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "not found"})
+		}
+	}))
+}
+
+func handleMoralisTokens(w http.ResponseWriter, r *http.Request, address string) {
+	result := []interface{}{}
+	cursor := ""
+	if strings.EqualFold(address, Wallet) {
+		if r.URL.Query().Get("cursor") == "" {
+			result = append(result, map[string]interface{}{
+				"token_address": "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", "name": "Polygon Ecosystem Token", "symbol": "POL", "decimals": 18,
+				"balance": "2000000000000000000", "balance_formatted": "2", "usd_price": 0.5, "usd_value": 1, "native_token": true,
+			})
+			cursor = "moralis-page-2"
+		} else {
+			result = append(result, map[string]interface{}{
+				"token_address": "0x3c499c542cef5e3811e1192ce70d8cc03d5c3359", "name": "USD Coin", "symbol": "USDC", "decimals": 6,
+				"balance": "12500000", "balance_formatted": "12.5", "usd_price": 1, "usd_value": 12.5, "native_token": false,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"cursor": cursor, "result": result})
+}
+
+func handleMoralisNFTs(w http.ResponseWriter, r *http.Request, address string) {
+	result := []interface{}{}
+	if strings.EqualFold(address, Wallet) {
+		result = append(result, map[string]interface{}{
+			"token_address": "0x2953399124f0cbb46d2cbacd8a89cf0599974963", "token_id": "7", "contract_type": "ERC1155",
+			"name": "OpenSea Collections", "token_uri": "https://example.com/meta/7",
+			"normalized_metadata": map[string]interface{}{
+				"name": "Lucky Seven", "description": "A lucky token", "image": "https://example.com/img/7.png",
+				"attributes": []interface{}{map[string]interface{}{"trait_type": "Rarity", "value": 7}},
+			},
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"cursor": nil, "result": result})
+}
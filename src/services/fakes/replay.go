@@ -0,0 +1,179 @@
+package fakes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Fixture 一次录制的 JSON-RPC 交互，只保存方法、参数和响应，不保存带 API key 的上游地址
+type Fixture struct {
+	Method   string          `json:"method"`
+	Params   json.RawMessage `json:"params"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Key 回放时匹配请求的键：方法名加参数的哈希，参数按键名排序后计算，与 JSON-RPC id 和 volatileParams 无关
+func (f Fixture) Key() string {
+	return fixtureKey(f.Method, f.Params)
+}
+
+// LoadFixtures 读取 fsys 根目录下所有 .json 录制文件
+func LoadFixtures(fsys fs.FS) (map[string]Fixture, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fixtures: %v", err)
+	}
+
+	fixtures := make(map[string]Fixture)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture %s: %v", entry.Name(), err)
+		}
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %v", entry.Name(), err)
+		}
+		fixtures[fixture.Key()] = fixture
+	}
+	return fixtures, nil
+}
+
+// NewReplayServer 启动回放录制结果的 JSON-RPC 服务，请求按方法和参数匹配录制文件，
+// 响应中的 id 替换为请求的 id；没有对应录制的请求返回 404 和 JSON-RPC 错误
+func NewReplayServer(fsys fs.FS) (*httptest.Server, error) {
+	fixtures, err := LoadFixtures(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, rpcError(nil, -32700, "parse error"))
+			return
+		}
+
+		fixture, ok := fixtures[fixtureKey(req.Method, req.Params)]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, rpcError(req.ID, -32601, "no recorded exchange for "+req.Method))
+			return
+		}
+
+		response := fixture.Response
+		var envelope map[string]json.RawMessage
+		if json.Unmarshal(response, &envelope) == nil {
+			id, _ := json.Marshal(req.ID)
+			envelope["id"] = id
+			response, _ = json.Marshal(envelope)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(fixture.Status)
+		w.Write(response)
+	})), nil
+}
+
+// NewRecordingServer 启动录制代理：请求原样转发给 upstreamURL，单个 JSON-RPC 请求的交互写入 dir 下的录制文件，
+// 文件名为 "<方法>-<参数哈希>.json"；批量请求只转发不录制
+func NewRecordingServer(upstreamURL string, dir string) (*httptest.Server, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %v", err)
+	}
+
+	client := &http.Client{}
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, rpcError(nil, -32700, "parse error"))
+			return
+		}
+
+		proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, upstreamURL, bytes.NewReader(body))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, rpcError(nil, -32603, "failed to create request"))
+			return
+		}
+		proxyReq.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(proxyReq)
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, rpcError(nil, -32603, "upstream request failed"))
+			return
+		}
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, rpcError(nil, -32603, "failed to read upstream response"))
+			return
+		}
+
+		var req rpcRequest
+		if json.Unmarshal(body, &req) == nil && req.Method != "" && json.Valid(respBody) {
+			fixture := Fixture{Method: req.Method, Params: req.Params, Status: resp.StatusCode, Response: respBody}
+			mu.Lock()
+			err := writeFixture(dir, fixture)
+			mu.Unlock()
+			if err != nil {
+				slog.Warn("failed to record fixture", "method", req.Method, "error", err)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		w.Write(respBody)
+	})), nil
+}
+
+// 辅助函数：写入录制文件，同一请求重复录制时覆盖
+func writeFixture(dir string, fixture Fixture) error {
+	var params, response bytes.Buffer
+	if err := json.Indent(&params, fixture.Params, "", "  "); err == nil {
+		fixture.Params = params.Bytes()
+	}
+	if err := json.Indent(&response, fixture.Response, "", "  "); err == nil {
+		fixture.Response = response.Bytes()
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fixture.Method + "-" + strings.TrimPrefix(fixture.Key(), fixture.Method+":") + ".json"
+	return os.WriteFile(filepath.Join(dir, name), append(data, '\n'), 0o644)
+}
+
+// 随当前时间变化的参数，匹配时忽略，例如价格历史的查询区间
+var volatileParams = []string{"fromTimestamp", "toTimestamp"}
+
+// 辅助函数：方法名加规范化参数的哈希，参数重新编码以忽略键顺序和空白
+func fixtureKey(method string, params json.RawMessage) string {
+	canonical := []byte("null")
+	var value interface{}
+	if len(params) > 0 && json.Unmarshal(params, &value) == nil {
+		if object, ok := value.(map[string]interface{}); ok {
+			for _, name := range volatileParams {
+				delete(object, name)
+			}
+		}
+		canonical, _ = json.Marshal(value)
+	}
+	sum := sha256.Sum256(canonical)
+	return method + ":" + hex.EncodeToString(sum[:6])
+}
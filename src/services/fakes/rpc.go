@@ -0,0 +1,227 @@
+package fakes
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// Multicall3 地址，与主网部署地址一致
+const Multicall3 = "0xcA11bde05977b3631167028862bE2a173976CA11"
+
+// 假节点上部署的 ERC20 代币
+const (
+	// USDCToken 6 位小数，symbol() 返回 string
+	USDCToken = "0x1111111111111111111111111111111111111111"
+	// MKRToken 18 位小数，symbol() 和 name() 返回 bytes32
+	MKRToken = "0x2222222222222222222222222222222222222222"
+)
+
+// 假代币的元数据和 Wallet 持有的余额
+var rpcTokens = map[string]struct {
+	decimals int64
+	symbol   string
+	name     string
+	bytes32  bool
+	balance  string
+}{
+	USDCToken: {decimals: 6, symbol: "USDC", name: "USD Coin", balance: "1234500000"},
+	MKRToken:  {decimals: 18, symbol: "MKR", name: "Maker", bytes32: true, balance: "0"},
+}
+
+// NewRPCServer 启动模拟以太坊 JSON-RPC 节点的服务
+// 支持 eth_getBalance（Wallet 持有 1.5 个原生币）以及对 Multicall3.aggregate3 的 eth_call，
+// 支持单个请求和批量请求
+func NewRPCServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			writeJSON(w, http.StatusBadRequest, rpcError(nil, -32700, "parse error"))
+			return
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+			var batch []rpcRequest
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				writeJSON(w, http.StatusBadRequest, rpcError(nil, -32700, "parse error"))
+				return
+			}
+			responses := make([]interface{}, len(batch))
+			for i, req := range batch {
+				responses[i] = ethRPCResponse(req)
+			}
+			writeJSON(w, http.StatusOK, responses)
+			return
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			writeJSON(w, http.StatusBadRequest, rpcError(nil, -32700, "parse error"))
+			return
+		}
+		writeJSON(w, http.StatusOK, ethRPCResponse(req))
+	}))
+}
+
+func ethRPCResponse(req rpcRequest) interface{} {
+	var params []json.RawMessage
+	json.Unmarshal(req.Params, &params)
+
+	switch req.Method {
+	case "eth_chainId":
+		return rpcResult(req.ID, "0x1")
+	case "eth_getBalance":
+		var address string
+		if len(params) > 0 {
+			json.Unmarshal(params[0], &address)
+		}
+		if strings.EqualFold(address, Wallet) {
+			return rpcResult(req.ID, "0x14d1120d7b160000") // 1.5 * 10^18
+		}
+		return rpcResult(req.ID, "0x0")
+	case "eth_call":
+		var call struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		if len(params) > 0 {
+			json.Unmarshal(params[0], &call)
+		}
+		if !strings.EqualFold(call.To, Multicall3) {
+			return rpcResult(req.ID, "0x")
+		}
+		data, err := hex.DecodeString(strings.TrimPrefix(call.Data, "0x"))
+		if err != nil || len(data) < 4 || hex.EncodeToString(data[:4]) != "82ad56cb" {
+			return rpcError(req.ID, 3, "execution reverted")
+		}
+		result, ok := aggregate3(data[4:])
+		if !ok {
+			return rpcError(req.ID, 3, "execution reverted")
+		}
+		return rpcResult(req.ID, "0x"+hex.EncodeToString(result))
+	default:
+		return rpcError(req.ID, -32601, "the method "+req.Method+" does not exist/is not available")
+	}
+}
+
+// 解码 aggregate3((address,bool,bytes)[]) 的参数，执行每个调用并编码 (bool,bytes)[] 返回值
+func aggregate3(args []byte) ([]byte, bool) {
+	word := func(offset int) (int, bool) {
+		if offset < 0 || offset+32 > len(args) {
+			return 0, false
+		}
+		return int(new(big.Int).SetBytes(args[offset : offset+32]).Int64()), true
+	}
+
+	arrayOffset, ok := word(0)
+	if !ok {
+		return nil, false
+	}
+	count, ok := word(arrayOffset)
+	if !ok {
+		return nil, false
+	}
+	elements := arrayOffset + 32
+
+	results := make([][]byte, 0, count)
+	successes := make([]bool, 0, count)
+	for i := 0; i < count; i++ {
+		elementOffset, ok := word(elements + 32*i)
+		if !ok {
+			return nil, false
+		}
+		start := elements + elementOffset
+		if start+32 > len(args) {
+			return nil, false
+		}
+		target := "0x" + hex.EncodeToString(args[start+12:start+32])
+		dataOffset, ok := word(start + 64)
+		if !ok {
+			return nil, false
+		}
+		length, ok := word(start + dataOffset)
+		if !ok || start+dataOffset+32+length > len(args) {
+			return nil, false
+		}
+		callData := args[start+dataOffset+32 : start+dataOffset+32+length]
+
+		result, success := erc20Call(target, callData)
+		results = append(results, result)
+		successes = append(successes, success)
+	}
+
+	// 编码 (bool success, bytes returnData)[]
+	head := append(uint256(32), uint256(int64(count))...)
+	tail := make([]byte, 0)
+	offset := int64(32 * count)
+	for i, result := range results {
+		element := make([]byte, 0)
+		if successes[i] {
+			element = append(element, uint256(1)...)
+		} else {
+			element = append(element, uint256(0)...)
+		}
+		element = append(element, uint256(64)...)
+		element = append(element, dynamicBytes(result)...)
+		head = append(head, uint256(offset)...)
+		tail = append(tail, element...)
+		offset += int64(len(element))
+	}
+	return append(head, tail...), true
+}
+
+// 模拟 ERC20 合约的只读调用，未知合约视为调用失败
+func erc20Call(target string, callData []byte) ([]byte, bool) {
+	token, ok := rpcTokens[strings.ToLower(target)]
+	if !ok || len(callData) < 4 {
+		return nil, false
+	}
+
+	switch hex.EncodeToString(callData[:4]) {
+	case "70a08231": // balanceOf(address)
+		if len(callData) < 36 {
+			return nil, false
+		}
+		balance := big.NewInt(0)
+		if strings.EqualFold("0x"+hex.EncodeToString(callData[16:36]), Wallet) {
+			balance.SetString(token.balance, 10)
+		}
+		word := make([]byte, 32)
+		balance.FillBytes(word)
+		return word, true
+	case "313ce567": // decimals()
+		return uint256(token.decimals), true
+	case "95d89b41": // symbol()
+		return abiString(token.symbol, token.bytes32), true
+	case "06fdde03": // name()
+		return abiString(token.name, token.bytes32), true
+	default:
+		return nil, false
+	}
+}
+
+func uint256(value int64) []byte {
+	word := make([]byte, 32)
+	big.NewInt(value).FillBytes(word)
+	return word
+}
+
+func dynamicBytes(value []byte) []byte {
+	padded := (len(value) + 31) / 32 * 32
+	data := make([]byte, 32+padded)
+	copy(data, uint256(int64(len(value))))
+	copy(data[32:], value)
+	return data
+}
+
+func abiString(value string, bytes32 bool) []byte {
+	if bytes32 {
+		word := make([]byte, 32)
+		copy(word, value)
+		return word
+	}
+	return append(uint256(32), dynamicBytes([]byte(value))...)
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/web3-smart-wallet/src/api"
+)
+
+// MoralisDefaultBaseURL Moralis Web3 Data API 地址
+const MoralisDefaultBaseURL = "https://deep-index.moralis.io"
+
+// 链标识到 Moralis chain 参数的映射
+var moralisChains = map[string]string{
+	"eth":      "eth",
+	"base":     "base",
+	"arbitrum": "arbitrum",
+	"optimism": "optimism",
+	"polygon":  "polygon",
+}
+
+// MoralisProvider 通过 Moralis Wallet API 和 NFT API 查询资产
+type MoralisProvider struct {
+	baseURL string
+	apiKey  string
+}
+
+// NewMoralisProvider 创建 Moralis 提供方，baseURL 为空时使用 MoralisDefaultBaseURL
+func NewMoralisProvider(baseURL string, apiKey string) Provider {
+	if baseURL == "" {
+		baseURL = MoralisDefaultBaseURL
+	}
+	return &MoralisProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+	}
+}
+
+func (p *MoralisProvider) Name() string {
+	return "moralis"
+}
+
+func (p *MoralisProvider) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.Token, string, error) {
+		return p.getTokens(ctx, address, chain, includeZeroBalance, token, pageSize, true)
+	})
+}
+
+func (p *MoralisProvider) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.Token, string, error) {
+		return p.getTokens(ctx, address, chain, true, token, pageSize, false)
+	})
+}
+
+func (p *MoralisProvider) GetNFTs(ctx context.Context, address string, chains []string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.NFT, string, error) {
+		return p.getNFTs(ctx, address, chain, includeMetadata, token)
+	})
+}
+
+// 查询单条链上的代币余额（包含原生币）
+func (p *MoralisProvider) getTokens(ctx context.Context, address string, chain string, includeZeroBalance bool, pageToken string, pageSize int, withBalance bool) ([]api.Token, string, error) {
+	moralisChain, ok := moralisChains[chain]
+	if !ok {
+		return nil, "", fmt.Errorf("moralis does not support chain %s", chain)
+	}
+
+	if pageSize <= 0 {
+		pageSize = 10 // 默认每页10个
+	}
+	query := url.Values{}
+	query.Set("chain", moralisChain)
+	query.Set("limit", strconv.Itoa(pageSize))
+	if pageToken != "" {
+		query.Set("cursor", pageToken)
+	}
+	requestURL := fmt.Sprintf("%s/api/v2.2/wallets/%s/tokens?%s", p.baseURL, address, query.Encode())
+
+	var response struct {
+		Cursor string `json:"cursor"`
+		Result []struct {
+			TokenAddress     string  `json:"token_address"`
+			Name             string  `json:"name"`
+			Symbol           string  `json:"symbol"`
+			Decimals         int     `json:"decimals"`
+			Balance          string  `json:"balance"`
+			BalanceFormatted string  `json:"balance_formatted"`
+			UsdPrice         float64 `json:"usd_price"`
+			UsdValue         float64 `json:"usd_value"`
+			NativeToken      bool    `json:"native_token"`
+		} `json:"result"`
+	}
+	if err := getJSON(ctx, requestURL, p.headers(), &response); err != nil {
+		return nil, "", fmt.Errorf("moralis api error: %w", err)
+	}
+
+	tokens := make([]api.Token, 0)
+	for _, asset := range response.Result {
+		// 如果余额为0且不包含零余额，则跳过
+		if !includeZeroBalance && strings.TrimLeft(asset.Balance, "0") == "" {
+			continue
+		}
+
+		tokenType := api.ERC20
+		tokenAddress := asset.TokenAddress
+		if asset.NativeToken {
+			tokenType = api.NATIVE
+			tokenAddress = ""
+		}
+
+		token := api.Token{
+			Address: tokenAddress,
+			Name:    asset.Name,
+			Symbol:  asset.Symbol,
+			Type:    &tokenType,
+			Chain:   strPtr(chain),
+		}
+		if withBalance {
+			decimals := asset.Decimals
+			token.Balance = strPtr(asset.BalanceFormatted)
+			token.Decimals = &decimals
+			token.TokenPrice = strPtr(strconv.FormatFloat(asset.UsdPrice, 'f', -1, 64))
+			token.BalanceUsd = strPtr(strconv.FormatFloat(asset.UsdValue, 'f', -1, 64))
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, response.Cursor, nil
+}
+
+// 查询单条链上的 NFT
+func (p *MoralisProvider) getNFTs(ctx context.Context, address string, chain string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+	moralisChain, ok := moralisChains[chain]
+	if !ok {
+		return nil, "", fmt.Errorf("moralis does not support chain %s", chain)
+	}
+
+	query := url.Values{}
+	query.Set("chain", moralisChain)
+	query.Set("format", "decimal")
+	query.Set("normalizeMetadata", strconv.FormatBool(includeMetadata))
+	if pageToken != "" {
+		query.Set("cursor", pageToken)
+	}
+	requestURL := fmt.Sprintf("%s/api/v2.2/%s/nft?%s", p.baseURL, address, query.Encode())
+
+	var response struct {
+		Cursor string `json:"cursor"`
+		Result []struct {
+			TokenAddress       string `json:"token_address"`
+			TokenID            string `json:"token_id"`
+			ContractType       string `json:"contract_type"`
+			Name               string `json:"name"`
+			TokenURI           string `json:"token_uri"`
+			NormalizedMetadata struct {
+				Name        string         `json:"name"`
+				Description string         `json:"description"`
+				Image       string         `json:"image"`
+				Attributes  []nftAttribute `json:"attributes"`
+			} `json:"normalized_metadata"`
+		} `json:"result"`
+	}
+	if err := getJSON(ctx, requestURL, p.headers(), &response); err != nil {
+		return nil, "", fmt.Errorf("moralis api error: %w", err)
+	}
+
+	nfts := make([]api.NFT, 0)
+	for _, asset := range response.Result {
+		// 只处理ERC721和ERC1155类型的token
+		if asset.ContractType != "ERC721" && asset.ContractType != "ERC1155" {
+			continue
+		}
+
+		name := asset.NormalizedMetadata.Name
+		if name == "" {
+			name = asset.Name
+		}
+
+		nftType := api.NFTType(asset.ContractType)
+		traits := toNFTTraits(asset.NormalizedMetadata.Attributes)
+		nfts = append(nfts, api.NFT{
+			ContractAddress: strPtr(asset.TokenAddress),
+			TokenId:         strPtr(asset.TokenID),
+			Type:            &nftType,
+			Name:            strPtr(name),
+			Description:     strPtr(asset.NormalizedMetadata.Description),
+			Image:           strPtr(asset.NormalizedMetadata.Image),
+			Attributes:      &traits,
+			Collection:      strPtr(asset.Name),
+			TokenUri:        strPtr(asset.TokenURI),
+			Chain:           strPtr(chain),
+		})
+	}
+
+	return nfts, response.Cursor, nil
+}
+
+func (p *MoralisProvider) headers() map[string]string {
+	return map[string]string{
+		"X-API-Key": p.apiKey,
+	}
+}
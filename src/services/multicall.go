@@ -0,0 +1,203 @@
+package services
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Multicall3DefaultAddress Multicall3 在各主要网络上的部署地址
+const Multicall3DefaultAddress = "0xcA11bde05977b3631167028862bE2a173976CA11"
+
+// 函数选择器
+const (
+	aggregate3Selector = "82ad56cb" // aggregate3((address,bool,bytes)[])
+	balanceOfSelector  = "70a08231" // balanceOf(address)
+	decimalsSelector   = "313ce567" // decimals()
+	symbolSelector     = "95d89b41" // symbol()
+	nameSelector       = "06fdde03" // name()
+)
+
+// multicall 是 Multicall3.aggregate3 中的一次调用
+type multicall struct {
+	Target   string
+	CallData []byte
+}
+
+// multicallResult 是 aggregate3 中一次调用的返回
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// 辅助函数：编码 aggregate3 调用数据，每个调用都允许失败
+func encodeAggregate3(calls []multicall) (string, error) {
+	// 数组内容：长度、各元素的偏移量、各元素
+	head := make([]byte, 0, 32*(len(calls)+1))
+	tail := make([]byte, 0)
+	head = append(head, abiUint(uint64(len(calls)))...)
+	offset := uint64(32 * len(calls))
+	for _, call := range calls {
+		target, err := abiAddress(call.Target)
+		if err != nil {
+			return "", err
+		}
+		element := make([]byte, 0, 32*4+len(call.CallData))
+		element = append(element, target...)
+		element = append(element, abiUint(1)...)  // allowFailure
+		element = append(element, abiUint(96)...) // callData 相对元素起点的偏移
+		element = append(element, abiBytes(call.CallData)...)
+
+		head = append(head, abiUint(offset)...)
+		tail = append(tail, element...)
+		offset += uint64(len(element))
+	}
+
+	data := make([]byte, 0, 32+len(head)+len(tail))
+	data = append(data, abiUint(32)...) // 数组参数的偏移
+	data = append(data, head...)
+	data = append(data, tail...)
+	return "0x" + aggregate3Selector + hex.EncodeToString(data), nil
+}
+
+// 辅助函数：解码 aggregate3 的返回值 (bool success, bytes returnData)[]
+func decodeAggregate3(result string) ([]multicallResult, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid multicall result: %v", err)
+	}
+
+	arrayOffset, err := abiReadUint(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	count, err := abiReadUint(data, arrayOffset)
+	if err != nil {
+		return nil, err
+	}
+	elements := arrayOffset + 32
+	if count > uint64(len(data))/32 {
+		return nil, fmt.Errorf("invalid multicall result: bad array length")
+	}
+
+	results := make([]multicallResult, count)
+	for i := uint64(0); i < count; i++ {
+		elementOffset, err := abiReadUint(data, elements+32*i)
+		if err != nil {
+			return nil, err
+		}
+		// 偏移量不超过数据长度，相加时不会溢出
+		if elementOffset > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid abi data: out of range")
+		}
+		start := elements + elementOffset
+		success, err := abiReadUint(data, start)
+		if err != nil {
+			return nil, err
+		}
+		bytesOffset, err := abiReadUint(data, start+32)
+		if err != nil {
+			return nil, err
+		}
+		if bytesOffset > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid abi data: out of range")
+		}
+		returnData, err := abiReadBytes(data, start+bytesOffset)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = multicallResult{Success: success == 1, ReturnData: returnData}
+	}
+	return results, nil
+}
+
+// 辅助函数：编码 balanceOf(address) 调用
+func encodeBalanceOf(owner string) ([]byte, error) {
+	address, err := abiAddress(owner)
+	if err != nil {
+		return nil, err
+	}
+	selector, _ := hex.DecodeString(balanceOfSelector)
+	return append(selector, address...), nil
+}
+
+// 辅助函数：编码无参数的调用
+func encodeSelector(selector string) []byte {
+	data, _ := hex.DecodeString(selector)
+	return data
+}
+
+// 辅助函数：解码 uint256 返回值
+func decodeUint256(data []byte) (*big.Int, error) {
+	if len(data) < 32 {
+		return nil, fmt.Errorf("invalid uint256 result")
+	}
+	return new(big.Int).SetBytes(data[:32]), nil
+}
+
+// 辅助函数：解码 string 返回值，兼容部分早期代币返回的 bytes32
+func decodeABIString(data []byte) (string, error) {
+	if len(data) == 32 {
+		return strings.TrimRight(string(data), "\x00"), nil
+	}
+	offset, err := abiReadUint(data, 0)
+	if err != nil {
+		return "", err
+	}
+	value, err := abiReadBytes(data, offset)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// 辅助函数：编码 uint256
+func abiUint(value uint64) []byte {
+	word := make([]byte, 32)
+	new(big.Int).SetUint64(value).FillBytes(word)
+	return word
+}
+
+// 辅助函数：编码地址（左侧补零到 32 字节）
+func abiAddress(address string) ([]byte, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(address), "0x"))
+	if err != nil || len(raw) != 20 {
+		return nil, fmt.Errorf("invalid address: %s", address)
+	}
+	return append(make([]byte, 12), raw...), nil
+}
+
+// 辅助函数：编码动态 bytes（长度 + 右侧补零的内容）
+func abiBytes(value []byte) []byte {
+	padded := (len(value) + 31) / 32 * 32
+	data := make([]byte, 32+padded)
+	copy(data, abiUint(uint64(len(value))))
+	copy(data[32:], value)
+	return data
+}
+
+// 辅助函数：读取指定位置的 uint256，超出 uint64 范围视为格式错误
+func abiReadUint(data []byte, offset uint64) (uint64, error) {
+	if offset > uint64(len(data)) || uint64(len(data))-offset < 32 {
+		return 0, fmt.Errorf("invalid abi data: out of range")
+	}
+	word := new(big.Int).SetBytes(data[offset : offset+32])
+	if !word.IsUint64() {
+		return 0, fmt.Errorf("invalid abi data: value too large")
+	}
+	return word.Uint64(), nil
+}
+
+// 辅助函数：读取指定位置的动态 bytes
+func abiReadBytes(data []byte, offset uint64) ([]byte, error) {
+	length, err := abiReadUint(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	start := offset + 32
+	if length > uint64(len(data))-start {
+		return nil, fmt.Errorf("invalid abi data: out of range")
+	}
+	return data[start : start+length], nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+)
+
+// 编码 aggregate3 的返回值 (bool success, bytes returnData)[]
+func encodeResults(results []multicallResult) string {
+	head := append(abiUint(32), abiUint(uint64(len(results)))...)
+	tail := make([]byte, 0)
+	offset := uint64(32 * len(results))
+	for _, result := range results {
+		success := uint64(0)
+		if result.Success {
+			success = 1
+		}
+		element := append(abiUint(success), abiUint(64)...)
+		element = append(element, abiBytes(result.ReturnData)...)
+		head = append(head, abiUint(offset)...)
+		tail = append(tail, element...)
+		offset += uint64(len(element))
+	}
+	return "0x" + hex.EncodeToString(append(head, tail...))
+}
+
+// 辅助函数：按 32 字节的字拼接十六进制数据
+func words(values ...[]byte) string {
+	return "0x" + hex.EncodeToString(bytes.Join(values, nil))
+}
+
+func TestEncodeAggregate3(t *testing.T) {
+	owner, err := encodeBalanceOf("0x742d35Cc6634C0532925a3b844Bc454e4438f44e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encodeAggregate3([]multicall{
+		{Target: "0x1111111111111111111111111111111111111111", CallData: owner},
+		{Target: "0x2222222222222222222222222222222222222222", CallData: encodeSelector(symbolSelector)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "0x" + aggregate3Selector +
+		// 数组偏移、长度、两个元素的偏移
+		strings.Repeat("0", 62) + "20" +
+		strings.Repeat("0", 63) + "2" +
+		strings.Repeat("0", 62) + "40" +
+		strings.Repeat("0", 61) + "100" +
+		// 第一个元素：target、allowFailure、callData 偏移、callData（36 字节，补齐到 64 字节）
+		strings.Repeat("0", 24) + strings.Repeat("1", 40) +
+		strings.Repeat("0", 63) + "1" +
+		strings.Repeat("0", 62) + "60" +
+		strings.Repeat("0", 62) + "24" +
+		balanceOfSelector + strings.Repeat("0", 24) + "742d35cc6634c0532925a3b844bc454e4438f44e" + strings.Repeat("0", 56) +
+		// 第二个元素：callData 为 4 字节的 symbol()
+		strings.Repeat("0", 24) + strings.Repeat("2", 40) +
+		strings.Repeat("0", 63) + "1" +
+		strings.Repeat("0", 62) + "60" +
+		strings.Repeat("0", 63) + "4" +
+		symbolSelector + strings.Repeat("0", 56)
+	if data != want {
+		t.Errorf("encodeAggregate3() =\n%s\nwant\n%s", data, want)
+	}
+
+	if _, err := encodeAggregate3([]multicall{{Target: "0x1234", CallData: owner}}); err == nil {
+		t.Error("encodeAggregate3() with an invalid target should fail")
+	}
+	if _, err := encodeBalanceOf("not-an-address"); err == nil {
+		t.Error("encodeBalanceOf() with an invalid owner should fail")
+	}
+}
+
+func TestDecodeAggregate3(t *testing.T) {
+	valid := []multicallResult{
+		{Success: true, ReturnData: abiUint(1234)},
+		{Success: false, ReturnData: []byte{}},
+		{Success: true, ReturnData: append(abiUint(32), abiBytes([]byte("USD Coin"))...)},
+	}
+	encoded := encodeResults(valid)
+
+	tests := []struct {
+		name    string
+		result  string
+		want    []multicallResult
+		wantErr bool
+	}{
+		{name: "results with a failed call", result: encoded, want: valid},
+		{name: "empty array", result: words(abiUint(32), abiUint(0)), want: []multicallResult{}},
+		{name: "invalid hex", result: "0xzz", wantErr: true},
+		{name: "empty result", result: "0x", wantErr: true},
+		{name: "array offset out of range", result: words(abiUint(64), abiUint(0)), wantErr: true},
+		{name: "array length exceeds data", result: words(abiUint(32), abiUint(1000)), wantErr: true},
+		{name: "array length too large", result: words(abiUint(32), abiUint(math.MaxUint64)), wantErr: true},
+		{name: "truncated element", result: encoded[:len(encoded)-64], wantErr: true},
+		{name: "element offset out of range", result: words(abiUint(32), abiUint(1), abiUint(4096)), wantErr: true},
+		{
+			// 偏移量与元素起点相加后溢出回到数据开头
+			name:    "element offset overflows",
+			result:  words(abiUint(32), abiUint(1), abiUint(math.MaxUint64-63), abiUint(1), abiUint(64), abiUint(0)),
+			wantErr: true,
+		},
+		{
+			name:    "return data offset overflows",
+			result:  words(abiUint(32), abiUint(1), abiUint(32), abiUint(1), abiUint(math.MaxUint64-95), abiUint(0)),
+			wantErr: true,
+		},
+		{
+			name:    "return data length exceeds data",
+			result:  words(abiUint(32), abiUint(1), abiUint(32), abiUint(1), abiUint(64), abiUint(64), abiUint(0)),
+			wantErr: true,
+		},
+		{
+			name:    "offset wider than uint64",
+			result:  words(append(make([]byte, 23), 1, 0, 0, 0, 0, 0, 0, 0, 32), abiUint(0)),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeAggregate3(tt.result)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decodeAggregate3() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeAggregate3() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("decodeAggregate3() returned %d results, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].Success != tt.want[i].Success || !bytes.Equal(got[i].ReturnData, tt.want[i].ReturnData) {
+					t.Errorf("result %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeABIString(t *testing.T) {
+	bytes32 := func(value string) []byte {
+		word := make([]byte, 32)
+		copy(word, value)
+		return word
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "string", data: append(abiUint(32), abiBytes([]byte("USDC"))...), want: "USDC"},
+		{name: "string longer than a word", data: append(abiUint(32), abiBytes([]byte(strings.Repeat("x", 40)))...), want: strings.Repeat("x", 40)},
+		{name: "empty string", data: append(abiUint(32), abiUint(0)...), want: ""},
+		{name: "bytes32", data: bytes32("MKR"), want: "MKR"},
+		{name: "bytes32 without padding", data: bytes32(strings.Repeat("y", 32)), want: strings.Repeat("y", 32)},
+		{name: "empty", data: nil, wantErr: true},
+		{name: "string offset out of range", data: append(abiUint(96), abiUint(0)...), wantErr: true},
+		{name: "string length exceeds data", data: append(abiUint(32), abiUint(33)...), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeABIString(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeABIString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decodeABIString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeUint256(t *testing.T) {
+	value, err := decodeUint256(append(abiUint(6), 0xff))
+	if err != nil || value.Int64() != 6 {
+		t.Errorf("decodeUint256() = %v, %v, want 6", value, err)
+	}
+	if _, err := decodeUint256(make([]byte, 31)); err == nil {
+		t.Error("decodeUint256() with a short result should fail")
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/logging"
+)
+
+// NativeTokenService 保证余额结果中包含每条链的原生币
+// 原生币固定排在第一页最前面，后续页中不再重复出现；
+// 上游第一页没有返回某条链的原生币时，通过 natives 读取余额、通过 prices 补充美元价格；
+// 读取失败时仍然返回其他代币，结果标记为缺少数据（PartialNativeBalance、PartialPrices）
+type NativeTokenService struct {
+	next          AnkrServiceInterface
+	natives       AnkrServiceInterface
+	prices        PriceServiceInterface
+	alwaysInclude bool
+}
+
+// NewNativeTokenService 创建原生币补全服务
+// next: 被包装的余额服务
+// natives: 用于读取原生币余额的服务，例如未配置代币的 RPCBalanceService
+// prices: 原生币价格来源，为 nil 时不补充价格
+// alwaysInclude: 为 true 时即使余额为 0 且未设置 includeZeroBalance 也返回原生币
+func NewNativeTokenService(next AnkrServiceInterface, natives AnkrServiceInterface, prices PriceServiceInterface, alwaysInclude bool) AnkrServiceInterface {
+	return &NativeTokenService{
+		next:          next,
+		natives:       natives,
+		prices:        prices,
+		alwaysInclude: alwaysInclude,
+	}
+}
+
+func (s *NativeTokenService) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	tokens, nextPageToken, err := s.next.GetTokens(ctx, address, chains, includeZeroBalance, pageToken, pageSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 分离原生币和其他代币
+	natives := make(map[string]api.Token)
+	others := make([]api.Token, 0, len(tokens))
+	for _, token := range tokens {
+		if token.Type != nil && *token.Type == api.NATIVE && token.Chain != nil {
+			natives[*token.Chain] = token
+			continue
+		}
+		others = append(others, token)
+	}
+
+	// 原生币只在第一页返回
+	if pageToken != "" {
+		return others, nextPageToken, nil
+	}
+
+	// 并发补全上游没有返回的原生币，先找出缺少的链，避免与写入 natives 的 goroutine 同时读取
+	missing := make([]string, 0, len(chains))
+	for _, chain := range chains {
+		if _, ok := natives[chain]; !ok {
+			missing = append(missing, chain)
+		}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chain := range missing {
+		wg.Add(1)
+		go func(chain string) {
+			defer wg.Done()
+			token, err := s.nativeToken(ctx, address, chain)
+			if err != nil {
+				// 缺少原生币的结果标记为不完整，响应带上 X-Data-Partial 并且不写入缓存
+				logging.FromContext(ctx).Warn("failed to fetch native token", "address", address, "chain", chain, "error", err)
+				recordPartial(ctx, PartialNativeBalance)
+				return
+			}
+			mu.Lock()
+			natives[chain] = token
+			mu.Unlock()
+		}(chain)
+	}
+	wg.Wait()
+
+	// 按请求的链顺序排在最前面
+	result := make([]api.Token, 0, len(natives)+len(others))
+	for _, chain := range chains {
+		token, ok := natives[chain]
+		if !ok {
+			continue
+		}
+		if !includeZeroBalance && !s.alwaysInclude && isZeroAmount(token.Balance) {
+			continue
+		}
+		result = append(result, normalizeNative(token, chain))
+	}
+	result = append(result, others...)
+
+	return result, nextPageToken, nil
+}
+
+func (s *NativeTokenService) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return s.next.GetTokenList(ctx, address, chains, pageToken, pageSize)
+}
+
+// 读取单条链上的原生币余额和价格
+func (s *NativeTokenService) nativeToken(ctx context.Context, address string, chain string) (api.Token, error) {
+	tokens, _, err := s.natives.GetTokens(ctx, address, []string{chain}, true, "", 1)
+	if err != nil {
+		return api.Token{}, err
+	}
+
+	var token *api.Token
+	for i := range tokens {
+		if tokens[i].Type != nil && *tokens[i].Type == api.NATIVE {
+			token = &tokens[i]
+			break
+		}
+	}
+	if token == nil {
+		return api.Token{}, fmt.Errorf("native balance not returned")
+	}
+
+	if s.prices != nil && token.TokenPrice == nil {
+		price, err := s.prices.GetTokenPrice(ctx, chain, "")
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to fetch native token price", "chain", chain, "error", err)
+			recordPartial(ctx, PartialPrices)
+		} else {
+			token.TokenPrice = strPtr(price)
+			if token.Balance != nil {
+				token.BalanceUsd = strPtr(multiplyDecimal(*token.Balance, price))
+			}
+		}
+	}
+	return *token, nil
+}
+
+// 辅助函数：用链的元数据补全原生币的名称、符号和小数位数
+func normalizeNative(token api.Token, chain string) api.Token {
+	info, ok := GetChain(chain)
+	if !ok {
+		return token
+	}
+	nativeType := api.NATIVE
+	token.Type = &nativeType
+	token.Chain = strPtr(chain)
+	if token.Name == "" {
+		token.Name = info.NativeName
+	}
+	if token.Symbol == "" {
+		token.Symbol = info.NativeSymbol
+	}
+	if token.Decimals == nil {
+		decimals := info.NativeDecimals
+		token.Decimals = &decimals
+	}
+	return token
+}
+
+// 辅助函数：判断十进制余额是否为0，无法解析时按0处理
+func isZeroAmount(balance *string) bool {
+	if balance == nil {
+		return true
+	}
+	value, ok := new(big.Rat).SetString(*balance)
+	return !ok || value.Sign() == 0
+}
+
+// 辅助函数：计算两个十进制字符串的乘积，保留 2 位小数
+func multiplyDecimal(a string, b string) string {
+	x, ok := new(big.Rat).SetString(a)
+	if !ok {
+		return "0"
+	}
+	y, ok := new(big.Rat).SetString(b)
+	if !ok {
+		return "0"
+	}
+	return new(big.Rat).Mul(x, y).FloatString(2)
+}
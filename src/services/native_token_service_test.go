@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+)
+
+// 固定返回 tokens 的余额服务，用作上游余额和原生币余额来源
+type staticTokens struct {
+	tokens []api.Token
+	// errChains 查询这些链时返回错误
+	errChains map[string]bool
+}
+
+func (s staticTokens) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	result := make([]api.Token, 0, len(s.tokens))
+	for _, chain := range chains {
+		if s.errChains[chain] {
+			return nil, "", errors.New("rpc unavailable")
+		}
+	}
+	for _, token := range s.tokens {
+		for _, chain := range chains {
+			if token.Chain != nil && *token.Chain == chain {
+				result = append(result, token)
+			}
+		}
+	}
+	return result, "next", nil
+}
+
+func (s staticTokens) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return s.GetTokens(ctx, address, chains, true, pageToken, pageSize)
+}
+
+// 原生币价格固定为 2000，failing 为 true 时查询失败
+type nativePrices struct {
+	failing bool
+}
+
+func (p nativePrices) GetTokenPrice(ctx context.Context, chain string, contractAddress string) (string, error) {
+	if p.failing {
+		return "", errors.New("price unavailable")
+	}
+	return "2000", nil
+}
+
+func (p nativePrices) GetTokenPriceAt(ctx context.Context, chain string, contractAddress string, at time.Time) (string, error) {
+	return p.GetTokenPrice(ctx, chain, contractAddress)
+}
+
+func nativeToken(chain string, balance string) api.Token {
+	nativeType := api.NATIVE
+	return api.Token{Chain: strPtr(chain), Type: &nativeType, Balance: strPtr(balance)}
+}
+
+func erc20Token(chain string, symbol string) api.Token {
+	return api.Token{Chain: strPtr(chain), Symbol: symbol, Balance: strPtr("1")}
+}
+
+func TestNativeTokenService(t *testing.T) {
+	upstream := staticTokens{tokens: []api.Token{
+		erc20Token("eth", "USDC"),
+		nativeToken("base", "0.5"),
+		erc20Token("base", "DEGEN"),
+	}}
+	natives := staticTokens{tokens: []api.Token{nativeToken("eth", "1.5"), nativeToken("polygon", "0")}}
+
+	tests := []struct {
+		name          string
+		natives       staticTokens
+		prices        nativePrices
+		alwaysInclude bool
+		includeZero   bool
+		pageToken     string
+		chains        []string
+		want          []string
+		wantPartial   string
+	}{
+		{
+			name:   "natives first in chain order",
+			chains: []string{"eth", "base"},
+			want:   []string{"ETH 1.5 3000.00", "ETH 0.5 -", "USDC", "DEGEN"},
+		},
+		{
+			name:      "later pages drop natives",
+			chains:    []string{"eth", "base"},
+			pageToken: "page2",
+			want:      []string{"USDC", "DEGEN"},
+		},
+		{
+			name:   "zero native balance is skipped",
+			chains: []string{"polygon", "base"},
+			want:   []string{"ETH 0.5 -", "DEGEN"},
+		},
+		{
+			name:          "zero native balance with alwaysInclude",
+			chains:        []string{"polygon", "base"},
+			alwaysInclude: true,
+			want:          []string{"POL 0 0.00", "ETH 0.5 -", "DEGEN"},
+		},
+		{
+			name:        "zero native balance with includeZeroBalance",
+			chains:      []string{"polygon"},
+			includeZero: true,
+			want:        []string{"POL 0 0.00"},
+		},
+		{
+			name:        "failed native balance marks the result partial",
+			chains:      []string{"eth", "base"},
+			natives:     staticTokens{errChains: map[string]bool{"eth": true}},
+			want:        []string{"ETH 0.5 -", "USDC", "DEGEN"},
+			wantPartial: PartialNativeBalance,
+		},
+		{
+			name:        "failed native price marks the result partial",
+			chains:      []string{"eth"},
+			prices:      nativePrices{failing: true},
+			want:        []string{"ETH 1.5 -", "USDC"},
+			wantPartial: PartialPrices,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := natives
+			if tt.natives.tokens != nil || tt.natives.errChains != nil {
+				source = tt.natives
+			}
+			service := NewNativeTokenService(upstream, source, tt.prices, tt.alwaysInclude)
+
+			ctx, recorder := WithSource(context.Background())
+			tokens, next, err := service.GetTokens(ctx, "0xabc", tt.chains, tt.includeZero, tt.pageToken, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next != "next" {
+				t.Fatalf("next = %q, want the upstream cursor", next)
+			}
+
+			got := make([]string, len(tokens))
+			for i, token := range tokens {
+				got[i] = token.Symbol
+				if token.Type != nil && *token.Type == api.NATIVE {
+					usd := "-"
+					if token.BalanceUsd != nil {
+						usd = *token.BalanceUsd
+					}
+					got[i] += " " + *token.Balance + " " + usd
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("tokens = %v, want %v", got, tt.want)
+			}
+			if partial := recorder.Partial(); partial != tt.wantPartial {
+				t.Fatalf("partial = %q, want %q", partial, tt.wantPartial)
+			}
+		})
+	}
+}
@@ -1,13 +1,9 @@
 package services
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"time"
 
 	"github.com/web3-smart-wallet/src/api"
 )
@@ -17,24 +13,19 @@ type NFTService struct {
 }
 
 type NFTServiceInterface interface {
-	GetNFTs(address string, includeMetadata bool, pageToken string) ([]api.NFT, string, error)
+	GetNFTs(ctx context.Context, address string, chains []string, includeMetadata bool, pageToken string) ([]api.NFT, string, error)
 }
 
-func NewNFTService() NFTServiceInterface {
-	apiURL := os.Getenv("ANKR_API_URL")
-	if apiURL == "" {
-		panic("ANKR_API_URL environment variable is not set")
-	}
-
+func NewNFTService(apiURL string) NFTServiceInterface {
 	return &NFTService{
 		apiURL: apiURL,
 	}
 }
 
-func (s *NFTService) GetNFTs(address string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+func (s *NFTService) GetNFTs(ctx context.Context, address string, chains []string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
 	// 构建请求体
 	params := map[string]interface{}{
-		"blockchain":      "base",
+		"blockchain":      chains,
 		"walletAddress":   address,
 		"includeMetadata": includeMetadata,
 	}
@@ -51,49 +42,6 @@ func (s *NFTService) GetNFTs(address string, includeMetadata bool, pageToken str
 		"id":      1,
 	}
 
-	// 发送请求
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to marshal request: %v", err)
-	}
-
-	// fmt.Printf("Sending request to %s with payload: %s\n", s.apiURL, string(jsonData))
-
-	client := &http.Client{
-		Timeout: 30 * time.Second, // 设置超时时间
-	}
-	req, err := http.NewRequest("POST", s.apiURL, bytes.NewBuffer(jsonData))
-	fmt.Printf("%s\n", s.apiURL)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch NFTs: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 读取响应体
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("Error reading response body: %v\n", err)
-		return nil, "", fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	// 只在非200响应时打印详细信息
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("=== Non-200 Response ===\n")
-		fmt.Printf("Status Code: %d\n", resp.StatusCode)
-		fmt.Printf("Response Headers:\n")
-		for key, values := range resp.Header {
-			fmt.Printf("  %s: %v\n", key, values)
-		}
-		fmt.Printf("Raw Response Body:\n%s\n", string(respBody))
-		fmt.Println("=== End Response ===")
-	}
-
 	// 解析响应
 	var response struct {
 		Jsonrpc string `json:"jsonrpc"`
@@ -119,18 +67,10 @@ func (s *NFTService) GetNFTs(address string, includeMetadata bool, pageToken str
 			} `json:"assets"`
 			SyncStatus interface{} `json:"syncStatus"`
 		} `json:"result"`
-		Error *struct {
-			Code    int    `json:"code"`
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-
-	if err := json.Unmarshal(respBody, &response); err != nil {
-		return nil, "", fmt.Errorf("failed to decode response: %v", err)
 	}
 
-	if response.Error != nil && response.Error.Message != "" {
-		return nil, "", fmt.Errorf("ankr api error: %s", response.Error.Message)
+	if err := postJSON(ctx, s.apiURL, nil, payload, &response); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch NFTs: %w", err)
 	}
 
 	// 处理NFT数据
@@ -141,8 +81,6 @@ func (s *NFTService) GetNFTs(address string, includeMetadata bool, pageToken str
 			continue
 		}
 
-		// fmt.Printf("Found NFT: %s (Type: %s, TokenId: %s)\n", asset.Name, asset.ContractType, asset.TokenId)
-
 		// 处理traits
 		attributes := make([]api.Attribute, 0)
 		for _, trait := range asset.Traits {
@@ -189,11 +127,11 @@ func (s *NFTService) GetNFTs(address string, includeMetadata bool, pageToken str
 			Attributes:      &nftTraits,
 			Collection:      strPtr(asset.CollectionName),
 			TokenUri:        strPtr(asset.TokenUrl),
+			Chain:           strPtr(asset.Blockchain),
 		}
 
 		nfts = append(nfts, nft)
 	}
 
-	// fmt.Printf("Returning %d NFTs for address %s\n", len(nfts), address)
 	return nfts, response.Result.NextPageToken, nil
 }
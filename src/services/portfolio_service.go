@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/logging"
+)
+
+// 汇总时每页查询的代币数量和最多翻页数
+const (
+	portfolioPageSize = 100
+	portfolioMaxPages = 50
+)
+
+// 计算 24 小时变化时只查询价值最高的持仓的历史价格，并限制并发查询数
+const (
+	portfolioChangeHoldings   = 20
+	portfolioPriceConcurrency = 8
+)
+
+type PortfolioServiceInterface interface {
+	GetPortfolio(ctx context.Context, address string, chains []string, top int) (*api.Portfolio, error)
+}
+
+// PortfolioService 在服务端遍历全部余额分页，汇总资产价值
+// 所有金额使用 big.Rat 精确计算，输出时保留 2 位小数
+type PortfolioService struct {
+	tokens AnkrServiceInterface
+	prices PriceServiceInterface
+}
+
+// NewPortfolioService 创建资产汇总服务，prices 为 nil 时不计算 24 小时变化
+func NewPortfolioService(tokens AnkrServiceInterface, prices PriceServiceInterface) PortfolioServiceInterface {
+	return &PortfolioService{
+		tokens: tokens,
+		prices: prices,
+	}
+}
+
+// 持仓及其美元价值
+type holding struct {
+	token api.Token
+	value *big.Rat
+}
+
+func (s *PortfolioService) GetPortfolio(ctx context.Context, address string, chains []string, top int) (*api.Portfolio, error) {
+	// 遍历所有分页，超过 portfolioMaxPages 时只汇总已获取的分页并标记为不完整
+	holdings := make([]holding, 0)
+	pageToken := ""
+	truncated := false
+	for page := 0; ; page++ {
+		if page >= portfolioMaxPages {
+			logging.FromContext(ctx).Warn("portfolio truncated", "pages", portfolioMaxPages)
+			truncated = true
+			break
+		}
+		tokens, nextPageToken, err := s.tokens.GetTokens(ctx, address, chains, false, pageToken, portfolioPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, token := range tokens {
+			if isZeroAmount(token.Balance) {
+				continue
+			}
+			holdings = append(holdings, holding{token: token, value: tokenValue(token)})
+		}
+		if nextPageToken == "" {
+			break
+		}
+		pageToken = nextPageToken
+	}
+
+	// 总价值和各链小计
+	total := new(big.Rat)
+	chainTotals := make(map[string]*big.Rat)
+	for _, chain := range chains {
+		chainTotals[chain] = new(big.Rat)
+	}
+	for _, h := range holdings {
+		if h.value == nil {
+			continue
+		}
+		total.Add(total, h.value)
+		if h.token.Chain != nil {
+			if _, ok := chainTotals[*h.token.Chain]; !ok {
+				chainTotals[*h.token.Chain] = new(big.Rat)
+			}
+			chainTotals[*h.token.Chain].Add(chainTotals[*h.token.Chain], h.value)
+		}
+	}
+
+	chainValues := make([]api.PortfolioChain, 0, len(chainTotals))
+	for chain := range chainTotals {
+		chainValues = append(chainValues, api.PortfolioChain{Chain: chain})
+	}
+	sort.Slice(chainValues, func(i, j int) bool {
+		if c := chainTotals[chainValues[i].Chain].Cmp(chainTotals[chainValues[j].Chain]); c != 0 {
+			return c > 0
+		}
+		return chainValues[i].Chain < chainValues[j].Chain
+	})
+	for i := range chainValues {
+		chainValues[i].TotalUsd = chainTotals[chainValues[i].Chain].FloatString(2)
+	}
+
+	// 按价值排序取前 top 个持仓，没有价格的排在最后
+	sorted := make([]holding, len(holdings))
+	copy(sorted, holdings)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].value == nil || sorted[j].value == nil {
+			return sorted[j].value == nil && sorted[i].value != nil
+		}
+		return sorted[i].value.Cmp(sorted[j].value) > 0
+	})
+	if top > len(sorted) {
+		top = len(sorted)
+	}
+	topHoldings := make([]api.Token, top)
+	for i := 0; i < top; i++ {
+		topHoldings[i] = sorted[i].token
+	}
+
+	return &api.Portfolio{
+		Address:     address,
+		TotalUsd:    total.FloatString(2),
+		TokenCount:  len(holdings),
+		Chains:      chainValues,
+		TopHoldings: topHoldings,
+		Change24h:   s.change24h(ctx, sorted[:min(len(sorted), portfolioChangeHoldings)]),
+		Truncated:   truncated,
+	}, nil
+}
+
+// 按 24 小时前的价格计算持仓的价值变化，holdings 为按价值排序后价值最高的持仓
+// 查询不到历史价格的代币不计入，全部查询失败时返回 nil
+func (s *PortfolioService) change24h(ctx context.Context, holdings []holding) *api.PortfolioChange {
+	if s.prices == nil {
+		return nil
+	}
+
+	at := time.Now().Add(-24 * time.Hour)
+	current := new(big.Rat)
+	previous := new(big.Rat)
+	covered := 0
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, portfolioPriceConcurrency)
+	for _, h := range holdings {
+		if h.value == nil || h.token.Chain == nil {
+			continue
+		}
+		balance, ok := new(big.Rat).SetString(*h.token.Balance)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(h holding, balance *big.Rat) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			contract := h.token.Address
+			if h.token.Type != nil && *h.token.Type == api.NATIVE {
+				contract = ""
+			}
+			price, err := s.prices.GetTokenPriceAt(ctx, *h.token.Chain, contract, at)
+			if err != nil {
+				logging.FromContext(ctx).Warn("failed to fetch 24h price", "symbol", h.token.Symbol, "chain", *h.token.Chain, "error", err)
+				return
+			}
+			pastPrice, ok := new(big.Rat).SetString(price)
+			if !ok {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			current.Add(current, h.value)
+			previous.Add(previous, new(big.Rat).Mul(balance, pastPrice))
+			covered++
+		}(h, balance)
+	}
+	wg.Wait()
+
+	if covered == 0 {
+		return nil
+	}
+
+	change := new(big.Rat).Sub(current, previous)
+	percent := new(big.Rat)
+	if previous.Sign() != 0 {
+		percent.Quo(change, previous)
+		percent.Mul(percent, big.NewRat(100, 1))
+	}
+	return &api.PortfolioChange{
+		Usd:     change.FloatString(2),
+		Percent: percent.FloatString(2),
+	}
+}
+
+// 辅助函数：计算持仓的美元价值，优先使用 balanceUsd，否则用余额乘以单价，没有价格时返回 nil
+func tokenValue(token api.Token) *big.Rat {
+	if token.BalanceUsd != nil && *token.BalanceUsd != "" {
+		if value, ok := new(big.Rat).SetString(*token.BalanceUsd); ok {
+			return value
+		}
+	}
+	if token.Balance == nil || token.TokenPrice == nil || *token.TokenPrice == "" {
+		return nil
+	}
+	balance, ok := new(big.Rat).SetString(*token.Balance)
+	if !ok {
+		return nil
+	}
+	price, ok := new(big.Rat).SetString(*token.TokenPrice)
+	if !ok {
+		return nil
+	}
+	return balance.Mul(balance, price)
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/services/fakes"
+)
+
+// 按页返回代币的余额服务，endless 为 true 时每次都返回下一页
+type pagedTokens struct {
+	pages   [][]api.Token
+	endless bool
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *pagedTokens) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+
+	page := 0
+	if pageToken != "" {
+		var err error
+		if page, err = strconv.Atoi(pageToken); err != nil {
+			return nil, "", errors.New("unknown page token " + pageToken)
+		}
+	}
+	if s.endless {
+		return []api.Token{pricedToken("eth", fmt.Sprintf("T%d", page), "1", "1")}, strconv.Itoa(page + 1), nil
+	}
+	next := ""
+	if page+1 < len(s.pages) {
+		next = strconv.Itoa(page + 1)
+	}
+	return s.pages[page], next, nil
+}
+
+func (s *pagedTokens) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return s.GetTokens(ctx, address, chains, true, pageToken, pageSize)
+}
+
+// 以合约地址为键的历史价格，没有价格的代币查询失败；记录被查询的合约
+type historyPrices struct {
+	prices map[string]string
+
+	mu      sync.Mutex
+	queried []string
+}
+
+func (p *historyPrices) GetTokenPrice(ctx context.Context, chain string, contractAddress string) (string, error) {
+	return p.GetTokenPriceAt(ctx, chain, contractAddress, time.Now())
+}
+
+func (p *historyPrices) GetTokenPriceAt(ctx context.Context, chain string, contractAddress string, at time.Time) (string, error) {
+	p.mu.Lock()
+	p.queried = append(p.queried, contractAddress)
+	p.mu.Unlock()
+
+	price, ok := p.prices[contractAddress]
+	if !ok {
+		return "", errors.New("no price history")
+	}
+	return price, nil
+}
+
+// 辅助函数：symbol 同时用作合约地址，balanceUsd 为空时没有价格
+func pricedToken(chain string, symbol string, balance string, balanceUsd string) api.Token {
+	token := api.Token{Chain: strPtr(chain), Symbol: symbol, Address: symbol, Balance: strPtr(balance)}
+	if balanceUsd != "" {
+		token.BalanceUsd = strPtr(balanceUsd)
+	}
+	return token
+}
+
+func TestPortfolioService(t *testing.T) {
+	// 只有单价的代币按余额乘以单价计算价值
+	byPrice := api.Token{Chain: strPtr("base"), Symbol: "DEGEN", Address: "DEGEN", Balance: strPtr("1000"), TokenPrice: strPtr("0.0032")}
+
+	tests := []struct {
+		name       string
+		pages      [][]api.Token
+		chains     []string
+		top        int
+		wantTotal  string
+		wantCount  int
+		wantChains []string // "chain totalUsd"
+		wantTop    []string
+	}{
+		{
+			// 0.1 + 0.2 + 0.705 用浮点数相加约为 1.00499999，精确计算为 1.005，四舍五入为 1.01
+			name: "exact decimal total",
+			pages: [][]api.Token{
+				{pricedToken("eth", "A", "1", "0.1"), pricedToken("eth", "B", "1", "0.2")},
+				{pricedToken("eth", "C", "1", "0.705")},
+			},
+			chains:     []string{"eth"},
+			top:        5,
+			wantTotal:  "1.01",
+			wantCount:  3,
+			wantChains: []string{"eth 1.01"},
+			wantTop:    []string{"C", "B", "A"},
+		},
+		{
+			// 没有持仓的链小计为 0，各链按价值降序、价值相同时按名称排列
+			name: "per-chain subtotals",
+			pages: [][]api.Token{
+				{pricedToken("eth", "ETH", "1", "3000"), byPrice, pricedToken("polygon", "POL", "10", "3.2")},
+			},
+			chains:     []string{"polygon", "eth", "base", "arbitrum"},
+			top:        2,
+			wantTotal:  "3006.40",
+			wantCount:  3,
+			wantChains: []string{"eth 3000.00", "base 3.20", "polygon 3.20", "arbitrum 0.00"},
+			wantTop:    []string{"ETH", "DEGEN"},
+		},
+		{
+			// 零余额不计入，没有价格的持仓计入数量但排在最后，top 超过持仓数时返回全部持仓
+			name: "unpriced and zero holdings",
+			pages: [][]api.Token{
+				{pricedToken("eth", "NOPRICE", "5", ""), pricedToken("eth", "ZERO", "0", "0"), pricedToken("eth", "USDC", "10", "10")},
+				{pricedToken("eth", "DAI", "20", "20")},
+			},
+			chains:     []string{"eth"},
+			top:        10,
+			wantTotal:  "30.00",
+			wantCount:  3,
+			wantChains: []string{"eth 30.00"},
+			wantTop:    []string{"DAI", "USDC", "NOPRICE"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewPortfolioService(&pagedTokens{pages: tt.pages}, nil)
+			portfolio, err := service.GetPortfolio(context.Background(), fakes.Wallet, tt.chains, tt.top)
+			if err != nil {
+				t.Fatalf("GetPortfolio() error = %v", err)
+			}
+			if portfolio.TotalUsd != tt.wantTotal || portfolio.TokenCount != tt.wantCount {
+				t.Errorf("total = %s, count = %d, want %s, %d", portfolio.TotalUsd, portfolio.TokenCount, tt.wantTotal, tt.wantCount)
+			}
+			chains := make([]string, len(portfolio.Chains))
+			for i, chain := range portfolio.Chains {
+				chains[i] = chain.Chain + " " + chain.TotalUsd
+			}
+			if fmt.Sprint(chains) != fmt.Sprint(tt.wantChains) {
+				t.Errorf("chains = %v, want %v", chains, tt.wantChains)
+			}
+			if got := symbols(portfolio.TopHoldings); fmt.Sprint(got) != fmt.Sprint(tt.wantTop) {
+				t.Errorf("top holdings = %v, want %v", got, tt.wantTop)
+			}
+			if portfolio.Change24h != nil || portfolio.Truncated {
+				t.Errorf("change24h = %+v, truncated = %v, want none", portfolio.Change24h, portfolio.Truncated)
+			}
+		})
+	}
+}
+
+func TestPortfolioServiceChange24h(t *testing.T) {
+	// 价值为 1..25 的持仓，只有价值最高的 20 个查询历史价格
+	tokens := make([]api.Token, 0, 25)
+	prices := make(map[string]string)
+	for i := 1; i <= 25; i++ {
+		symbol := fmt.Sprintf("T%02d", i)
+		tokens = append(tokens, pricedToken("eth", symbol, "1", strconv.Itoa(i)))
+		prices[symbol] = "1"
+	}
+	// 没有价格的持仓不查询历史价格
+	tokens = append(tokens, pricedToken("eth", "NOPRICE", "1", ""))
+
+	tests := []struct {
+		name   string
+		prices map[string]string
+		want   *api.PortfolioChange
+	}{
+		{
+			// 当前价值 6+...+25 = 310，24 小时前每个持仓价值 1，共 20
+			name:   "top 20 holdings",
+			prices: prices,
+			want:   &api.PortfolioChange{Usd: "290.00", Percent: "1450.00"},
+		},
+		{
+			// 查询不到历史价格的持仓不计入：只剩 T25，当前 25，24 小时前 1
+			name:   "missing history",
+			prices: map[string]string{"T25": "1"},
+			want:   &api.PortfolioChange{Usd: "24.00", Percent: "2400.00"},
+		},
+		{
+			name:   "no history",
+			prices: map[string]string{},
+			want:   nil,
+		},
+	}
+
+	wantQueried := make([]string, 0, 20)
+	for i := 6; i <= 25; i++ {
+		wantQueried = append(wantQueried, fmt.Sprintf("T%02d", i))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := &historyPrices{prices: tt.prices}
+			service := NewPortfolioService(&pagedTokens{pages: [][]api.Token{tokens}}, history)
+			portfolio, err := service.GetPortfolio(context.Background(), fakes.Wallet, []string{"eth"}, 3)
+			if err != nil {
+				t.Fatalf("GetPortfolio() error = %v", err)
+			}
+			if portfolio.TotalUsd != "325.00" {
+				t.Errorf("total = %s, want 325.00", portfolio.TotalUsd)
+			}
+
+			queried := append([]string(nil), history.queried...)
+			sort.Strings(queried)
+			if fmt.Sprint(queried) != fmt.Sprint(wantQueried) {
+				t.Errorf("queried = %v, want %v", queried, wantQueried)
+			}
+			switch {
+			case tt.want == nil && portfolio.Change24h != nil:
+				t.Errorf("change24h = %+v, want none", portfolio.Change24h)
+			case tt.want != nil && (portfolio.Change24h == nil || *portfolio.Change24h != *tt.want):
+				t.Errorf("change24h = %+v, want %+v", portfolio.Change24h, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortfolioServiceTruncated(t *testing.T) {
+	tokens := &pagedTokens{endless: true}
+	service := NewPortfolioService(tokens, nil)
+	portfolio, err := service.GetPortfolio(context.Background(), fakes.Wallet, []string{"eth"}, 1)
+	if err != nil {
+		t.Fatalf("GetPortfolio() error = %v", err)
+	}
+	if !portfolio.Truncated || tokens.calls != portfolioMaxPages {
+		t.Errorf("truncated = %v after %d pages, want true after %d", portfolio.Truncated, tokens.calls, portfolioMaxPages)
+	}
+	if portfolio.TokenCount != portfolioMaxPages || portfolio.TotalUsd != strconv.Itoa(portfolioMaxPages)+".00" {
+		t.Errorf("count = %d, total = %s, want the %d fetched pages", portfolio.TokenCount, portfolio.TotalUsd, portfolioMaxPages)
+	}
+}
+
+func TestPortfolioServiceWithFakes(t *testing.T) {
+	ankr := fakes.NewAnkrServer()
+	defer ankr.Close()
+
+	service := NewPortfolioService(NewAnkrService(ankr.URL), NewAnkrPriceService(ankr.URL))
+	portfolio, err := service.GetPortfolio(context.Background(), fakes.Wallet, []string{"base", "eth"}, 2)
+	if err != nil {
+		t.Fatalf("GetPortfolio() error = %v", err)
+	}
+
+	// 1500 + 5.1098800936437622513 + 3.2，跨越两页
+	if portfolio.TotalUsd != "1508.31" || portfolio.TokenCount != 3 || portfolio.Truncated {
+		t.Errorf("total = %s, count = %d, truncated = %v, want 1508.31, 3, false", portfolio.TotalUsd, portfolio.TokenCount, portfolio.Truncated)
+	}
+	if len(portfolio.Chains) != 2 || portfolio.Chains[0].Chain != "base" || portfolio.Chains[0].TotalUsd != "1508.31" || portfolio.Chains[1].TotalUsd != "0.00" {
+		t.Errorf("chains = %+v, want base 1508.31 and eth 0.00", portfolio.Chains)
+	}
+	if got := symbols(portfolio.TopHoldings); fmt.Sprint(got) != "[ETH USDC]" {
+		t.Errorf("top holdings = %v, want [ETH USDC]", got)
+	}
+	// 24 小时前 ETH 为 2900，其他代币为 1：之前价值 1450 + 5.10942 + 1000
+	want := api.PortfolioChange{Usd: "-946.80", Percent: "-38.56"}
+	if portfolio.Change24h == nil || *portfolio.Change24h != want {
+		t.Errorf("change24h = %+v, want %+v", portfolio.Change24h, want)
+	}
+}
+
+func TestPortfolioServiceError(t *testing.T) {
+	service := NewPortfolioService(staticTokens{errChains: map[string]bool{"eth": true}}, nil)
+	if _, err := service.GetPortfolio(context.Background(), fakes.Wallet, []string{"eth"}, 1); err == nil {
+		t.Error("GetPortfolio() should fail when a balance page fails")
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PriceServiceInterface 查询代币的美元价格
+type PriceServiceInterface interface {
+	// GetTokenPrice 返回代币的美元价格，contractAddress 为空表示该链的原生币
+	GetTokenPrice(ctx context.Context, chain string, contractAddress string) (string, error)
+	// GetTokenPriceAt 返回代币在指定时间点（或之前最近一次报价）的美元价格
+	GetTokenPriceAt(ctx context.Context, chain string, contractAddress string, at time.Time) (string, error)
+}
+
+// AnkrPriceService 通过 ankr_getTokenPrice 和 ankr_getTokenPriceHistory 查询价格
+type AnkrPriceService struct {
+	apiURL string
+}
+
+func NewAnkrPriceService(apiURL string) PriceServiceInterface {
+	return &AnkrPriceService{
+		apiURL: apiURL,
+	}
+}
+
+func (s *AnkrPriceService) GetTokenPrice(ctx context.Context, chain string, contractAddress string) (string, error) {
+	params := map[string]interface{}{
+		"blockchain": chain,
+	}
+	if contractAddress != "" {
+		params["contractAddress"] = contractAddress
+	}
+
+	payload := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "ankr_getTokenPrice",
+		"params":  params,
+		"id":      1,
+	}
+
+	var response struct {
+		Result struct {
+			UsdPrice string `json:"usdPrice"`
+		} `json:"result"`
+	}
+	if err := postJSON(ctx, s.apiURL, nil, payload, &response); err != nil {
+		return "", fmt.Errorf("ankr api error: %w", err)
+	}
+	return response.Result.UsdPrice, nil
+}
+
+func (s *AnkrPriceService) GetTokenPriceAt(ctx context.Context, chain string, contractAddress string, at time.Time) (string, error) {
+	// 取目标时间前一小时内的报价
+	params := map[string]interface{}{
+		"blockchain":    chain,
+		"fromTimestamp": at.Add(-time.Hour).Unix(),
+		"toTimestamp":   at.Unix(),
+		"limit":         10,
+	}
+	if contractAddress != "" {
+		params["contractAddress"] = contractAddress
+	}
+
+	payload := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "ankr_getTokenPriceHistory",
+		"params":  params,
+		"id":      1,
+	}
+
+	var response struct {
+		Result struct {
+			Quotes []struct {
+				Timestamp int64  `json:"timestamp"`
+				UsdPrice  string `json:"usdPrice"`
+			} `json:"quotes"`
+		} `json:"result"`
+	}
+	if err := postJSON(ctx, s.apiURL, nil, payload, &response); err != nil {
+		return "", fmt.Errorf("ankr api error: %w", err)
+	}
+
+	// 选择不晚于目标时间的最近一次报价
+	price := ""
+	var latest int64
+	for _, quote := range response.Result.Quotes {
+		if quote.Timestamp <= at.Unix() && quote.Timestamp >= latest && quote.UsdPrice != "" {
+			latest = quote.Timestamp
+			price = quote.UsdPrice
+		}
+	}
+	if price == "" {
+		return "", fmt.Errorf("no price history for %s on %s", contractAddress, chain)
+	}
+	return price, nil
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/web3-smart-wallet/src/api"
+)
+
+// Provider 是链上数据提供方的抽象
+// 每个实现负责调用各自的接口，并把响应统一转换为 api.Token 和 api.NFT
+type Provider interface {
+	AnkrServiceInterface
+	NFTServiceInterface
+	// Name 返回提供方名称，如 "ankr"、"alchemy"
+	Name() string
+}
+
+// AnkrProvider 将现有的 AnkrService 和 NFTService 组合为 Provider
+type AnkrProvider struct {
+	AnkrServiceInterface
+	NFTServiceInterface
+}
+
+func NewAnkrProvider(ankrService AnkrServiceInterface, nftService NFTServiceInterface) Provider {
+	return &AnkrProvider{
+		AnkrServiceInterface: ankrService,
+		NFTServiceInterface:  nftService,
+	}
+}
+
+func (p *AnkrProvider) Name() string {
+	return "ankr"
+}
+
+// ProviderRouter 按链选择提供方，同一请求中的多条链可以由不同的提供方处理
+// 每条链可以配置多个提供方，按顺序故障转移
+type ProviderRouter struct {
+	targets        map[string]Provider
+	defaultTarget  string
+	chainProviders map[string]string
+}
+
+// NewProviderRouter 创建按链路由的提供方
+// providers: 以名称为键的可用提供方
+// defaultProviders: 未单独配置的链使用的提供方列表，第一个为主提供方
+// chainProviders: 链标识到提供方列表的映射
+// options: 提供方列表的故障转移参数
+func NewProviderRouter(providers map[string]Provider, defaultProviders []string, chainProviders map[string][]string, options FailoverOptions) (*ProviderRouter, error) {
+	r := &ProviderRouter{
+		targets:        make(map[string]Provider),
+		chainProviders: make(map[string]string),
+	}
+
+	// 每个不同的提供方列表对应一个故障转移提供方
+	target := func(names []string) (string, error) {
+		if len(names) == 0 {
+			return "", fmt.Errorf("empty provider list")
+		}
+		key := strings.Join(names, "|")
+		if _, ok := r.targets[key]; ok {
+			return key, nil
+		}
+		list := make([]Provider, len(names))
+		for i, name := range names {
+			provider, ok := providers[name]
+			if !ok {
+				return "", fmt.Errorf("provider %q is not configured", name)
+			}
+			list[i] = provider
+		}
+		r.targets[key] = NewFailoverProvider(list, options)
+		return key, nil
+	}
+
+	key, err := target(defaultProviders)
+	if err != nil {
+		return nil, fmt.Errorf("invalid default providers: %v", err)
+	}
+	r.defaultTarget = key
+
+	for chain, names := range chainProviders {
+		if _, ok := GetChain(chain); !ok {
+			return nil, fmt.Errorf("unsupported chain %q in provider mapping", chain)
+		}
+		key, err := target(names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid providers for chain %q: %v", chain, err)
+		}
+		r.chainProviders[chain] = key
+	}
+
+	return r, nil
+}
+
+// ParseProviderList 解析以 "|" 分隔的提供方列表，例如 "ankr|alchemy" 表示 Ankr 为主、Alchemy 为备
+func ParseProviderList(value string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(value, "|") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ParseProviderChains 解析形如 "eth=alchemy|ankr,polygon=moralis" 的链与提供方映射
+func ParseProviderChains(value string) (map[string][]string, error) {
+	mapping := make(map[string][]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		chain, names, ok := strings.Cut(pair, "=")
+		providers := ParseProviderList(names)
+		if !ok || strings.TrimSpace(chain) == "" || len(providers) == 0 {
+			return nil, fmt.Errorf("invalid provider mapping %q, expected chain=provider[|fallback...]", pair)
+		}
+		mapping[strings.ToLower(strings.TrimSpace(chain))] = providers
+	}
+	return mapping, nil
+}
+
+func (r *ProviderRouter) Name() string {
+	return "router"
+}
+
+func (r *ProviderRouter) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	groups, order := r.group(chains)
+	return fetchSegments(order, pageToken, func(name string, token string) ([]api.Token, string, error) {
+		return r.targets[name].GetTokens(ctx, address, groups[name], includeZeroBalance, token, pageSize)
+	})
+}
+
+func (r *ProviderRouter) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	groups, order := r.group(chains)
+	return fetchSegments(order, pageToken, func(name string, token string) ([]api.Token, string, error) {
+		return r.targets[name].GetTokenList(ctx, address, groups[name], token, pageSize)
+	})
+}
+
+func (r *ProviderRouter) GetNFTs(ctx context.Context, address string, chains []string, includeMetadata bool, pageToken string) ([]api.NFT, string, error) {
+	groups, order := r.group(chains)
+	return fetchSegments(order, pageToken, func(name string, token string) ([]api.NFT, string, error) {
+		return r.targets[name].GetNFTs(ctx, address, groups[name], includeMetadata, token)
+	})
+}
+
+// 按提供方对链分组，返回分组以及提供方的首次出现顺序
+func (r *ProviderRouter) group(chains []string) (map[string][]string, []string) {
+	groups := make(map[string][]string)
+	order := make([]string, 0)
+	for _, chain := range chains {
+		name, ok := r.chainProviders[chain]
+		if !ok {
+			name = r.defaultTarget
+		}
+		if _, ok := groups[name]; !ok {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], chain)
+	}
+	return groups, order
+}
+
+// fetchSegments 并发查询多个分段（提供方或链）并合并结果
+// 只有一个分段时直接透传上游的分页游标；多个分段时游标为各分段游标的 base64 JSON 编码，
+// 后续页只查询仍有数据的分段
+func fetchSegments[T any](segments []string, pageToken string, fetch func(segment string, token string) ([]T, string, error)) ([]T, string, error) {
+	if len(segments) == 1 {
+		return fetch(segments[0], pageToken)
+	}
+
+	cursors := make(map[string]string)
+	if pageToken == "" {
+		for _, segment := range segments {
+			cursors[segment] = ""
+		}
+	} else {
+		decoded, err := decodeCursor(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		cursors = decoded
+	}
+
+	active := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if _, ok := cursors[segment]; ok {
+			active = append(active, segment)
+		}
+	}
+
+	type segmentResult struct {
+		items []T
+		next  string
+		err   error
+	}
+	results := make([]segmentResult, len(active))
+
+	var wg sync.WaitGroup
+	for i, segment := range active {
+		wg.Add(1)
+		go func(i int, segment string) {
+			defer wg.Done()
+			items, next, err := fetch(segment, cursors[segment])
+			results[i] = segmentResult{items: items, next: next, err: err}
+		}(i, segment)
+	}
+	wg.Wait()
+
+	items := make([]T, 0)
+	nextCursors := make(map[string]string)
+	for i, segment := range active {
+		if results[i].err != nil {
+			return nil, "", results[i].err
+		}
+		items = append(items, results[i].items...)
+		if results[i].next != "" {
+			nextCursors[segment] = results[i].next
+		}
+	}
+
+	return items, encodeCursor(nextCursors), nil
+}
+
+// ErrInvalidPageToken 客户端传入的分页游标无法解析或不属于任何提供方
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// 辅助函数：编码多分段分页游标，没有后续页时返回空字符串
+func encodeCursor(cursors map[string]string) string {
+	if len(cursors) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(cursors)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// 辅助函数：解码多分段分页游标
+func decodeCursor(pageToken string) (map[string]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	cursors := make(map[string]string)
+	if err := json.Unmarshal(data, &cursors); err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	return cursors, nil
+}
+
+// 辅助函数：通过共用的上游客户端发送 GET 请求并解析 JSON 响应
+func getJSON(ctx context.Context, url string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return upstreamClient.doJSON(req, req.URL.Host, out)
+}
+
+// 辅助函数：通过共用的上游客户端发送 JSON POST 请求并解析 JSON 响应
+func postJSON(ctx context.Context, url string, headers map[string]string, payload interface{}, out interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return upstreamClient.doJSON(req, rpcMethod(payload, req.URL.Host), out)
+}
+
+// PostJSON 通过共用的上游客户端发送 JSON POST 请求并解析 JSON 响应，供服务层以外的上游调用（如 did:ethr 注册表查询）使用，
+// 与服务层共用重试、熔断、指标和追踪；JSON-RPC 错误返回 *UpstreamError
+func PostJSON(ctx context.Context, url string, payload interface{}, out interface{}) error {
+	return postJSON(ctx, url, nil, payload, out)
+}
+
+// 辅助函数：取 JSON-RPC 请求的方法名，批量请求为 "batch"，非 JSON-RPC 请求使用 fallback
+func rpcMethod(payload interface{}, fallback string) string {
+	switch p := payload.(type) {
+	case map[string]interface{}:
+		if method, ok := p["method"].(string); ok {
+			return method
+		}
+	case []map[string]interface{}, []interface{}:
+		return "batch"
+	}
+	return fallback
+}
+
+// rpcError JSON-RPC 错误对象
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// 各提供方 NFT 元数据中通用的 trait 结构
+type nftAttribute struct {
+	TraitType string      `json:"trait_type"`
+	Value     interface{} `json:"value"`
+}
+
+// 辅助函数：将 trait 列表转换为 api.NFTTrait，非字符串的值转换为 JSON 字符串
+func toNFTTraits(attributes []nftAttribute) []api.NFTTrait {
+	traits := make([]api.NFTTrait, len(attributes))
+	for i, attr := range attributes {
+		var value string
+		if strValue, ok := attr.Value.(string); ok {
+			value = strValue
+		} else {
+			jsonValue, _ := json.Marshal(attr.Value)
+			value = string(jsonValue)
+		}
+		traits[i] = api.NFTTrait{
+			TraitType: strPtr(attr.TraitType),
+			Value:     strPtr(value),
+		}
+	}
+	return traits
+}
+
+// 辅助函数：将字符串转换为指针
+func strPtr(s string) *string {
+	return &s
+}
+
+// 辅助函数：判断十六进制数值是否为0
+func isZeroHex(value string) bool {
+	return strings.TrimLeft(strings.TrimPrefix(value, "0x"), "0") == ""
+}
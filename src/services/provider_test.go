@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/services/fakes"
+)
+
+func TestMain(m *testing.M) {
+	// 失败的上游调用只重试一次且不等待，测试不受退避时间影响
+	ConfigureUpstreamClient(ClientOptions{
+		Timeout:             5 * time.Second,
+		MaxRetries:          1,
+		BackoffBase:         time.Millisecond,
+		BackoffMax:          10 * time.Millisecond,
+		MaxIdleConnsPerHost: 4,
+	}, nil)
+	os.Exit(m.Run())
+}
+
+// 辅助函数：读取所有分页的代币
+func allTokens(t *testing.T, ctx context.Context, p Provider, chains []string) []api.Token {
+	t.Helper()
+	var tokens []api.Token
+	pageToken := ""
+	for range 10 {
+		page, next, err := p.GetTokens(ctx, fakes.Wallet, chains, false, pageToken, 10)
+		if err != nil {
+			t.Fatalf("GetTokens: %v", err)
+		}
+		tokens = append(tokens, page...)
+		if next == "" {
+			return tokens
+		}
+		pageToken = next
+	}
+	t.Fatal("GetTokens did not finish paging")
+	return nil
+}
+
+// 辅助函数：代币的摘要 "symbol balance price usd"，缺少的字段为 "-"
+func tokenSummary(token api.Token) string {
+	value := func(s *string) string {
+		if s == nil || *s == "" {
+			return "-"
+		}
+		return *s
+	}
+	return strings.Join([]string{token.Symbol, value(token.Balance), value(token.TokenPrice), value(token.BalanceUsd)}, " ")
+}
+
+func TestProviders(t *testing.T) {
+	ankr := fakes.NewAnkrServer()
+	defer ankr.Close()
+	alchemy := fakes.NewAlchemyServer()
+	defer alchemy.Close()
+	moralis := fakes.NewMoralisServer()
+	defer moralis.Close()
+	covalent := fakes.NewCovalentServer()
+	defer covalent.Close()
+
+	tests := []struct {
+		name       string
+		provider   Provider
+		chain      string
+		wantTokens []string
+		wantNFTs   []string // "contract tokenId type"
+	}{
+		{
+			name:       "ankr",
+			provider:   NewAnkrProvider(NewAnkrService(ankr.URL), NewNFTService(ankr.URL)),
+			chain:      "base",
+			wantTokens: []string{"ETH 0.5 3000 1500", "USDC 5.10942 1.0000900481157865768 5.1098800936437622513", "DEGEN 1000 0.0032 3.2"},
+			wantNFTs:   []string{"0x2867a6dfb2c15f789c3bf0b5547ac4117850a060 1 ERC1155"},
+		},
+		{
+			name:     "alchemy",
+			provider: NewAlchemyProvider(alchemy.URL+"/{network}", fakes.APIKey),
+			chain:    "eth",
+			// 零余额的 DAI 被过滤，价格来自 Prices API
+			wantTokens: []string{"USDC 5 0.9999 5.00"},
+			wantNFTs:   []string{"0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d 42 ERC721"},
+		},
+		{
+			name:       "moralis",
+			provider:   NewMoralisProvider(moralis.URL, fakes.APIKey),
+			chain:      "polygon",
+			wantTokens: []string{"POL 2 0.5 1", "USDC 12.5 1 12.5"},
+			wantNFTs:   []string{"0x2953399124f0cbb46d2cbacd8a89cf0599974963 7 ERC1155"},
+		},
+		{
+			name:       "covalent",
+			provider:   NewCovalentProvider(covalent.URL, fakes.APIKey),
+			chain:      "arbitrum",
+			wantTokens: []string{"ETH 0.25 3000 750", "ARB 100 0.8 80"},
+			wantNFTs:   []string{"0xfae39ec09730ca0f14262a636d2d7c5539353752 1001 ERC721"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, source := WithSource(context.Background())
+			tokens := allTokens(t, ctx, tt.provider, []string{tt.chain})
+			if len(tokens) != len(tt.wantTokens) {
+				t.Fatalf("got %d tokens, want %d", len(tokens), len(tt.wantTokens))
+			}
+			for i, token := range tokens {
+				if got := tokenSummary(token); got != tt.wantTokens[i] {
+					t.Errorf("token %d = %q, want %q", i, got, tt.wantTokens[i])
+				}
+				if token.Chain == nil || *token.Chain != tt.chain {
+					t.Errorf("token %d chain = %v, want %s", i, token.Chain, tt.chain)
+				}
+			}
+			if partial := source.Partial(); partial != "" {
+				t.Errorf("Partial = %q, want complete data", partial)
+			}
+
+			nfts, next, err := tt.provider.GetNFTs(ctx, fakes.Wallet, []string{tt.chain}, true, "")
+			if err != nil {
+				t.Fatalf("GetNFTs: %v", err)
+			}
+			if next != "" || len(nfts) != len(tt.wantNFTs) {
+				t.Fatalf("GetNFTs = %d NFTs, next %q; want %d", len(nfts), next, len(tt.wantNFTs))
+			}
+			for i, nft := range nfts {
+				got := *nft.ContractAddress + " " + *nft.TokenId + " " + string(*nft.Type)
+				if got != tt.wantNFTs[i] {
+					t.Errorf("nft %d = %q, want %q", i, got, tt.wantNFTs[i])
+				}
+			}
+		})
+	}
+}
+
+// Prices API 失败时仍然返回余额，并标记缺少价格
+func TestAlchemyProviderWithoutPrices(t *testing.T) {
+	alchemy := fakes.NewAlchemyServer()
+	defer alchemy.Close()
+	target, _ := url.Parse(alchemy.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/prices/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	ctx, source := WithSource(context.Background())
+	tokens := allTokens(t, ctx, NewAlchemyProvider(server.URL+"/{network}", fakes.APIKey), []string{"eth"})
+	if len(tokens) != 1 || tokenSummary(tokens[0]) != "USDC 5 - -" {
+		t.Fatalf("tokens = %v, want USDC without price", tokens)
+	}
+	if partial := source.Partial(); partial != PartialPrices {
+		t.Fatalf("Partial = %q, want %q", partial, PartialPrices)
+	}
+}
+
+func TestProviderRejectsWrongKey(t *testing.T) {
+	moralis := fakes.NewMoralisServer()
+	defer moralis.Close()
+
+	_, _, err := NewMoralisProvider(moralis.URL, "wrong-key").GetTokens(context.Background(), fakes.Wallet, []string{"eth"}, false, "", 10)
+	var upstream *UpstreamError
+	if !errors.As(err, &upstream) || upstream.Kind != UpstreamAuthFailed {
+		t.Fatalf("error = %v, want UpstreamAuthFailed", err)
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrNonPublicAddress 用户提供的地址解析到了回环、内网、链路本地等非公网 IP
+var ErrNonPublicAddress = errors.New("address is not a public IP")
+
+// 公网 IP 判断之外额外拒绝的网段：运营商级 NAT、IETF 协议分配、基准测试、保留地址和 NAT64（可以映射到任意 IPv4 内网地址）
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// GetPublicJSON 使用共用上游客户端的超时和重试策略 GET 用户提供的地址（如 did:web 文档）并解析 JSON 响应
+// 连接建立时校验 DNS 解析后的 IP，只允许公网地址（包括重定向后的地址），防止 SSRF；
+// 响应体超过 maxBytes 时返回错误。主机由用户决定，所以不经过熔断器，method 用作指标和 span 的名称
+func GetPublicJSON(ctx context.Context, url string, method string, maxBytes int64, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	return upstreamClient.do(req, method, requestOptions{client: upstreamClient.public, maxBytes: maxBytes}, out)
+}
+
+// 辅助函数：ip 是否为可以从服务端访问的公网地址
+func isPublicIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// 辅助函数：基于共用连接池配置的 transport，拨号时拒绝非公网 IP，并且不使用代理（否则校验的是代理地址）
+func publicTransport(base *http.Transport) *http.Transport {
+	transport := base.Clone()
+	transport.Proxy = nil
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrNonPublicAddress, address)
+			}
+			if !isPublicIP(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrNonPublicAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport.DialContext = dialer.DialContext
+	return transport
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+// RPCBalanceService 通过标准以太坊 JSON-RPC 直接读取余额，不依赖索引服务
+// 原生币余额使用 eth_getBalance，ERC20 的 balanceOf/decimals/symbol/name 通过 Multicall3 批量读取，
+// 因此只能查询配置中列出的代币。可以替代 AnkrService 对接本地开发链
+type RPCBalanceService struct {
+	endpoints        map[string]string
+	tokens           map[string][]string
+	multicallAddress string
+}
+
+// NewRPCBalanceService 创建 JSON-RPC 余额服务
+// endpoints: 链标识到 RPC 节点地址的映射
+// tokens: 链标识到需要查询的 ERC20 合约地址列表
+// multicallAddress: Multicall3 合约地址，为空时使用 Multicall3DefaultAddress
+func NewRPCBalanceService(endpoints map[string]string, tokens map[string][]string, multicallAddress string) AnkrServiceInterface {
+	if multicallAddress == "" {
+		multicallAddress = Multicall3DefaultAddress
+	}
+	return &RPCBalanceService{
+		endpoints:        endpoints,
+		tokens:           tokens,
+		multicallAddress: multicallAddress,
+	}
+}
+
+// ParseRPCEndpoints 解析形如 "eth=http://localhost:8545,base=https://..." 的链与 RPC 节点映射
+func ParseRPCEndpoints(value string) (map[string]string, error) {
+	endpoints := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		chain, endpoint, ok := strings.Cut(pair, "=")
+		chain = strings.ToLower(strings.TrimSpace(chain))
+		if !ok || chain == "" || strings.TrimSpace(endpoint) == "" {
+			return nil, fmt.Errorf("invalid rpc endpoint %q, expected chain=url", pair)
+		}
+		if _, ok := GetChain(chain); !ok {
+			return nil, fmt.Errorf("unsupported chain: %s", chain)
+		}
+		endpoints[chain] = strings.TrimSpace(endpoint)
+	}
+	return endpoints, nil
+}
+
+// ParseRPCTokens 解析形如 "eth=0xA0b8...|0xdAC1...,base=0x8335..." 的链与代币合约映射
+func ParseRPCTokens(value string) (map[string][]string, error) {
+	tokens := make(map[string][]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		chain, addresses, ok := strings.Cut(pair, "=")
+		chain = strings.ToLower(strings.TrimSpace(chain))
+		if !ok || chain == "" {
+			return nil, fmt.Errorf("invalid token list %q, expected chain=address[|address...]", pair)
+		}
+		for _, address := range strings.Split(addresses, "|") {
+			address = strings.TrimSpace(address)
+			if address == "" {
+				continue
+			}
+			if _, err := abiAddress(address); err != nil {
+				return nil, err
+			}
+			tokens[chain] = append(tokens[chain], address)
+		}
+	}
+	return tokens, nil
+}
+
+func (s *RPCBalanceService) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.Token, string, error) {
+		return s.getTokens(ctx, address, chain, includeZeroBalance, token, pageSize, true)
+	})
+}
+
+func (s *RPCBalanceService) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return fetchSegments(chains, pageToken, func(chain string, token string) ([]api.Token, string, error) {
+		return s.getTokens(ctx, address, chain, false, token, pageSize, false)
+	})
+}
+
+// 查询单条链上的原生币和已配置代币的余额，在本地分页
+func (s *RPCBalanceService) getTokens(ctx context.Context, address string, chain string, includeZeroBalance bool, pageToken string, pageSize int, withBalance bool) ([]api.Token, string, error) {
+	rpcURL, ok := s.endpoints[chain]
+	if !ok {
+		return nil, "", fmt.Errorf("no rpc endpoint configured for chain %s", chain)
+	}
+	chainInfo, _ := GetChain(chain)
+	offset, err := parseOffset(pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if pageSize <= 0 {
+		pageSize = 10 // 默认每页10个
+	}
+
+	// 每个代币依次调用 balanceOf、decimals、symbol、name
+	owner, err := encodeBalanceOf(address)
+	if err != nil {
+		return nil, "", err
+	}
+	contracts := s.tokens[chain]
+	calls := make([]multicall, 0, len(contracts)*4)
+	for _, contract := range contracts {
+		calls = append(calls,
+			multicall{Target: contract, CallData: owner},
+			multicall{Target: contract, CallData: encodeSelector(decimalsSelector)},
+			multicall{Target: contract, CallData: encodeSelector(symbolSelector)},
+			multicall{Target: contract, CallData: encodeSelector(nameSelector)},
+		)
+	}
+
+	// 原生币余额和 Multicall3 调用合并为一个 JSON-RPC 批量请求
+	batch := []interface{}{
+		map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "eth_getBalance",
+			"params":  []interface{}{address, "latest"},
+			"id":      0,
+		},
+	}
+	if len(calls) > 0 {
+		callData, err := encodeAggregate3(calls)
+		if err != nil {
+			return nil, "", err
+		}
+		batch = append(batch, map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "eth_call",
+			"params": []interface{}{
+				map[string]string{
+					"to":   s.multicallAddress,
+					"data": callData,
+				},
+				"latest",
+			},
+			"id": 1,
+		})
+	}
+
+	var responses []struct {
+		ID     int       `json:"id"`
+		Result string    `json:"result"`
+		Error  *rpcError `json:"error"`
+	}
+	if err := postJSON(ctx, rpcURL, nil, batch, &responses); err != nil {
+		return nil, "", fmt.Errorf("rpc error: %w", err)
+	}
+	results := make(map[int]string)
+	for _, response := range responses {
+		if response.Error != nil && response.Error.Message != "" {
+			return nil, "", &UpstreamError{
+				Kind:    rpcErrorKind(response.Error),
+				Method:  "batch",
+				RPCCode: response.Error.Code,
+				Err:     fmt.Errorf("rpc error %d: %s", response.Error.Code, response.Error.Message),
+			}
+		}
+		results[response.ID] = response.Result
+	}
+	nativeBalance, ok := results[0]
+	if !ok {
+		return nil, "", fmt.Errorf("rpc error: missing eth_getBalance result")
+	}
+
+	tokens := make([]api.Token, 0, len(contracts)+1)
+
+	// 原生币
+	if includeZeroBalance || !isZeroHex(nativeBalance) {
+		nativeType := api.NATIVE
+		token := api.Token{
+			Address: "",
+			Name:    chainInfo.NativeName,
+			Symbol:  chainInfo.NativeSymbol,
+			Type:    &nativeType,
+			Chain:   strPtr(chain),
+		}
+		if withBalance {
+			decimals := chainInfo.NativeDecimals
+			token.Balance = strPtr(utils.FormatTokenBalance(nativeBalance, decimals))
+			token.Decimals = &decimals
+		}
+		tokens = append(tokens, token)
+	}
+
+	// ERC20 代币
+	if len(calls) > 0 {
+		multicallResults, err := decodeAggregate3(results[1])
+		if err != nil {
+			return nil, "", err
+		}
+		if len(multicallResults) != len(calls) {
+			return nil, "", fmt.Errorf("rpc error: expected %d multicall results, got %d", len(calls), len(multicallResults))
+		}
+
+		for i, contract := range contracts {
+			balanceResult := multicallResults[i*4]
+			if !balanceResult.Success {
+				// 不是有效的 ERC20 合约，跳过
+				continue
+			}
+			balance, err := decodeUint256(balanceResult.ReturnData)
+			if err != nil {
+				continue
+			}
+			if !includeZeroBalance && balance.Sign() == 0 {
+				continue
+			}
+
+			decimals := 18
+			if r := multicallResults[i*4+1]; r.Success {
+				if value, err := decodeUint256(r.ReturnData); err == nil && value.Cmp(big.NewInt(255)) <= 0 {
+					decimals = int(value.Int64())
+				}
+			}
+			var symbol, name string
+			if r := multicallResults[i*4+2]; r.Success {
+				symbol, _ = decodeABIString(r.ReturnData)
+			}
+			if r := multicallResults[i*4+3]; r.Success {
+				name, _ = decodeABIString(r.ReturnData)
+			}
+
+			tokenType := api.ERC20
+			token := api.Token{
+				Address: contract,
+				Name:    name,
+				Symbol:  symbol,
+				Type:    &tokenType,
+				Chain:   strPtr(chain),
+			}
+			if withBalance {
+				token.Balance = strPtr(utils.FormatTokenAmount(balance, decimals))
+				token.Decimals = &decimals
+			}
+			tokens = append(tokens, token)
+		}
+	}
+
+	page, next := paginate(tokens, offset, pageSize)
+	return page, next, nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/services/fakes"
+)
+
+// 假节点上没有部署合约的地址，aggregate3 中对它的调用全部失败
+const missingToken = "0x3333333333333333333333333333333333333333"
+
+func TestRPCBalanceService(t *testing.T) {
+	node := fakes.NewRPCServer()
+	defer node.Close()
+
+	service := NewRPCBalanceService(
+		map[string]string{"eth": node.URL},
+		map[string][]string{"eth": {fakes.USDCToken, fakes.MKRToken, missingToken}},
+		fakes.Multicall3,
+	)
+
+	type wantToken struct {
+		address  string
+		symbol   string
+		name     string
+		balance  string
+		decimals int
+	}
+	tests := []struct {
+		name        string
+		owner       string
+		includeZero bool
+		want        []wantToken
+	}{
+		{
+			// MKR 余额为零被过滤，调用失败的合约被跳过
+			name:  "non-zero balances",
+			owner: fakes.Wallet,
+			want: []wantToken{
+				{address: "", symbol: "ETH", name: "Ether", balance: "1.5", decimals: 18},
+				{address: fakes.USDCToken, symbol: "USDC", name: "USD Coin", balance: "1234.5", decimals: 6},
+			},
+		},
+		{
+			// MKR 的 symbol() 和 name() 返回 bytes32
+			name:        "zero balances with bytes32 metadata",
+			owner:       fakes.Wallet,
+			includeZero: true,
+			want: []wantToken{
+				{address: "", symbol: "ETH", name: "Ether", balance: "1.5", decimals: 18},
+				{address: fakes.USDCToken, symbol: "USDC", name: "USD Coin", balance: "1234.5", decimals: 6},
+				{address: fakes.MKRToken, symbol: "MKR", name: "Maker", balance: "0", decimals: 18},
+			},
+		},
+		{
+			name:  "empty wallet",
+			owner: "0x4ed4e862860bed51a9570b96d89af5e1b0efefed",
+			want:  []wantToken{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, next, err := service.GetTokens(context.Background(), tt.owner, []string{"eth"}, tt.includeZero, "", 10)
+			if err != nil {
+				t.Fatalf("GetTokens() error = %v", err)
+			}
+			if next != "" {
+				t.Errorf("GetTokens() next = %q, want none", next)
+			}
+			if len(tokens) != len(tt.want) {
+				t.Fatalf("GetTokens() returned %d tokens, want %d: %+v", len(tokens), len(tt.want), tokens)
+			}
+			for i, want := range tt.want {
+				token := tokens[i]
+				if token.Address != want.address || token.Symbol != want.symbol || token.Name != want.name {
+					t.Errorf("token %d = %s %q %q, want %s %q %q", i, token.Address, token.Symbol, token.Name, want.address, want.symbol, want.name)
+				}
+				if token.Balance == nil || *token.Balance != want.balance {
+					t.Errorf("token %d balance = %v, want %s", i, token.Balance, want.balance)
+				}
+				if token.Decimals == nil || *token.Decimals != want.decimals {
+					t.Errorf("token %d decimals = %v, want %d", i, token.Decimals, want.decimals)
+				}
+			}
+		})
+	}
+}
+
+func TestRPCBalanceServicePagination(t *testing.T) {
+	node := fakes.NewRPCServer()
+	defer node.Close()
+
+	service := NewRPCBalanceService(
+		map[string]string{"eth": node.URL},
+		map[string][]string{"eth": {fakes.USDCToken, fakes.MKRToken}},
+		fakes.Multicall3,
+	)
+
+	ctx := context.Background()
+	first, next, err := service.GetTokenList(ctx, fakes.Wallet, []string{"eth"}, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 || first[0].Type == nil || *first[0].Type != api.NATIVE || next == "" {
+		t.Fatalf("first page = %+v, next %q, want the native token and a next page", first, next)
+	}
+	// 代币列表不包含余额
+	if first[0].Balance != nil {
+		t.Errorf("GetTokenList() balance = %s, want none", *first[0].Balance)
+	}
+
+	second, next, err := service.GetTokenList(ctx, fakes.Wallet, []string{"eth"}, next, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 1 || second[0].Address != fakes.USDCToken || next != "" {
+		t.Errorf("second page = %+v, next %q, want USDC and no next page", second, next)
+	}
+}
+
+func TestRPCBalanceServiceErrors(t *testing.T) {
+	// 批量请求中 eth_call 返回错误
+	reverting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":0,"result":"0x0"},{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"execution reverted"}}]`))
+	}))
+	defer reverting.Close()
+
+	// aggregate3 返回的结果数量与调用数量不一致
+	short := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":0,"result":"0x0"},{"jsonrpc":"2.0","id":1,"result":"` + encodeResults([]multicallResult{{Success: true, ReturnData: abiUint(1)}}) + `"}]`))
+	}))
+	defer short.Close()
+
+	tokens := map[string][]string{"eth": {fakes.USDCToken}}
+	tests := []struct {
+		name      string
+		endpoints map[string]string
+		owner     string
+		upstream  bool
+	}{
+		{name: "chain without endpoint", endpoints: map[string]string{}, owner: fakes.Wallet},
+		{name: "invalid owner", endpoints: map[string]string{"eth": short.URL}, owner: "0x1234"},
+		{name: "reverted call", endpoints: map[string]string{"eth": reverting.URL}, owner: fakes.Wallet, upstream: true},
+		{name: "missing results", endpoints: map[string]string{"eth": short.URL}, owner: fakes.Wallet},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewRPCBalanceService(tt.endpoints, tokens, "")
+			_, _, err := service.GetTokens(context.Background(), tt.owner, []string{"eth"}, false, "", 10)
+			if err == nil {
+				t.Fatal("GetTokens() should fail")
+			}
+			var upstream *UpstreamError
+			if errors.As(err, &upstream) != tt.upstream {
+				t.Errorf("GetTokens() error = %v, upstream error %v, want %v", err, !tt.upstream, tt.upstream)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+type TransactionServiceInterface interface {
+	GetTransactions(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Transaction, string, error)
+}
+
+// TransactionService 通过 ankr_getTransactionsByAddress 查询钱包的交易记录
+type TransactionService struct {
+	apiURL string
+}
+
+func NewTransactionService(apiURL string) TransactionServiceInterface {
+	return &TransactionService{
+		apiURL: apiURL,
+	}
+}
+
+func (s *TransactionService) GetTransactions(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Transaction, string, error) {
+	// 构建请求体，按时间倒序返回
+	params := map[string]interface{}{
+		"blockchain": chains,
+		"address":    []string{address},
+		"descOrder":  true,
+	}
+
+	// 添加分页参数
+	if pageSize > 0 {
+		params["pageSize"] = pageSize
+	} else {
+		params["pageSize"] = 10 // 默认每页10个
+	}
+
+	if pageToken != "" {
+		params["pageToken"] = pageToken
+	}
+
+	payload := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "ankr_getTransactionsByAddress",
+		"params":  params,
+		"id":      1,
+	}
+
+	var response struct {
+		Result struct {
+			Transactions []struct {
+				Blockchain  string `json:"blockchain"`
+				Hash        string `json:"hash"`
+				From        string `json:"from"`
+				To          string `json:"to"`
+				Value       string `json:"value"`
+				GasPrice    string `json:"gasPrice"`
+				GasUsed     string `json:"gasUsed"`
+				Status      string `json:"status"`
+				BlockNumber string `json:"blockNumber"`
+				Timestamp   string `json:"timestamp"`
+			} `json:"transactions"`
+			NextPageToken string `json:"nextPageToken"`
+		} `json:"result"`
+	}
+	if err := postJSON(ctx, s.apiURL, nil, payload, &response); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+	recordSource(ctx, "ankr")
+
+	// 转换为 API 响应格式
+	transactions := make([]api.Transaction, 0, len(response.Result.Transactions))
+	for _, tx := range response.Result.Transactions {
+		decimals := 18
+		symbol := ""
+		if chain, ok := GetChain(tx.Blockchain); ok {
+			decimals = chain.NativeDecimals
+			symbol = chain.NativeSymbol
+		}
+
+		direction, counterparty := transactionDirection(address, tx.From, tx.To)
+
+		// 手续费 = gasUsed * gasPrice
+		fee := new(big.Int).Mul(parseHexBig(tx.GasUsed), parseHexBig(tx.GasPrice))
+
+		transaction := api.Transaction{
+			Hash:         tx.Hash,
+			Chain:        tx.Blockchain,
+			Direction:    direction,
+			From:         tx.From,
+			To:           strPtr(tx.To),
+			Counterparty: strPtr(counterparty),
+			Value:        utils.FormatTokenBalance(tx.Value, decimals),
+			Fee:          strPtr(utils.FormatTokenAmount(fee, decimals)),
+			Symbol:       strPtr(symbol),
+			Status:       transactionStatus(tx.Status, tx.BlockNumber),
+		}
+		if tx.BlockNumber != "" {
+			blockNumber := parseHexBig(tx.BlockNumber).Int64()
+			transaction.BlockNumber = &blockNumber
+		}
+		if tx.Timestamp != "" {
+			timestamp := time.Unix(parseHexBig(tx.Timestamp).Int64(), 0).UTC()
+			transaction.Timestamp = &timestamp
+		}
+
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, response.Result.NextPageToken, nil
+}
+
+// 辅助函数：判断交易相对于查询地址的方向，返回方向和对方地址
+func transactionDirection(address string, from string, to string) (api.TransactionDirection, string) {
+	fromSelf := strings.EqualFold(from, address)
+	toSelf := strings.EqualFold(to, address)
+	switch {
+	case fromSelf && toSelf:
+		return api.TransactionDirectionSelf, address
+	case fromSelf:
+		return api.TransactionDirectionOut, to
+	default:
+		return api.TransactionDirectionIn, from
+	}
+}
+
+// 辅助函数：将回执状态转换为交易状态，尚未打包的交易为 pending
+func transactionStatus(status string, blockNumber string) api.TransactionStatus {
+	if blockNumber == "" {
+		return api.Pending
+	}
+	if status != "" && isZeroHex(status) {
+		return api.Failed
+	}
+	return api.Success
+}
+
+// 辅助函数：解析十六进制（0x 前缀）或十进制数值，无法解析时返回 0
+func parseHexBig(value string) *big.Int {
+	number := new(big.Int)
+	if strings.HasPrefix(value, "0x") {
+		if _, ok := number.SetString(strings.TrimPrefix(value, "0x"), 16); ok {
+			return number
+		}
+		return new(big.Int)
+	}
+	if _, ok := number.SetString(value, 10); ok {
+		return number
+	}
+	return new(big.Int)
+}
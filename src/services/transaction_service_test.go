@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/services/fakes"
+)
+
+const (
+	txWallet = "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	txPeer   = "0x4ed4e862860bed51a9570b96d89af5e1b0efefed"
+)
+
+// 返回固定交易记录的 Ankr 服务，记录请求参数
+func newTransactionServer(t *testing.T, transactions []map[string]interface{}, params *map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params map[string]interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		*params = req.Params
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"result":  map[string]interface{}{"transactions": transactions, "nextPageToken": "tx-page-2"},
+		})
+	}))
+}
+
+func TestTransactionService(t *testing.T) {
+	tests := []struct {
+		name             string
+		tx               map[string]interface{}
+		wantDirection    api.TransactionDirection
+		wantCounterparty string
+		wantValue        string
+		wantFee          string
+		wantSymbol       string
+		wantStatus       api.TransactionStatus
+		wantBlock        *int64
+		wantTimestamp    *time.Time
+	}{
+		{
+			// 0.05 ETH，手续费 21000 * 1 gwei
+			name:             "outgoing",
+			tx:               map[string]interface{}{"blockchain": "eth", "from": txWallet, "to": txPeer, "value": "0xb1a2bc2ec50000", "gasPrice": "0x3b9aca00", "gasUsed": "0x5208", "status": "0x1", "blockNumber": "0x1a2b3c", "timestamp": "0x67877a28"},
+			wantDirection:    api.TransactionDirectionOut,
+			wantCounterparty: txPeer,
+			wantValue:        "0.05",
+			wantFee:          "0.000021",
+			wantSymbol:       "ETH",
+			wantStatus:       api.Success,
+			wantBlock:        int64Ptr(0x1a2b3c),
+			wantTimestamp:    timePtr(time.Unix(0x67877a28, 0).UTC()),
+		},
+		{
+			// 地址大小写不同也属于查询地址
+			name:             "incoming",
+			tx:               map[string]interface{}{"blockchain": "polygon", "from": txPeer, "to": "0x742D35Cc6634C0532925a3b844Bc454e4438f44e", "value": "0xde0b6b3a7640000", "gasPrice": "0x2", "gasUsed": "0x3", "status": "0x1", "blockNumber": "0x10", "timestamp": "0x10"},
+			wantDirection:    api.TransactionDirectionIn,
+			wantCounterparty: txPeer,
+			wantValue:        "1",
+			wantFee:          "0.000000000000000006",
+			wantSymbol:       "POL",
+			wantStatus:       api.Success,
+			wantBlock:        int64Ptr(16),
+			wantTimestamp:    timePtr(time.Unix(16, 0).UTC()),
+		},
+		{
+			name:             "self",
+			tx:               map[string]interface{}{"blockchain": "eth", "from": txWallet, "to": txWallet, "value": "0x0", "gasPrice": "0x1", "gasUsed": "0x5208", "status": "0x1", "blockNumber": "0x1", "timestamp": "0x1"},
+			wantDirection:    api.TransactionDirectionSelf,
+			wantCounterparty: txWallet,
+			wantValue:        "0",
+			wantFee:          "0.000000000000021",
+			wantSymbol:       "ETH",
+			wantStatus:       api.Success,
+			wantBlock:        int64Ptr(1),
+			wantTimestamp:    timePtr(time.Unix(1, 0).UTC()),
+		},
+		{
+			// 回执状态为 0 的交易执行失败，仍然收取手续费
+			name:             "failed",
+			tx:               map[string]interface{}{"blockchain": "eth", "from": txWallet, "to": txPeer, "value": "0x0", "gasPrice": "0x3b9aca00", "gasUsed": "0xc350", "status": "0x0", "blockNumber": "0x1a0000", "timestamp": "0x67800000"},
+			wantDirection:    api.TransactionDirectionOut,
+			wantCounterparty: txPeer,
+			wantValue:        "0",
+			wantFee:          "0.00005",
+			wantSymbol:       "ETH",
+			wantStatus:       api.Failed,
+			wantBlock:        int64Ptr(0x1a0000),
+			wantTimestamp:    timePtr(time.Unix(0x67800000, 0).UTC()),
+		},
+		{
+			// 尚未打包的交易没有区块号、时间和回执
+			name:             "pending",
+			tx:               map[string]interface{}{"blockchain": "eth", "from": txWallet, "to": txPeer, "value": "0x1", "gasPrice": "0x3b9aca00", "gasUsed": "", "status": ""},
+			wantDirection:    api.TransactionDirectionOut,
+			wantCounterparty: txPeer,
+			wantValue:        "0.000000000000000001",
+			wantFee:          "0",
+			wantSymbol:       "ETH",
+			wantStatus:       api.Pending,
+		},
+		{
+			// 部分节点以十进制返回数值，无法解析的值按 0 处理
+			name:             "decimal and malformed numbers",
+			tx:               map[string]interface{}{"blockchain": "eth", "from": txPeer, "to": txWallet, "value": "0x0", "gasPrice": "1000", "gasUsed": "0xzz", "status": "1", "blockNumber": "12345", "timestamp": "1736930000"},
+			wantDirection:    api.TransactionDirectionIn,
+			wantCounterparty: txPeer,
+			wantValue:        "0",
+			wantFee:          "0",
+			wantSymbol:       "ETH",
+			wantStatus:       api.Success,
+			wantBlock:        int64Ptr(12345),
+			wantTimestamp:    timePtr(time.Unix(1736930000, 0).UTC()),
+		},
+		{
+			// 未知链使用 18 位小数，没有 symbol
+			name:             "unknown chain",
+			tx:               map[string]interface{}{"blockchain": "unknown", "from": txWallet, "to": txPeer, "value": "0xde0b6b3a7640000", "gasPrice": "0x0", "gasUsed": "0x0", "status": "0x1", "blockNumber": "0x1", "timestamp": "0x1"},
+			wantDirection:    api.TransactionDirectionOut,
+			wantCounterparty: txPeer,
+			wantValue:        "1",
+			wantFee:          "0",
+			wantSymbol:       "",
+			wantStatus:       api.Success,
+			wantBlock:        int64Ptr(1),
+			wantTimestamp:    timePtr(time.Unix(1, 0).UTC()),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params map[string]interface{}
+			server := newTransactionServer(t, []map[string]interface{}{tt.tx}, &params)
+			defer server.Close()
+
+			transactions, next, err := NewTransactionService(server.URL).GetTransactions(context.Background(), txWallet, []string{"eth"}, "", 0)
+			if err != nil {
+				t.Fatalf("GetTransactions() error = %v", err)
+			}
+			if next != "tx-page-2" || len(transactions) != 1 {
+				t.Fatalf("GetTransactions() = %d transactions, next %q", len(transactions), next)
+			}
+			tx := transactions[0]
+			if tx.Direction != tt.wantDirection || tx.Counterparty == nil || *tx.Counterparty != tt.wantCounterparty {
+				t.Errorf("direction = %s, counterparty = %v, want %s, %s", tx.Direction, tx.Counterparty, tt.wantDirection, tt.wantCounterparty)
+			}
+			if tx.Value != tt.wantValue || tx.Fee == nil || *tx.Fee != tt.wantFee {
+				t.Errorf("value = %s, fee = %v, want %s, %s", tx.Value, tx.Fee, tt.wantValue, tt.wantFee)
+			}
+			if tx.Symbol == nil || *tx.Symbol != tt.wantSymbol {
+				t.Errorf("symbol = %v, want %q", tx.Symbol, tt.wantSymbol)
+			}
+			if tx.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", tx.Status, tt.wantStatus)
+			}
+			if (tx.BlockNumber == nil) != (tt.wantBlock == nil) || (tx.BlockNumber != nil && *tx.BlockNumber != *tt.wantBlock) {
+				t.Errorf("block number = %v, want %v", tx.BlockNumber, tt.wantBlock)
+			}
+			if (tx.Timestamp == nil) != (tt.wantTimestamp == nil) || (tx.Timestamp != nil && !tx.Timestamp.Equal(*tt.wantTimestamp)) {
+				t.Errorf("timestamp = %v, want %v", tx.Timestamp, tt.wantTimestamp)
+			}
+
+			// 默认每页 10 条，按时间倒序
+			if params["pageSize"] != float64(10) || params["descOrder"] != true {
+				t.Errorf("request params = %v, want pageSize 10 and descOrder", params)
+			}
+		})
+	}
+}
+
+func TestTransactionServiceWithFakes(t *testing.T) {
+	ankr := fakes.NewAnkrServer()
+	defer ankr.Close()
+
+	service := NewTransactionService(ankr.URL)
+	ctx := context.Background()
+	first, next, err := service.GetTransactions(ctx, fakes.Wallet, []string{"base"}, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 2 || next == "" {
+		t.Fatalf("first page = %d transactions, next %q, want 2 and a next page", len(first), next)
+	}
+	if first[0].Direction != api.TransactionDirectionOut || first[0].Value != "0.05" || *first[0].Fee != "0.000021" {
+		t.Errorf("first transaction = %s %s fee %s, want out 0.05 fee 0.000021", first[0].Direction, first[0].Value, *first[0].Fee)
+	}
+	if first[1].Direction != api.TransactionDirectionIn || first[1].Value != "1" {
+		t.Errorf("second transaction = %s %s, want in 1", first[1].Direction, first[1].Value)
+	}
+
+	second, next, err := service.GetTransactions(ctx, fakes.Wallet, []string{"base"}, next, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 1 || second[0].Status != api.Failed || next != "" {
+		t.Errorf("second page = %+v, next %q, want one failed transaction", second, next)
+	}
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func timePtr(value time.Time) *time.Time {
+	return &value
+}
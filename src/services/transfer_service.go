@@ -0,0 +1,436 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/web3-smart-wallet/src/api"
+	"github.com/web3-smart-wallet/src/logging"
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+const (
+	// 补充代币元数据时最多读取的余额页数
+	transferMetadataPages = 3
+	// 单次请求最多读取的上游转账页数（两类转账合计），筛选条件使大部分记录被过滤时返回不足一页的结果
+	transferMaxFetches = 8
+)
+
+// TransferFilter 转账记录的筛选条件，零值表示不筛选
+type TransferFilter struct {
+	Contract  string
+	Direction api.TransactionDirection
+	Since     *time.Time
+	Until     *time.Time
+}
+
+type TransferServiceInterface interface {
+	GetTransfers(ctx context.Context, address string, chains []string, filter TransferFilter, pageToken string, pageSize int) ([]api.Transfer, string, error)
+}
+
+// TransferService 通过 ankr_getTokenTransfers 和 ankr_getNftTransfers 查询代币转账记录
+// 两类转账按时间倒序归并为一个分页；Ankr 不支持按合约筛选，合约和方向在服务端筛选，不足一页时继续读取上游分页
+// ERC20 的 symbol 和 decimals 优先使用余额接口（tokens）返回的元数据，与 /balance 保持一致
+type TransferService struct {
+	apiURL string
+	tokens AnkrServiceInterface
+}
+
+func NewTransferService(apiURL string, tokens AnkrServiceInterface) TransferServiceInterface {
+	return &TransferService{
+		apiURL: apiURL,
+		tokens: tokens,
+	}
+}
+
+// 转账类型，也是分页游标中的分段名称
+var transferSegments = []string{"erc20", "nft"}
+
+// transferItem 待返回的转账记录，rawValue 为 ERC20 未按精度换算的数量，补充元数据后重新换算
+type transferItem struct {
+	transfer api.Transfer
+	rawValue string
+}
+
+// transferStream 一类转账的读取位置：items 为 token 对应的上游页中符合筛选条件的记录，pos 为已返回的数量
+type transferStream struct {
+	segment string
+	token   string
+	items   []transferItem
+	pos     int
+	next    string
+}
+
+func (s *TransferService) GetTransfers(ctx context.Context, address string, chains []string, filter TransferFilter, pageToken string, pageSize int) ([]api.Transfer, string, error) {
+	if pageSize <= 0 {
+		pageSize = 10 // 默认每页10个
+	}
+
+	streams, err := decodeTransferCursor(pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	fetch := func(stream *transferStream) error {
+		var items []transferItem
+		var next string
+		var err error
+		if stream.segment == "erc20" {
+			items, next, err = s.getTokenTransfers(ctx, address, chains, filter, stream.token, pageSize)
+		} else {
+			items, next, err = s.getNFTTransfers(ctx, address, chains, filter, stream.token, pageSize)
+		}
+		if err != nil {
+			return err
+		}
+		stream.items, stream.next = items, next
+		stream.pos = min(stream.pos, len(items))
+		return nil
+	}
+
+	// 各类转账的当前页并发读取
+	errs := make([]error, len(streams))
+	var wg sync.WaitGroup
+	for i, stream := range streams {
+		wg.Add(1)
+		go func(i int, stream *transferStream) {
+			defer wg.Done()
+			errs[i] = fetch(stream)
+		}(i, stream)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, "", err
+	}
+
+	// 按时间倒序归并：每次取各类转账中最新的一条；某一类读完当前页时先读取它的下一页，
+	// 达到读取上限时停止，避免它后续页中更新的记录排在已返回的记录之后
+	fetches := len(streams)
+	page := make([]transferItem, 0, pageSize)
+	for len(page) < pageSize {
+		var newest *transferStream
+		blocked := false
+		for _, stream := range streams {
+			for stream.pos >= len(stream.items) && stream.next != "" && fetches < transferMaxFetches {
+				stream.token, stream.pos = stream.next, 0
+				if err := fetch(stream); err != nil {
+					return nil, "", err
+				}
+				fetches++
+			}
+			if stream.pos >= len(stream.items) {
+				blocked = blocked || stream.next != ""
+				continue
+			}
+			if newest == nil || newer(stream.items[stream.pos].transfer, newest.items[newest.pos].transfer) {
+				newest = stream
+			}
+		}
+		if newest == nil || blocked {
+			break
+		}
+		page = append(page, newest.items[newest.pos])
+		newest.pos++
+	}
+
+	return s.withMetadata(ctx, address, chains, page), encodeTransferCursor(streams), nil
+}
+
+// 查询 ERC20 转账
+func (s *TransferService) getTokenTransfers(ctx context.Context, address string, chains []string, filter TransferFilter, pageToken string, pageSize int) ([]transferItem, string, error) {
+	var response struct {
+		Result struct {
+			Transfers []struct {
+				Blockchain      string `json:"blockchain"`
+				FromAddress     string `json:"fromAddress"`
+				ToAddress       string `json:"toAddress"`
+				ContractAddress string `json:"contractAddress"`
+				Value           string `json:"value"`
+				ValueRawInteger string `json:"valueRawInteger"`
+				TokenName       string `json:"tokenName"`
+				TokenSymbol     string `json:"tokenSymbol"`
+				TokenDecimals   int    `json:"tokenDecimals"`
+				TransactionHash string `json:"transactionHash"`
+				BlockHeight     int64  `json:"blockHeight"`
+				Timestamp       int64  `json:"timestamp"`
+			} `json:"transfers"`
+			NextPageToken string `json:"nextPageToken"`
+		} `json:"result"`
+	}
+	if err := s.call(ctx, "ankr_getTokenTransfers", address, chains, filter, pageToken, pageSize, &response); err != nil {
+		return nil, "", err
+	}
+
+	transfers := make([]transferItem, 0, len(response.Result.Transfers))
+	for _, t := range response.Result.Transfers {
+		direction, counterparty := transactionDirection(address, t.FromAddress, t.ToAddress)
+		if !filter.matches(t.ContractAddress, direction) {
+			continue
+		}
+
+		// 元数据在归并出最终一页后用余额接口的数据覆盖
+		name, symbol, decimals := t.TokenName, t.TokenSymbol, t.TokenDecimals
+		value := t.Value
+		if t.ValueRawInteger != "" {
+			value = utils.FormatDecimalBalance(t.ValueRawInteger, decimals)
+		}
+
+		transfer := api.Transfer{
+			Hash:            t.TransactionHash,
+			Chain:           t.Blockchain,
+			Type:            api.TransferTypeERC20,
+			Direction:       direction,
+			From:            t.FromAddress,
+			To:              t.ToAddress,
+			Counterparty:    strPtr(counterparty),
+			ContractAddress: t.ContractAddress,
+			Name:            strPtr(name),
+			Symbol:          strPtr(symbol),
+			Decimals:        &decimals,
+			Value:           value,
+		}
+		setTransferBlock(&transfer, t.BlockHeight, t.Timestamp)
+		transfers = append(transfers, transferItem{transfer: transfer, rawValue: t.ValueRawInteger})
+	}
+
+	return transfers, response.Result.NextPageToken, nil
+}
+
+// 查询 ERC721 和 ERC1155 转账
+func (s *TransferService) getNFTTransfers(ctx context.Context, address string, chains []string, filter TransferFilter, pageToken string, pageSize int) ([]transferItem, string, error) {
+	var response struct {
+		Result struct {
+			Transfers []struct {
+				Blockchain       string `json:"blockchain"`
+				FromAddress      string `json:"fromAddress"`
+				ToAddress        string `json:"toAddress"`
+				ContractAddress  string `json:"contractAddress"`
+				Type             string `json:"type"`
+				Value            string `json:"value"`
+				TokenID          string `json:"tokenId"`
+				CollectionName   string `json:"collectionName"`
+				CollectionSymbol string `json:"collectionSymbol"`
+				TransactionHash  string `json:"transactionHash"`
+				BlockHeight      int64  `json:"blockHeight"`
+				Timestamp        int64  `json:"timestamp"`
+			} `json:"transfers"`
+			NextPageToken string `json:"nextPageToken"`
+		} `json:"result"`
+	}
+	if err := s.call(ctx, "ankr_getNftTransfers", address, chains, filter, pageToken, pageSize, &response); err != nil {
+		return nil, "", err
+	}
+
+	transfers := make([]transferItem, 0, len(response.Result.Transfers))
+	for _, t := range response.Result.Transfers {
+		// 只处理ERC721和ERC1155类型的token
+		transferType := api.TransferType(t.Type)
+		if transferType != api.TransferTypeERC721 && transferType != api.TransferTypeERC1155 {
+			continue
+		}
+		direction, counterparty := transactionDirection(address, t.FromAddress, t.ToAddress)
+		if !filter.matches(t.ContractAddress, direction) {
+			continue
+		}
+
+		value := t.Value
+		if value == "" {
+			value = "1"
+		}
+
+		transfer := api.Transfer{
+			Hash:            t.TransactionHash,
+			Chain:           t.Blockchain,
+			Type:            transferType,
+			Direction:       direction,
+			From:            t.FromAddress,
+			To:              t.ToAddress,
+			Counterparty:    strPtr(counterparty),
+			ContractAddress: t.ContractAddress,
+			Name:            strPtr(t.CollectionName),
+			Symbol:          strPtr(t.CollectionSymbol),
+			Value:           value,
+			TokenId:         strPtr(t.TokenID),
+		}
+		setTransferBlock(&transfer, t.BlockHeight, t.Timestamp)
+		transfers = append(transfers, transferItem{transfer: transfer})
+	}
+
+	return transfers, response.Result.NextPageToken, nil
+}
+
+// 发送转账查询请求，时间范围由 Ankr 过滤
+func (s *TransferService) call(ctx context.Context, method string, address string, chains []string, filter TransferFilter, pageToken string, pageSize int, out interface{}) error {
+	params := map[string]interface{}{
+		"blockchain": chains,
+		"address":    []string{address},
+		"descOrder":  true,
+		"pageSize":   pageSize,
+	}
+	if filter.Since != nil {
+		params["fromTimestamp"] = filter.Since.Unix()
+	}
+	if filter.Until != nil {
+		params["toTimestamp"] = filter.Until.Unix()
+	}
+	if pageToken != "" {
+		params["pageToken"] = pageToken
+	}
+
+	payload := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      1,
+	}
+	if err := postJSON(ctx, s.apiURL, nil, payload, out); err != nil {
+		return fmt.Errorf("failed to fetch transfers: %w", err)
+	}
+	recordSource(ctx, "ankr")
+	return nil
+}
+
+// 用余额接口的元数据覆盖本页 ERC20 转账的名称、symbol 和精度，并按精度重新换算数量
+func (s *TransferService) withMetadata(ctx context.Context, address string, chains []string, page []transferItem) []api.Transfer {
+	needed := make(map[string]bool)
+	for _, item := range page {
+		if item.transfer.Type == api.TransferTypeERC20 {
+			needed[metadataKey(item.transfer.Chain, item.transfer.ContractAddress)] = true
+		}
+	}
+	metadata := s.tokenMetadata(ctx, address, chains, needed)
+
+	transfers := make([]api.Transfer, len(page))
+	for i, item := range page {
+		transfer := item.transfer
+		if token, ok := metadata[metadataKey(transfer.Chain, transfer.ContractAddress)]; ok && transfer.Type == api.TransferTypeERC20 {
+			transfer.Name, transfer.Symbol = strPtr(token.Name), strPtr(token.Symbol)
+			if token.Decimals != nil {
+				decimals := *token.Decimals
+				transfer.Decimals = &decimals
+				if item.rawValue != "" {
+					transfer.Value = utils.FormatDecimalBalance(item.rawValue, decimals)
+				}
+			}
+		}
+		transfers[i] = transfer
+	}
+	return transfers
+}
+
+// 读取 needed 中合约的元数据，全部找到后不再读取后续余额页；失败时只记录日志，转账记录使用 Ankr 返回的元数据
+// 余额接口在启用缓存时经过 CachedAnkrService，同一地址的分页在缓存有效期内不会重复请求上游
+func (s *TransferService) tokenMetadata(ctx context.Context, address string, chains []string, needed map[string]bool) map[string]api.Token {
+	metadata := make(map[string]api.Token)
+	if s.tokens == nil || len(needed) == 0 {
+		return metadata
+	}
+
+	pageToken := ""
+	for page := 0; page < transferMetadataPages && len(metadata) < len(needed); page++ {
+		tokens, nextPageToken, err := s.tokens.GetTokens(ctx, address, chains, true, pageToken, 100)
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to load token metadata for transfers", "error", err)
+			break
+		}
+		for _, token := range tokens {
+			if token.Chain == nil || token.Address == "" {
+				continue
+			}
+			if key := metadataKey(*token.Chain, token.Address); needed[key] {
+				metadata[key] = token
+			}
+		}
+		if nextPageToken == "" {
+			break
+		}
+		pageToken = nextPageToken
+	}
+	return metadata
+}
+
+// 判断转账是否符合合约和方向筛选条件
+func (f TransferFilter) matches(contract string, direction api.TransactionDirection) bool {
+	if f.Contract != "" && !strings.EqualFold(f.Contract, contract) {
+		return false
+	}
+	if f.Direction != "" && direction != f.Direction && direction != api.TransactionDirectionSelf {
+		return false
+	}
+	return true
+}
+
+// 辅助函数：a 是否比 b 更新，没有时间的记录排在最后
+func newer(a api.Transfer, b api.Transfer) bool {
+	if a.Timestamp == nil || b.Timestamp == nil {
+		return a.Timestamp != nil && b.Timestamp == nil
+	}
+	return a.Timestamp.After(*b.Timestamp)
+}
+
+// 辅助函数：解码转账分页游标，每类转账的游标为 "<已返回数量>:<上游游标>"，读完的类型不在游标中；
+// 空游标表示从两类转账的第一页开始
+func decodeTransferCursor(pageToken string) ([]*transferStream, error) {
+	streams := make([]*transferStream, 0, len(transferSegments))
+	if pageToken == "" {
+		for _, segment := range transferSegments {
+			streams = append(streams, &transferStream{segment: segment})
+		}
+		return streams, nil
+	}
+
+	cursors, err := decodeCursor(pageToken)
+	if err != nil {
+		return nil, err
+	}
+	for _, segment := range transferSegments {
+		cursor, ok := cursors[segment]
+		if !ok {
+			continue
+		}
+		skip, token, found := strings.Cut(cursor, ":")
+		pos, err := strconv.Atoi(skip)
+		if !found || err != nil || pos < 0 {
+			return nil, ErrInvalidPageToken
+		}
+		streams = append(streams, &transferStream{segment: segment, token: token, pos: pos})
+	}
+	if len(streams) == 0 {
+		return nil, ErrInvalidPageToken
+	}
+	return streams, nil
+}
+
+// 辅助函数：编码转账分页游标，当前页还有未返回的记录时从当前页继续，否则从下一页开始；全部读完时返回空字符串
+func encodeTransferCursor(streams []*transferStream) string {
+	cursors := make(map[string]string)
+	for _, stream := range streams {
+		switch {
+		case stream.pos < len(stream.items):
+			cursors[stream.segment] = strconv.Itoa(stream.pos) + ":" + stream.token
+		case stream.next != "":
+			cursors[stream.segment] = "0:" + stream.next
+		}
+	}
+	return encodeCursor(cursors)
+}
+
+// 辅助函数：元数据表的键，合约地址不区分大小写
+func metadataKey(chain string, contract string) string {
+	return chain + "|" + strings.ToLower(contract)
+}
+
+// 辅助函数：设置区块高度和时间
+func setTransferBlock(transfer *api.Transfer, blockHeight int64, timestamp int64) {
+	if blockHeight > 0 {
+		transfer.BlockNumber = &blockHeight
+	}
+	if timestamp > 0 {
+		t := time.Unix(timestamp, 0).UTC()
+		transfer.Timestamp = &t
+	}
+}
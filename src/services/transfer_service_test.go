@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/web3-smart-wallet/src/api"
+)
+
+const (
+	transferWallet = "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	transferPeer   = "0x4ed4e862860bed51a9570b96d89af5e1b0efefed"
+	transferUSDC   = "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913"
+	transferDEGEN  = "0x4ed4e862860bed51a9570b96d89af5e1b0efefed"
+	transferNFT    = "0x2867a6dfb2c15f789c3bf0b5547ac4117850a060"
+)
+
+// 按时间倒序排列的上游转账记录，hash 为 "<类型>-<时间>"
+var (
+	testTokenTransfers = []map[string]interface{}{
+		tokenTransfer(100, transferUSDC, transferPeer, transferWallet),
+		tokenTransfer(80, transferDEGEN, transferWallet, transferPeer),
+		tokenTransfer(60, transferUSDC, transferPeer, transferWallet),
+		tokenTransfer(40, transferDEGEN, transferWallet, transferPeer),
+		tokenTransfer(20, transferUSDC, transferWallet, transferPeer),
+	}
+	testNFTTransfers = []map[string]interface{}{
+		{"blockchain": "base", "fromAddress": transferPeer, "toAddress": transferWallet, "contractAddress": transferNFT, "type": "ERC721", "tokenId": "1", "transactionHash": "nft-90", "blockHeight": 90, "timestamp": 90},
+		{"blockchain": "base", "fromAddress": transferWallet, "toAddress": transferPeer, "contractAddress": transferNFT, "type": "ERC721", "tokenId": "1", "transactionHash": "nft-50", "blockHeight": 50, "timestamp": 50},
+	}
+)
+
+func tokenTransfer(timestamp int, contract string, from string, to string) map[string]interface{} {
+	return map[string]interface{}{
+		"blockchain": "base", "fromAddress": from, "toAddress": to, "contractAddress": contract,
+		"value": "0.000001", "valueRawInteger": "1000000", "tokenSymbol": "UPSTREAM", "tokenDecimals": 12,
+		"transactionHash": "erc20-" + strconv.Itoa(timestamp), "blockHeight": timestamp, "timestamp": timestamp,
+	}
+}
+
+// 假 Ankr 转账接口：不论请求的 pageSize，每页固定返回 2 条，游标为下一条记录的下标
+func newTransferServer(t *testing.T) (*httptest.Server, *int) {
+	calls := new(int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+			Params struct {
+				PageToken string `json:"pageToken"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+			return
+		}
+		*calls++
+
+		transfers := testTokenTransfers
+		if req.Method == "ankr_getNftTransfers" {
+			transfers = testNFTTransfers
+		}
+		start, _ := strconv.Atoi(req.Params.PageToken)
+		end := min(start+2, len(transfers))
+		next := ""
+		if end < len(transfers) {
+			next = strconv.Itoa(end)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"result":  map[string]interface{}{"transfers": transfers[start:end], "nextPageToken": next},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+// 只返回 USDC 元数据的余额接口
+type metadataTokens struct{}
+
+func (metadataTokens) GetTokens(ctx context.Context, address string, chains []string, includeZeroBalance bool, pageToken string, pageSize int) ([]api.Token, string, error) {
+	chain, decimals := "base", 6
+	return []api.Token{{Chain: &chain, Address: transferUSDC, Name: "USD Coin", Symbol: "USDC", Decimals: &decimals}}, "", nil
+}
+
+func (m metadataTokens) GetTokenList(ctx context.Context, address string, chains []string, pageToken string, pageSize int) ([]api.Token, string, error) {
+	return m.GetTokens(ctx, address, chains, true, pageToken, pageSize)
+}
+
+func TestTransferServicePages(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   TransferFilter
+		pageSize int
+		want     [][]string
+	}{
+		{
+			name:     "merges both kinds newest first",
+			pageSize: 3,
+			want:     [][]string{{"erc20-100", "nft-90", "erc20-80"}, {"erc20-60", "nft-50", "erc20-40"}, {"erc20-20"}},
+		},
+		{
+			name:     "single page spans several upstream pages",
+			pageSize: 10,
+			want:     [][]string{{"erc20-100", "nft-90", "erc20-80", "erc20-60", "nft-50", "erc20-40", "erc20-20"}},
+		},
+		{
+			name:     "direction filter fills the page",
+			filter:   TransferFilter{Direction: api.TransactionDirectionOut},
+			pageSize: 3,
+			want:     [][]string{{"erc20-80", "nft-50", "erc20-40"}, {"erc20-20"}},
+		},
+		{
+			name:     "contract filter ignores case",
+			filter:   TransferFilter{Contract: "0x4ED4E862860BED51A9570B96D89AF5E1B0EFEFED"},
+			pageSize: 5,
+			want:     [][]string{{"erc20-80", "erc20-40"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTransferServer(t)
+			service := NewTransferService(server.URL, nil)
+
+			pageToken := ""
+			for i, want := range tt.want {
+				transfers, next, err := service.GetTransfers(context.Background(), transferWallet, []string{"base"}, tt.filter, pageToken, tt.pageSize)
+				if err != nil {
+					t.Fatalf("page %d: %v", i, err)
+				}
+				got := make([]string, len(transfers))
+				for j, transfer := range transfers {
+					got[j] = transfer.Hash
+				}
+				if len(got) != len(want) {
+					t.Fatalf("page %d = %v, want %v", i, got, want)
+				}
+				for j := range want {
+					if got[j] != want[j] {
+						t.Fatalf("page %d = %v, want %v", i, got, want)
+					}
+				}
+				if last := i == len(tt.want)-1; last != (next == "") {
+					t.Fatalf("page %d next = %q, last page %t", i, next, last)
+				}
+				pageToken = next
+			}
+		})
+	}
+}
+
+func TestTransferServiceNoMatches(t *testing.T) {
+	server, calls := newTransferServer(t)
+	service := NewTransferService(server.URL, nil)
+
+	// 没有记录符合筛选条件时读完所有上游分页，返回空结果且没有下一页
+	filter := TransferFilter{Contract: "0x0000000000000000000000000000000000000001"}
+	transfers, next, err := service.GetTransfers(context.Background(), transferWallet, []string{"base"}, filter, "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 0 || next != "" {
+		t.Fatalf("got %d transfers, next %q; want none", len(transfers), next)
+	}
+	if *calls > transferMaxFetches {
+		t.Fatalf("%d upstream calls, limit is %d", *calls, transferMaxFetches)
+	}
+}
+
+func TestTransferServiceMetadata(t *testing.T) {
+	server, _ := newTransferServer(t)
+	service := NewTransferService(server.URL, metadataTokens{})
+
+	transfers, _, err := service.GetTransfers(context.Background(), transferWallet, []string{"base"}, TransferFilter{}, "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// USDC 使用余额接口的 symbol 和精度，DEGEN 没有元数据，保留上游返回的值
+	tests := []struct {
+		index    int
+		symbol   string
+		decimals int
+		value    string
+	}{
+		{index: 0, symbol: "USDC", decimals: 6, value: "1"},
+		{index: 2, symbol: "UPSTREAM", decimals: 12, value: "0.000001"},
+	}
+	for _, tt := range tests {
+		transfer := transfers[tt.index]
+		if *transfer.Symbol != tt.symbol || *transfer.Decimals != tt.decimals || transfer.Value != tt.value {
+			t.Errorf("%s: symbol %s, decimals %d, value %s; want %s, %d, %s",
+				transfer.Hash, *transfer.Symbol, *transfer.Decimals, transfer.Value, tt.symbol, tt.decimals, tt.value)
+		}
+	}
+}
+
+func TestTransferServiceInvalidCursor(t *testing.T) {
+	server, calls := newTransferServer(t)
+	service := NewTransferService(server.URL, nil)
+
+	tests := []struct {
+		name      string
+		pageToken string
+	}{
+		{name: "not base64", pageToken: "not-a-cursor!"},
+		{name: "missing position", pageToken: encodeCursor(map[string]string{"erc20": "2"})},
+		{name: "negative position", pageToken: encodeCursor(map[string]string{"erc20": "-1:2"})},
+		{name: "unknown segment", pageToken: encodeCursor(map[string]string{"native": "0:2"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := service.GetTransfers(context.Background(), transferWallet, []string{"base"}, TransferFilter{}, tt.pageToken, 3)
+			if !errors.Is(err, ErrInvalidPageToken) {
+				t.Fatalf("error = %v, want ErrInvalidPageToken", err)
+			}
+		})
+	}
+	if *calls != 0 {
+		t.Fatalf("%d upstream calls for invalid cursors", *calls)
+	}
+}
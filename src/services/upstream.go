@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/web3-smart-wallet/src/metrics"
+	"github.com/web3-smart-wallet/src/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// upstreamCall 一次上游调用，结束时记录指标并结束 span
+type upstreamCall struct {
+	method string
+	start  time.Time
+	span   trace.Span
+}
+
+// 辅助函数：开始一次上游调用，method 为 JSON-RPC 方法名或 REST 接口的主机名
+// span 只记录主机名，不记录可能带有 API key 的完整 URL
+func startUpstream(ctx context.Context, method string, rawURL string) (context.Context, *upstreamCall) {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	ctx, span := tracing.StartClient(ctx, method,
+		semconv.RPCMethod(method),
+		semconv.ServerAddress(host),
+	)
+	return ctx, &upstreamCall{
+		method: method,
+		start:  time.Now(),
+		span:   span,
+	}
+}
+
+// end 记录调用结果，outcome 为 "ok" 或错误类型
+func (u *upstreamCall) end(outcome string, err error) {
+	metrics.ObserveUpstream(u.method, time.Since(u.start), outcome)
+	if err == nil && outcome != "ok" {
+		err = errors.New(outcome)
+	}
+	tracing.End(u.span, err)
+}
+
+// 辅助函数：为响应解析创建子 span，便于区分网络耗时和 JSON 解析耗时
+func startDecode(ctx context.Context) trace.Span {
+	_, span := tracing.Start(ctx, "decode response")
+	return span
+}
+
+// UpstreamErrorKind 上游错误的分类
+type UpstreamErrorKind int
+
+const (
+	// UpstreamFailed 其他上游错误：5xx、网络错误、无法解析的响应等
+	UpstreamFailed UpstreamErrorKind = iota
+	// UpstreamInvalidParams 上游拒绝了请求参数
+	UpstreamInvalidParams
+	// UpstreamRateLimited 上游限流，重试后仍然失败
+	UpstreamRateLimited
+	// UpstreamAuthFailed 上游拒绝了我们的 API key
+	UpstreamAuthFailed
+	// UpstreamUnsupportedChain 上游不支持请求的链
+	UpstreamUnsupportedChain
+	// UpstreamTimeout 上游未在超时前响应
+	UpstreamTimeout
+)
+
+func (k UpstreamErrorKind) String() string {
+	switch k {
+	case UpstreamInvalidParams:
+		return "invalid_params"
+	case UpstreamRateLimited:
+		return "rate_limited"
+	case UpstreamAuthFailed:
+		return "auth_failed"
+	case UpstreamUnsupportedChain:
+		return "unsupported_chain"
+	case UpstreamTimeout:
+		return "timeout"
+	}
+	return "failed"
+}
+
+// 辅助函数：该类错误是否可能在重试后恢复
+func (k UpstreamErrorKind) transient() bool {
+	return k == UpstreamFailed || k == UpstreamRateLimited || k == UpstreamTimeout
+}
+
+// UpstreamError 分类后的上游错误，可以用 errors.As 判断
+// Error() 包含上游返回的原始信息，只能写入日志，不能返回给客户端
+type UpstreamError struct {
+	Kind UpstreamErrorKind
+	// Method JSON-RPC 方法名或 REST 接口的主机名
+	Method string
+	// Status 上游的 HTTP 状态码，网络错误时为 0
+	Status int
+	// RPCCode JSON-RPC 错误码，非 JSON-RPC 错误时为 0
+	RPCCode int
+	// RetryAfter 上游 Retry-After 要求的等待时间
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Method, e.Kind, e.Err)
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// 辅助函数：按 HTTP 状态码分类非 200 响应
+func statusError(method string, status int, retryAfter time.Duration) *UpstreamError {
+	e := &UpstreamError{
+		Kind:       UpstreamFailed,
+		Method:     method,
+		Status:     status,
+		RetryAfter: retryAfter,
+		Err:        fmt.Errorf("unexpected status %d", status),
+	}
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		e.Kind = UpstreamInvalidParams
+	case http.StatusUnauthorized, http.StatusForbidden:
+		e.Kind = UpstreamAuthFailed
+	case http.StatusTooManyRequests:
+		e.Kind = UpstreamRateLimited
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		e.Kind = UpstreamTimeout
+	}
+	return e
+}
+
+// 辅助函数：按错误码和错误信息分类 JSON-RPC 错误，Ankr 对多类错误使用相同的错误码，需要结合信息判断
+func rpcErrorKind(rpcErr *rpcError) UpstreamErrorKind {
+	message := strings.ToLower(rpcErr.Message)
+	containsAny := func(words ...string) bool {
+		for _, word := range words {
+			if strings.Contains(message, word) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case containsAny("not supported", "unsupported") && containsAny("chain", "network"):
+		return UpstreamUnsupportedChain
+	case rpcErr.Code == -32005 || containsAny("rate limit", "too many requests", "limit exceeded"):
+		return UpstreamRateLimited
+	case containsAny("unauthorized", "forbidden", "api key", "apikey", "token is invalid"):
+		return UpstreamAuthFailed
+	case rpcErr.Code == -32602 || rpcErr.Code == -32600 || containsAny("invalid param", "invalid argument"):
+		return UpstreamInvalidParams
+	case containsAny("timeout", "timed out"):
+		return UpstreamTimeout
+	}
+	return UpstreamFailed
+}
+
+// 辅助函数：网络错误，超时（包括单次请求超时）归为 UpstreamTimeout
+func networkErrorKind(err error) UpstreamErrorKind {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return UpstreamTimeout
+	}
+	return UpstreamFailed
+}
@@ -0,0 +1,34 @@
+package timeout
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/apierror"
+	"github.com/web3-smart-wallet/src/logging"
+)
+
+// Middleware 为每个请求的 UserContext 设置超时，服务层的上游调用继承该 deadline；
+// 超过预算且 handler 返回的错误由超时引起（即包装了 context.DeadlineExceeded）时改为 504 apierror.Timeout，
+// 其余错误和响应（如 400、404、429）原样返回。exempt 为 nil 或对路径返回 true 时不设置超时
+func Middleware(budgets Budgets, exempt func(path string) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		budget := budgets.For(c.Path())
+		if budget <= 0 || (exempt != nil && exempt(c.Path())) {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), budget)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return err
+		}
+
+		logging.FromContext(ctx).Warn("request deadline exceeded", "timeout", budget.String())
+		return apierror.Timeout(budget)
+	}
+}
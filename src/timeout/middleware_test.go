@@ -0,0 +1,116 @@
+package timeout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/apierror"
+)
+
+func TestMiddleware(t *testing.T) {
+	budgets := Budgets{Default: 20 * time.Millisecond}
+
+	// 等待 UserContext 的 deadline，返回包装后的 ctx 错误，模拟被超时中断的上游调用
+	waitForDeadline := func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return fmt.Errorf("ankr api error: %w", c.UserContext().Err())
+	}
+
+	tests := []struct {
+		name       string
+		exempt     func(path string) bool
+		handler    fiber.Handler
+		wantStatus int
+		wantCode   string
+		// wantBudget 响应由中间件按预算生成时 details.timeout 为预算
+		wantBudget string
+	}{
+		{
+			name:       "deadline exceeded",
+			handler:    waitForDeadline,
+			wantStatus: fiber.StatusGatewayTimeout,
+			wantCode:   apierror.CodeTimeout,
+			wantBudget: "20ms",
+		},
+		{
+			name: "client error after the deadline is kept",
+			handler: func(c *fiber.Ctx) error {
+				<-c.UserContext().Done()
+				return apierror.NotFound("did_not_found", "DID not found")
+			},
+			wantStatus: fiber.StatusNotFound,
+			wantCode:   apierror.CodeNotFound,
+		},
+		{
+			name: "deadline error from another context is kept",
+			handler: func(c *fiber.Ctx) error {
+				// 上游自身的单次请求超时，请求预算尚未用完
+				ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+				defer cancel()
+				<-ctx.Done()
+				return fmt.Errorf("upstream: %w", ctx.Err())
+			},
+			wantStatus: fiber.StatusGatewayTimeout,
+			wantCode:   apierror.CodeTimeout,
+		},
+		{
+			name: "fast handler",
+			handler: func(c *fiber.Ctx) error {
+				if _, ok := c.UserContext().Deadline(); !ok {
+					return fiber.ErrInternalServerError
+				}
+				return c.SendString("ok")
+			},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:   "exempt path has no deadline",
+			exempt: func(path string) bool { return strings.HasPrefix(path, "/") },
+			handler: func(c *fiber.Ctx) error {
+				if _, ok := c.UserContext().Deadline(); ok {
+					return fiber.ErrInternalServerError
+				}
+				return c.SendString("ok")
+			},
+			wantStatus: fiber.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+			app.Use(Middleware(budgets, tt.exempt))
+			app.Get("/", tt.handler)
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil), -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			body, _ := io.ReadAll(resp.Body)
+			var response struct {
+				Code    string                 `json:"code"`
+				Details map[string]interface{} `json:"details"`
+			}
+			json.Unmarshal(body, &response)
+			if response.Code != tt.wantCode {
+				t.Fatalf("code = %q, want %q", response.Code, tt.wantCode)
+			}
+			if budget, _ := response.Details["timeout"].(string); budget != tt.wantBudget {
+				t.Fatalf("details.timeout = %q, want %q", budget, tt.wantBudget)
+			}
+		})
+	}
+}
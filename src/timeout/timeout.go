@@ -0,0 +1,74 @@
+package timeout
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/web3-smart-wallet/src/utils"
+)
+
+// RouteBudget 匹配 Pattern 的路由使用的超时时间
+type RouteBudget struct {
+	// Pattern 按路径段匹配，"*" 匹配任意一段，例如 "/api/user/*/portfolio"
+	Pattern string
+	Timeout time.Duration
+}
+
+// Budgets 请求的超时预算，包括所有上游调用和重试，零值表示不限制
+type Budgets struct {
+	Default time.Duration
+	Routes  []RouteBudget
+}
+
+// ParseBudgets 解析 "15s,/api/user/*/portfolio=30s" 格式的配置，
+// 不带 "=" 的一项为默认超时，其余为按路由覆盖的超时（按顺序匹配）
+func ParseBudgets(value string) (Budgets, error) {
+	var budgets Budgets
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pattern, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			spec = entry
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(spec))
+		if err != nil || timeout < 0 {
+			return Budgets{}, fmt.Errorf("invalid timeout %q", spec)
+		}
+		if !ok {
+			budgets.Default = timeout
+			continue
+		}
+
+		pattern = strings.TrimSpace(pattern)
+		if !strings.HasPrefix(pattern, "/") {
+			return Budgets{}, fmt.Errorf("invalid route pattern %q", pattern)
+		}
+		budgets.Routes = append(budgets.Routes, RouteBudget{Pattern: pattern, Timeout: timeout})
+	}
+	return budgets, nil
+}
+
+// UnmarshalText 支持从配置文件和环境变量中按 ParseBudgets 的格式读取
+func (b *Budgets) UnmarshalText(text []byte) error {
+	budgets, err := ParseBudgets(string(text))
+	if err != nil {
+		return err
+	}
+	*b = budgets
+	return nil
+}
+
+// For 返回 path 适用的超时时间，未匹配任何路由时使用默认值
+func (b Budgets) For(path string) time.Duration {
+	for _, route := range b.Routes {
+		if utils.MatchPath(route.Pattern, path) {
+			return route.Timeout
+		}
+	}
+	return b.Default
+}
@@ -0,0 +1,63 @@
+package timeout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBudgets(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Budgets
+		wantErr bool
+	}{
+		{value: "15s", want: Budgets{Default: 15 * time.Second}},
+		{
+			value: "15s, /api/user/*/portfolio=30s",
+			want:  Budgets{Default: 15 * time.Second, Routes: []RouteBudget{{Pattern: "/api/user/*/portfolio", Timeout: 30 * time.Second}}},
+		},
+		{value: "0s", want: Budgets{}},
+		{value: "", want: Budgets{}},
+		{value: "soon", wantErr: true},
+		{value: "-1s", wantErr: true},
+		{value: "api/user=1s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseBudgets(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Default != tt.want.Default || len(got.Routes) != len(tt.want.Routes) {
+				t.Fatalf("ParseBudgets = %+v, want %+v", got, tt.want)
+			}
+			for i := range got.Routes {
+				if got.Routes[i] != tt.want.Routes[i] {
+					t.Fatalf("route %d = %+v, want %+v", i, got.Routes[i], tt.want.Routes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBudgetsFor(t *testing.T) {
+	budgets, _ := ParseBudgets("15s,/api/user/*/portfolio=30s")
+
+	tests := []struct {
+		path string
+		want time.Duration
+	}{
+		{path: "/api/user/0x1/portfolio", want: 30 * time.Second},
+		{path: "/api/user/0x1", want: 15 * time.Second},
+		{path: "/api/user/0x1/portfolio/", want: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := budgets.For(tt.path); got != tt.want {
+			t.Errorf("For(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/web3-smart-wallet/src/logging"
+	"github.com/web3-smart-wallet/src/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware 从请求头（traceparent、tracestate、baggage）中恢复上游的 trace context，
+// 为每个请求创建 server span 并放入 UserContext；span 名称在路由匹配后更新为 "GET /api/user/:address/balance" 格式，
+// 路径中的钱包地址与日志一样脱敏
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+		ctx, span := Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(logging.RedactString(c.Path())),
+				semconv.ClientAddress(c.IP()),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		// 先交给 ErrorHandler 生成响应，span 才能记录最终的状态码
+		if err := utils.HandleNext(c); err != nil {
+			RecordError(span, err)
+		}
+
+		// 中间件（app.Use）的路由路径为 "/"，说明请求没有到达任何业务路由
+		if route := c.Route(); route != nil && route.Path != "/" {
+			span.SetName(c.Method() + " " + route.Path)
+			span.SetAttributes(semconv.HTTPRoute(route.Path))
+		}
+		status := c.Response().StatusCode()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+		return nil
+	}
+}
+
+// StartHandler 为 Server 的处理函数创建子 span，并更新请求的 UserContext
+func StartHandler(c *fiber.Ctx, name string, attrs ...attribute.KeyValue) trace.Span {
+	ctx, span := Start(c.UserContext(), name, trace.WithAttributes(attrs...))
+	c.SetUserContext(ctx)
+	return span
+}
+
+// headerCarrier 把 fiber 请求头适配为 propagation.TextMapCarrier
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key string, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0)
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
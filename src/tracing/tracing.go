@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/web3-smart-wallet/src/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 本服务创建的 span 使用的 instrumentation 名称
+const instrumentationName = "github.com/web3-smart-wallet"
+
+// Config 链路追踪配置
+type Config struct {
+	// Exporter 为 "otlp"、"stdout" 或 "none"（默认）
+	Exporter string
+	// ServiceName 写入 resource 的 service.name
+	ServiceName string
+	// SampleRatio 根 span 的采样比例，0 到 1，被上游采样的请求始终采样
+	SampleRatio float64
+}
+
+// Setup 配置全局 TracerProvider 和 W3C trace context 传播，返回的 shutdown 在退出前刷新未导出的 span
+// OTLP 导出的地址等参数通过标准的 OTEL_EXPORTER_OTLP_* 环境变量配置
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", config.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %v", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(config.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start 创建子 span，未调用 Setup 时为 no-op
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// StartClient 为上游调用创建 client 类型的 span
+func StartClient(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// End 结束 span，err 不为空时记录错误并把状态设置为 Error
+func End(span trace.Span, err error) {
+	if err != nil {
+		message := RecordError(span, err)
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
+
+// RecordError 在 span 上记录脱敏后的错误并返回脱敏后的信息；
+// 上游错误（如 *url.Error）包含带 API key 的完整地址，不能原样发送给 trace exporter
+func RecordError(span trace.Span, err error) string {
+	message := logging.RedactString(err.Error())
+	span.RecordError(errors.New(message))
+	return message
+}
@@ -0,0 +1,17 @@
+package utils
+
+import "github.com/gofiber/fiber/v2"
+
+// HandleNext 调用后续处理器，出错时立即交给 ErrorHandler 生成响应，
+// 外层的日志、指标和追踪中间件因此能拿到最终的状态码；ErrorHandler 本身失败时返回 500。
+// 返回的原始错误只用于记录，调用方不应再向上返回，否则会生成两次响应
+func HandleNext(c *fiber.Ctx) error {
+	err := c.Next()
+	if err == nil {
+		return nil
+	}
+	if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+		_ = c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return err
+}
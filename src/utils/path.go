@@ -0,0 +1,18 @@
+package utils
+
+import "strings"
+
+// MatchPath 按路径段匹配，pattern 中的 "*" 匹配任意一段，例如 "/api/user/*/portfolio"
+func MatchPath(pattern string, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return false
+	}
+	for i, segment := range patternSegments {
+		if segment != "*" && segment != pathSegments[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+)
+
+// ErrInvalidSignature 签名格式不正确或无法恢复出公钥
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Keccak256 计算以太坊使用的 Keccak-256 哈希
+func Keccak256(data ...[]byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hash.Write(d)
+	}
+	return hash.Sum(nil)
+}
+
+// HashPersonalMessage 按 EIP-191 personal_sign 规则计算消息哈希
+// keccak256("\x19Ethereum Signed Message:\n" + len(message) + message)
+func HashPersonalMessage(message string) []byte {
+	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(message))
+	return Keccak256([]byte(prefix), []byte(message))
+}
+
+// RecoverPersonalSignAddress 从 personal_sign 签名中恢复签名者的以太坊地址（小写）
+// signatureHex: 65 字节的 r || s || v 签名，v 可以是 0/1 或 27/28
+func RecoverPersonalSignAddress(message string, signatureHex string) (string, error) {
+	signature, err := hex.DecodeString(strings.TrimPrefix(signatureHex, "0x"))
+	if err != nil || len(signature) != 65 {
+		return "", fmt.Errorf("%w: expected 65 byte hex signature", ErrInvalidSignature)
+	}
+
+	v := signature[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		return "", fmt.Errorf("%w: invalid recovery id", ErrInvalidSignature)
+	}
+
+	// secp256k1 库使用的紧凑格式为 [27 + recovery id] || r || s
+	compact := make([]byte, 65)
+	compact[0] = 27 + v
+	copy(compact[1:], signature[:64])
+
+	publicKey, _, err := ecdsa.RecoverCompact(compact, HashPersonalMessage(message))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	// 地址为未压缩公钥（去掉 0x04 前缀）的 keccak256 哈希的后 20 字节
+	hash := Keccak256(publicKey.SerializeUncompressed()[1:])
+	return "0x" + hex.EncodeToString(hash[12:]), nil
+}
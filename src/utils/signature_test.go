@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// 固定的测试私钥
+var testKey = secp256k1.PrivKeyFromBytes([]byte(strings.Repeat("\x01", 32)))
+
+// 辅助函数：按钱包 personal_sign 的格式签名，返回 r || s || v（v 为 27/28）
+func personalSign(key *secp256k1.PrivateKey, message string) []byte {
+	compact := ecdsa.SignCompact(key, HashPersonalMessage(message), false)
+	signature := make([]byte, 65)
+	copy(signature, compact[1:])
+	signature[64] = compact[0]
+	return signature
+}
+
+func TestHashPersonalMessage(t *testing.T) {
+	// personal_sign("hello") 的哈希，与 ethers.js hashMessage 的结果一致
+	got := hex.EncodeToString(HashPersonalMessage("hello"))
+	want := "50b2c43fd39106bafbba0da34fc430e1f91e3c96ea2acee2bc34119f92b37750"
+	if got != want {
+		t.Fatalf("HashPersonalMessage = %s, want %s", got, want)
+	}
+}
+
+func TestRecoverPersonalSignAddress(t *testing.T) {
+	hash := Keccak256(testKey.PubKey().SerializeUncompressed()[1:])
+	address := "0x" + hex.EncodeToString(hash[12:])
+
+	signature := personalSign(testKey, "hello")
+	lowV := append([]byte(nil), signature...)
+	lowV[64] -= 27
+	badV := append([]byte(nil), signature...)
+	badV[64] = 29
+
+	tests := []struct {
+		name      string
+		message   string
+		signature string
+		want      string
+		wantErr   error
+	}{
+		{name: "v 27/28", message: "hello", signature: "0x" + hex.EncodeToString(signature), want: address},
+		{name: "v 0/1", message: "hello", signature: hex.EncodeToString(lowV), want: address},
+		{name: "other message recovers other address", message: "hello!", signature: "0x" + hex.EncodeToString(signature)},
+		{name: "not hex", message: "hello", signature: "0xzz", wantErr: ErrInvalidSignature},
+		{name: "wrong length", message: "hello", signature: "0x" + hex.EncodeToString(signature[:64]), wantErr: ErrInvalidSignature},
+		{name: "invalid recovery id", message: "hello", signature: "0x" + hex.EncodeToString(badV), wantErr: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RecoverPersonalSignAddress(tt.message, tt.signature)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want == "" {
+				if got == address {
+					t.Fatalf("recovered the signer for a different message")
+				}
+				return
+			}
+			if got != tt.want {
+				t.Fatalf("address = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,6 +16,22 @@ func FormatTokenBalance(hexBalance string, decimals int) string {
 	balance := new(big.Int)
 	balance.SetString(hex, 16)
 
+	return FormatTokenAmount(balance, decimals)
+}
+
+// FormatDecimalBalance 将十进制字符串表示的最小单位余额转换为可读格式的字符串
+// rawBalance: 十进制格式的余额（如 wei）
+// decimals: 代币的小数位数
+func FormatDecimalBalance(rawBalance string, decimals int) string {
+	balance, ok := new(big.Int).SetString(rawBalance, 10)
+	if !ok {
+		return "0"
+	}
+	return FormatTokenAmount(balance, decimals)
+}
+
+// FormatTokenAmount 将最小单位的余额按小数位数格式化
+func FormatTokenAmount(balance *big.Int, decimals int) string {
 	// 创建 10^decimals 作为除数
 	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
 